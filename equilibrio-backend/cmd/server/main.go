@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Chart timezones on hosts without zoneinfo
 
 	"equilibrio-backend/internal/api"
 	"equilibrio-backend/internal/config"
@@ -12,6 +19,9 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -22,12 +32,41 @@ func main() {
 	cfg := config.Load()
 
 	// Initialize services
-	marketDataService := services.NewMarketDataService(cfg)
+	universeService, err := services.NewUniverseService(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize universes:", err)
+	}
+	cacheService, err := services.NewCacheService(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize cache:", err)
+	}
+	marketDataService := services.NewMarketDataService(cfg, cacheService, universeService)
+	var replicator *services.Replicator
+	if cfg.Replication {
+		replicator, err = services.NewReplicator(cfg, marketDataService)
+		if err != nil {
+			log.Fatal("Failed to initialize replication:", err)
+		}
+	}
+	streamHub := services.NewStreamHub(cfg, marketDataService)
+	eventService := services.NewEventService(cfg, marketDataService)
+	alertService, err := services.NewAlertService(cfg, marketDataService)
+	if err != nil {
+		log.Fatal("Failed to initialize alerts:", err)
+	}
+	webhookService, err := services.NewWebhookService(cfg, universeService, alertService, eventService)
+	if err != nil {
+		log.Fatal("Failed to initialize webhooks:", err)
+	}
 	indicatorService := services.NewIndicatorService()
-	cacheService := services.NewCacheService(cfg)
+	backtestService := services.NewBacktestService(marketDataService, indicatorService)
+	presetService, err := services.NewPresetService(cfg, marketDataService)
+	if err != nil {
+		log.Fatal("Failed to initialize presets:", err)
+	}
 
 	// Initialize API handlers
-	handlers := api.NewHandlers(marketDataService, indicatorService, cacheService)
+	handlers := api.NewHandlers(marketDataService, indicatorService, cacheService, backtestService, presetService, universeService, streamHub, eventService, alertService, webhookService)
 
 	// Setup router
 	router := gin.Default()
@@ -55,8 +94,45 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+	// WebSocket and SSE streams only end when their service closes them, so
+	// end them as shutdown starts rather than waiting for the timeout
+	server.RegisterOnShutdown(func() {
+		streamHub.Close()
+		eventService.Close()
+	})
+
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// Wait for an interrupt or termination signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Server did not shut down cleanly:", err)
+	}
+
+	// Stop the producers of alerts before the webhooks delivering them, which
+	// save their pending deliveries, and close the cache last
+	alertService.Close()
+	webhookService.Close()
+	if replicator != nil {
+		replicator.Close()
+	}
+	if err := cacheService.Close(); err != nil {
+		log.Println("Failed to close cache:", err)
 	}
+	log.Println("Server stopped")
 }
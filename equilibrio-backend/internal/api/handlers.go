@@ -1,42 +1,111 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"equilibrio-backend/internal/models"
 	"equilibrio-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
 )
 
 type Handlers struct {
 	marketDataService *services.MarketDataService
 	indicatorService  *services.IndicatorService
 	cacheService      *services.CacheService
+	backtestService   *services.BacktestService
+	presetService     *services.PresetService
+	universeService   *services.UniverseService
+	streamHub         *services.StreamHub
+	eventService      *services.EventService
+	alertService      *services.AlertService
+	webhookService    *services.WebhookService
 }
 
 func NewHandlers(
 	marketDataService *services.MarketDataService,
 	indicatorService *services.IndicatorService,
 	cacheService *services.CacheService,
+	backtestService *services.BacktestService,
+	presetService *services.PresetService,
+	universeService *services.UniverseService,
+	streamHub *services.StreamHub,
+	eventService *services.EventService,
+	alertService *services.AlertService,
+	webhookService *services.WebhookService,
 ) *Handlers {
 	return &Handlers{
 		marketDataService: marketDataService,
 		indicatorService:  indicatorService,
 		cacheService:      cacheService,
+		backtestService:   backtestService,
+		presetService:     presetService,
+		universeService:   universeService,
+		streamHub:         streamHub,
+		eventService:      eventService,
+		alertService:      alertService,
+		webhookService:    webhookService,
 	}
 }
 
 // GetStocks handles GET /api/stocks
 func (h *Handlers) GetStocks(c *gin.Context) {
+	req, err := h.bindStockListRequest(c)
+	if err != nil {
+		h.respondBindError(c, err)
+		return
+	}
+
+	var fields []string
+	if req.Fields != "" {
+		if fields, err = services.ParseFieldList(req.Fields); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Get stocks from service
+	page, err := h.marketDataService.GetStocks(req)
+	if err != nil {
+		h.respondStocksError(c, err, "Failed to fetch stocks")
+		return
+	}
+
+	// Calculate total pages
+	totalPages := (page.Total + req.PageSize - 1) / req.PageSize
+
+	response := models.StockListResponse{
+		Stocks:     page.Stocks,
+		Total:      page.Total,
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		TotalPages: totalPages,
+		NextCursor: page.NextCursor,
+		Version:    page.Version,
+		AsOf:       page.AsOf,
+	}
+	if fields != nil {
+		response.Stocks = services.ProjectStocks(page.Stocks, fields)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// bindStockListRequest parses the query parameters shared by the stock list and export endpoints
+func (h *Handlers) bindStockListRequest(c *gin.Context) (models.StockListRequest, error) {
 	var req models.StockListRequest
 
 	// Parse query parameters
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return req, err
 	}
 
 	// Manually parse array parameters that Gin doesn't handle well
@@ -57,6 +126,22 @@ func (h *Handlers) GetStocks(c *gin.Context) {
 		req.EquilibriumZone = strings.Split(equilibriumZoneParam, ",")
 	}
 
+	// Collect min_<field>/max_<field> range parameters
+	ranges, err := services.ParseRangeParams(c.Request.URL.Query())
+	if err != nil {
+		return req, err
+	}
+	req.Ranges = ranges
+
+	// A saved preset replaces the filter fields of the request
+	if req.Preset != "" {
+		preset, err := h.presetService.GetPreset(req.Preset)
+		if err != nil {
+			return req, err
+		}
+		applyFilter(&req, preset.Filter)
+	}
+
 	// Set defaults
 	if req.Page <= 0 {
 		req.Page = 1
@@ -72,34 +157,54 @@ func (h *Handlers) GetStocks(c *gin.Context) {
 	}
 
 	// Set default filter values
-	if req.RSIMin == 0 && req.RSIMax == 0 {
-		req.RSIMin = 0
-		req.RSIMax = 100
-	}
-	if req.PriceMin == 0 && req.PriceMax == 0 {
-		req.PriceMin = 0
-		req.PriceMax = 10000
-	}
+	services.DefaultFilterBounds(&req.RSIMax, &req.PriceMax)
 
-	// Get stocks from service
-	stocks, total, err := h.marketDataService.GetStocks(req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stocks"})
-		return
-	}
+	return req, nil
+}
 
-	// Calculate total pages
-	totalPages := (total + req.PageSize - 1) / req.PageSize
+// applyFilter copies the filter criteria into a stock list request
+func applyFilter(req *models.StockListRequest, filter models.StockFilter) {
+	req.SearchTerm = filter.SearchTerm
+	req.Sectors = filter.Sectors
+	req.RSIMin = filter.RSIMin
+	req.RSIMax = filter.RSIMax
+	req.PriceMin = filter.PriceMin
+	req.PriceMax = filter.PriceMax
+	req.VolumeProfile = filter.VolumeProfile
+	req.Signals = filter.Signals
+	req.Trend = filter.Trend
+	req.EquilibriumZone = filter.EquilibriumZone
+	req.Query = filter.Query
+	req.Ranges = filter.Ranges
+}
 
-	response := models.StockListResponse{
-		Stocks:     stocks,
-		Total:      total,
-		Page:       req.Page,
-		PageSize:   req.PageSize,
-		TotalPages: totalPages,
+// respondBindError maps stock list request parsing errors to HTTP responses
+func (h *Handlers) respondBindError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPresetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preset not found"})
+		return
 	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
 
-	c.JSON(http.StatusOK, response)
+// respondStocksError maps stock list errors to HTTP responses, reporting
+// screener query errors with their position
+func (h *Handlers) respondStocksError(c *gin.Context, err error, message string) {
+	var queryErr *services.QueryError
+	if errors.As(err, &queryErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": queryErr.Error(), "position": queryErr.Pos})
+		return
+	}
+	if errors.Is(err, services.ErrUniverseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidFilter) || errors.Is(err, services.ErrInvalidSort) ||
+		errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
 }
 
 // GetStock handles GET /api/stocks/:symbol
@@ -127,15 +232,74 @@ func (h *Handlers) GetStockChart(c *gin.Context) {
 		return
 	}
 
-	// Get optional days parameter (default to 90)
-	daysStr := c.DefaultQuery("days", "90")
-	days, err := strconv.Atoi(daysStr)
-	if err != nil || days < 1 || days > 365 {
-		days = 90 // Default to 90 days if invalid
+	req := models.ChartRequest{
+		Symbol:   symbol,
+		Interval: c.Query("interval"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Timezone: c.Query("tz"),
+		Type:     c.Query("type"),
+	}
+
+	// Get optional days parameter (defaults to the interval's range)
+	switch daysStr := c.Query("days"); daysStr {
+	case "":
+	case "max":
+		req.MaxHistory = true
+	default:
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer or max"})
+			return
+		}
+		req.Days = days
+	}
+	if maxBarsStr := c.Query("maxBars"); maxBarsStr != "" {
+		maxBars, err := strconv.Atoi(maxBarsStr)
+		if err != nil || maxBars < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "maxBars must be a positive integer"})
+			return
+		}
+		req.MaxBars = maxBars
+	}
+	for _, overlay := range strings.Split(c.Query("overlays"), ",") {
+		if overlay = strings.TrimSpace(overlay); overlay != "" {
+			req.Overlays = append(req.Overlays, overlay)
+		}
+	}
+	if levelsStr := c.Query("levels"); levelsStr != "" {
+		levels, err := strconv.ParseBool(levelsStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "levels must be true or false"})
+			return
+		}
+		req.Levels = levels
+	}
+	if boxSizeStr := c.Query("boxSize"); boxSizeStr != "" {
+		boxSize, err := strconv.ParseFloat(boxSizeStr, 64)
+		if err != nil || boxSize <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "boxSize must be a positive number"})
+			return
+		}
+		req.BoxSize = boxSize
+	}
+	for name, dest := range map[string]*int{"atrPeriod": &req.ATRPeriod, "reversal": &req.Reversal} {
+		if valueStr := c.Query(name); valueStr != "" {
+			value, err := strconv.Atoi(valueStr)
+			if err != nil || value < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a positive integer"})
+				return
+			}
+			*dest = value
+		}
 	}
 
 	// Get chart data from market data service
-	chartData, err := h.marketDataService.GetStockChartWithDays(symbol, days)
+	chartData, err := h.marketDataService.GetChart(req)
+	if errors.Is(err, services.ErrInvalidChart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chart data not found"})
 		return
@@ -144,10 +308,38 @@ func (h *Handlers) GetStockChart(c *gin.Context) {
 	c.JSON(http.StatusOK, chartData)
 }
 
+// SearchStocks handles GET /api/v1/search
+func (h *Handlers) SearchStocks(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+	}
+
+	results, err := h.marketDataService.Search(c.Query("universe"), c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrUniverseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
 // GetSectors handles GET /api/sectors
 func (h *Handlers) GetSectors(c *gin.Context) {
-	sectors, err := h.marketDataService.GetSectors()
+	sectors, err := h.marketDataService.GetSectors(c.Query("universe"))
 	if err != nil {
+		if errors.Is(err, services.ErrUniverseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sectors"})
 		return
 	}
@@ -170,8 +362,14 @@ func (h *Handlers) CalculateIndicators(c *gin.Context) {
 	if req.Period <= 0 {
 		req.Period = 200 // Default period
 	}
+	if req.Period > services.MaxIndicatorPeriod {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("period must be at most %d", services.MaxIndicatorPeriod)})
+		return
+	}
 
-	indicators, err := h.indicatorService.CalculateIndicators(req.Symbol, req.Period)
+	indicators, err := h.cacheService.LoadIndicators(c.Request.Context(), req.Symbol, req.Period, func() (*models.TechnicalIndicators, error) {
+		return h.indicatorService.CalculateIndicators(req.Symbol, req.Period)
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate indicators"})
 		return
@@ -180,24 +378,38 @@ func (h *Handlers) CalculateIndicators(c *gin.Context) {
 	c.JSON(http.StatusOK, indicators)
 }
 
-// RefreshData handles POST /api/refresh
+// RefreshData handles POST /api/refresh. An empty body refreshes everything.
 func (h *Handlers) RefreshData(c *gin.Context) {
-	err := h.marketDataService.RefreshAllData()
+	var req models.RefreshRequest
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.marketDataService.Refresh(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh data"})
+		switch {
+		case errors.Is(err, services.ErrInvalidRefresh):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUniverseNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh data"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Data refreshed successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Data refreshed successfully", "refreshed": result})
 }
 
 // ExportStocks handles GET /api/export
 func (h *Handlers) ExportStocks(c *gin.Context) {
-	var req models.StockListRequest
-
-	// Parse query parameters
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	req, err := h.bindStockListRequest(c)
+	if err != nil {
+		h.respondBindError(c, err)
 		return
 	}
 
@@ -205,14 +417,16 @@ func (h *Handlers) ExportStocks(c *gin.Context) {
 	req.Page = 1
 	req.PageSize = 10000 // Large number for export
 
-	stocks, _, err := h.marketDataService.GetStocks(req)
+	req.Cursor = ""
+
+	page, err := h.marketDataService.GetStocks(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export stocks"})
+		h.respondStocksError(c, err, "Failed to export stocks")
 		return
 	}
 
 	// Generate CSV
-	csvData := h.generateCSV(stocks)
+	csvData := h.generateCSV(page.Stocks)
 
 	c.Header("Content-Type", "text/csv")
 	c.Header("Content-Disposition", "attachment; filename=stocks.csv")
@@ -238,10 +452,654 @@ func (h *Handlers) generateCSV(stocks []models.StockData) string {
 	return csv
 }
 
+// RunBacktest handles POST /api/v1/backtest
+func (h *Handlers) RunBacktest(c *gin.Context) {
+	var req models.BacktestRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.backtestService.Backtest(req)
+	if err != nil {
+		h.respondBacktestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// OptimizeSignals handles POST /api/v1/backtest/optimize
+func (h *Handlers) OptimizeSignals(c *gin.Context) {
+	var req models.OptimizationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.backtestService.Optimize(req)
+	if err != nil {
+		h.respondBacktestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RunPortfolioBacktest handles POST /api/v1/backtest/portfolio
+func (h *Handlers) RunPortfolioBacktest(c *gin.Context) {
+	var req models.PortfolioRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.backtestService.Portfolio(req)
+	if err != nil {
+		h.respondBacktestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RunMonteCarlo handles POST /api/v1/backtest/montecarlo
+func (h *Handlers) RunMonteCarlo(c *gin.Context) {
+	var req models.MonteCarloRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.backtestService.MonteCarlo(req)
+	if err != nil {
+		h.respondBacktestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondBacktestError maps backtest service errors to HTTP responses
+func (h *Handlers) respondBacktestError(c *gin.Context, err error) {
+	var queryErr *services.QueryError
+	if errors.As(err, &queryErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": queryErr.Error(), "position": queryErr.Pos})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidBacktestConfig) || errors.Is(err, services.ErrInvalidFilter) ||
+		errors.Is(err, services.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrUniverseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Stock not found"})
+}
+
+// ListPresets handles GET /api/v1/presets
+func (h *Handlers) ListPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"presets": h.presetService.ListPresets()})
+}
+
+// GetPreset handles GET /api/v1/presets/:id
+func (h *Handlers) GetPreset(c *gin.Context) {
+	preset, err := h.presetService.GetPreset(c.Param("id"))
+	if err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preset)
+}
+
+// CreatePreset handles POST /api/v1/presets
+func (h *Handlers) CreatePreset(c *gin.Context) {
+	var req models.PresetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preset, err := h.presetService.CreatePreset(req)
+	if err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, preset)
+}
+
+// UpdatePreset handles PUT /api/v1/presets/:id
+func (h *Handlers) UpdatePreset(c *gin.Context) {
+	var req models.PresetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preset, err := h.presetService.UpdatePreset(c.Param("id"), req)
+	if err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preset)
+}
+
+// DeletePreset handles DELETE /api/v1/presets/:id
+func (h *Handlers) DeletePreset(c *gin.Context) {
+	if err := h.presetService.DeletePreset(c.Param("id")); err != nil {
+		h.respondPresetError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondPresetError maps preset service errors to HTTP responses
+func (h *Handlers) respondPresetError(c *gin.Context, err error) {
+	var queryErr *services.QueryError
+	switch {
+	case errors.Is(err, services.ErrPresetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preset not found"})
+	case errors.As(err, &queryErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "position": queryErr.Pos})
+	case errors.Is(err, services.ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preset"})
+	}
+}
+
+// ListAlertRules handles GET /api/v1/alerts/rules
+func (h *Handlers) ListAlertRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rules": h.alertService.ListRules()})
+}
+
+// GetAlertRule handles GET /api/v1/alerts/rules/:id
+func (h *Handlers) GetAlertRule(c *gin.Context) {
+	rule, err := h.alertService.GetRule(c.Param("id"))
+	if err != nil {
+		h.respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateAlertRule handles POST /api/v1/alerts/rules
+func (h *Handlers) CreateAlertRule(c *gin.Context) {
+	var req models.AlertRuleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.alertService.CreateRule(req)
+	if err != nil {
+		h.respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateAlertRule handles PUT /api/v1/alerts/rules/:id
+func (h *Handlers) UpdateAlertRule(c *gin.Context) {
+	var req models.AlertRuleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.alertService.UpdateRule(c.Param("id"), req)
+	if err != nil {
+		h.respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteAlertRule handles DELETE /api/v1/alerts/rules/:id
+func (h *Handlers) DeleteAlertRule(c *gin.Context) {
+	if err := h.alertService.DeleteRule(c.Param("id")); err != nil {
+		h.respondAlertError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListAlerts handles GET /api/v1/alerts
+func (h *Handlers) ListAlerts(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+	}
+
+	alerts, err := h.alertService.ListAlerts(c.Query("ruleId"), c.Query("symbol"), limit)
+	if err != nil {
+		h.respondAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// respondAlertError maps alert service errors to HTTP responses
+func (h *Handlers) respondAlertError(c *gin.Context, err error) {
+	var queryErr *services.QueryError
+	switch {
+	case errors.Is(err, services.ErrAlertRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+	case errors.As(err, &queryErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "position": queryErr.Pos})
+	case errors.Is(err, services.ErrInvalidAlertRule) || errors.Is(err, services.ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save alert rule"})
+	}
+}
+
+// ListWebhooks handles GET /api/v1/webhooks/endpoints
+func (h *Handlers) ListWebhooks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"endpoints": h.webhookService.ListEndpoints()})
+}
+
+// GetWebhook handles GET /api/v1/webhooks/endpoints/:id
+func (h *Handlers) GetWebhook(c *gin.Context) {
+	endpoint, err := h.webhookService.GetEndpoint(c.Param("id"))
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// CreateWebhook handles POST /api/v1/webhooks/endpoints
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	var req models.WebhookEndpointRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.webhookService.CreateEndpoint(req)
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// UpdateWebhook handles PUT /api/v1/webhooks/endpoints/:id
+func (h *Handlers) UpdateWebhook(c *gin.Context) {
+	var req models.WebhookEndpointRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.webhookService.UpdateEndpoint(c.Param("id"), req)
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// DeleteWebhook handles DELETE /api/v1/webhooks/endpoints/:id
+func (h *Handlers) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookService.DeleteEndpoint(c.Param("id")); err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestWebhook handles POST /api/v1/webhooks/endpoints/:id/test
+func (h *Handlers) TestWebhook(c *gin.Context) {
+	delivery, err := h.webhookService.TestEndpoint(c.Param("id"))
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// ListWebhookDeliveries handles GET /api/v1/webhooks/deliveries
+func (h *Handlers) ListWebhookDeliveries(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Query("endpointId"), c.Query("status"), limit)
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// GetWebhookDelivery handles GET /api/v1/webhooks/deliveries/:id
+func (h *Handlers) GetWebhookDelivery(c *gin.Context) {
+	delivery, err := h.webhookService.GetDelivery(c.Param("id"))
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ListWebhookDeadLetters handles GET /api/v1/webhooks/dead-letters
+func (h *Handlers) ListWebhookDeadLetters(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+	}
+
+	deadLetters, err := h.webhookService.ListDeadLetters(c.Query("endpointId"), limit)
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deadLetters": deadLetters})
+}
+
+// RetryWebhookDeadLetter handles POST /api/v1/webhooks/dead-letters/:id/retry
+func (h *Handlers) RetryWebhookDeadLetter(c *gin.Context) {
+	delivery, err := h.webhookService.RetryDeadLetter(c.Param("id"))
+	if err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// DeleteWebhookDeadLetter handles DELETE /api/v1/webhooks/dead-letters/:id
+func (h *Handlers) DeleteWebhookDeadLetter(c *gin.Context) {
+	if err := h.webhookService.DeleteDeadLetter(c.Param("id")); err != nil {
+		h.respondWebhookError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondWebhookError maps webhook service errors to HTTP responses
+func (h *Handlers) respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+	case errors.Is(err, services.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save webhook"})
+	}
+}
+
+// ListUniverses handles GET /api/v1/universes
+func (h *Handlers) ListUniverses(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"universes": h.universeService.ListUniverses(),
+		"default":   h.universeService.DefaultUniverse(),
+	})
+}
+
+// GetUniverse handles GET /api/v1/universes/:name
+func (h *Handlers) GetUniverse(c *gin.Context) {
+	universe, err := h.universeService.GetUniverse(c.Param("name"))
+	if err != nil {
+		h.respondUniverseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, universe)
+}
+
+// CreateUniverse handles POST /api/v1/universes
+func (h *Handlers) CreateUniverse(c *gin.Context) {
+	var req models.UniverseRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	universe, err := h.universeService.CreateUniverse(req)
+	if err != nil {
+		h.respondUniverseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, universe)
+}
+
+// UpdateUniverse handles PUT /api/v1/universes/:name
+func (h *Handlers) UpdateUniverse(c *gin.Context) {
+	var req models.UniverseRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	universe, err := h.universeService.UpdateUniverse(c.Param("name"), req)
+	if err != nil {
+		h.respondUniverseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, universe)
+}
+
+// DeleteUniverse handles DELETE /api/v1/universes/:name
+func (h *Handlers) DeleteUniverse(c *gin.Context) {
+	if err := h.universeService.DeleteUniverse(c.Param("name")); err != nil {
+		h.respondUniverseError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondUniverseError maps universe service errors to HTTP responses
+func (h *Handlers) respondUniverseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUniverseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Universe not found"})
+	case errors.Is(err, services.ErrUniverseConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidUniverse):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save universe"})
+	}
+}
+
+// GetCacheStats handles GET /api/v1/cache/stats
+func (h *Handlers) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cacheService.Stats())
+}
+
+// streamWriteTimeout bounds how long a stream message may take to write
+const streamWriteTimeout = 10 * time.Second
+
+// StreamUpdates handles GET /api/v1/stream, upgrading to a WebSocket. Clients
+// send subscribe and unsubscribe requests and receive ticks, indicator
+// changes and screener rows entering or leaving.
+func (h *Handlers) StreamUpdates(c *gin.Context) {
+	server := websocket.Server{
+		// Any origin may connect, as with the CORS policy of the REST API
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   h.serveStream,
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func (h *Handlers) serveStream(conn *websocket.Conn) {
+	client := h.streamHub.Connect()
+	defer conn.Close()
+	defer client.Close()
+
+	go func() {
+		defer client.Close()
+		for {
+			var req models.StreamRequest
+			err := websocket.JSON.Receive(conn, &req)
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			switch {
+			case err == nil:
+				client.Handle(req)
+			case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+				client.Reject("", err)
+			default:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-client.Messages():
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := websocket.JSON.Send(conn, msg); err != nil {
+				return
+			}
+		case <-client.Done():
+			if err := client.Err(); err != nil {
+				conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				websocket.JSON.Send(conn, models.StreamMessage{Type: "error", Error: "disconnected: " + err.Error()})
+			}
+			return
+		}
+	}
+}
+
+// eventsHeartbeat is how often an idle event stream sends a comment so
+// proxies keep the connection open
+const eventsHeartbeat = 15 * time.Second
+
+// StreamEvents handles GET /api/v1/events, a Server-Sent Events stream of
+// signal, trend and equilibrium zone changes. A reconnecting client resumes
+// after the ID in its Last-Event-ID header (or lastEventId parameter).
+func (h *Handlers) StreamEvents(c *gin.Context) {
+	var lastEventID int64
+	if raw := c.GetHeader("Last-Event-ID"); raw != "" || c.Query("lastEventId") != "" {
+		if raw == "" {
+			raw = c.Query("lastEventId")
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID must be a non-negative integer"})
+			return
+		}
+		lastEventID = id
+	}
+
+	universe := ""
+	if name := c.Query("universe"); name != "" {
+		u, err := h.universeService.GetUniverse(name)
+		if err != nil {
+			h.respondUniverseError(c, err)
+			return
+		}
+		universe = u.Name
+	}
+	filter, err := services.ParseEventFilter(universe, c.Query("symbols"), c.Query("types"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, missed, gap := h.eventService.Subscribe(lastEventID, filter)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Stop nginx-style proxies from buffering the stream
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if gap {
+		fmt.Fprint(w, "event: gap\ndata: {\"error\":\"some events since Last-Event-ID are no longer available\"}\n\n")
+	}
+	for _, event := range missed {
+		writeSignalEvent(w, event)
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event := <-sub.Events():
+			writeSignalEvent(w, event)
+			w.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			w.Flush()
+		case <-sub.Done():
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSignalEvent writes an event in the Server-Sent Events format
+func writeSignalEvent(w io.Writer, event models.SignalEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
+
 // HealthCheck handles GET /health
 func (h *Handlers) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "healthy",
 		"service": "equilibrio-backend",
+		"cache":   h.cacheService.Backend(),
+		"streams": h.streamHub.ClientCount(),
 	})
 }
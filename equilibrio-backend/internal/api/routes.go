@@ -16,12 +16,59 @@ func SetupRoutes(router *gin.Engine, handlers *Handlers) {
 		v1.GET("/stocks/:symbol", handlers.GetStock)
 		v1.GET("/sectors", handlers.GetSectors)
 		v1.GET("/export", handlers.ExportStocks)
+		v1.GET("/search", handlers.SearchStocks)
+
+		// Filter presets
+		v1.GET("/presets", handlers.ListPresets)
+		v1.POST("/presets", handlers.CreatePreset)
+		v1.GET("/presets/:id", handlers.GetPreset)
+		v1.PUT("/presets/:id", handlers.UpdatePreset)
+		v1.DELETE("/presets/:id", handlers.DeletePreset)
+
+		// Universes
+		v1.GET("/universes", handlers.ListUniverses)
+		v1.POST("/universes", handlers.CreateUniverse)
+		v1.GET("/universes/:name", handlers.GetUniverse)
+		v1.PUT("/universes/:name", handlers.UpdateUniverse)
+		v1.DELETE("/universes/:name", handlers.DeleteUniverse)
 
 		// Data management
 		v1.POST("/refresh", handlers.RefreshData)
+		v1.GET("/cache/stats", handlers.GetCacheStats)
+
+		// Alerts
+		v1.GET("/alerts", handlers.ListAlerts)
+		v1.GET("/alerts/rules", handlers.ListAlertRules)
+		v1.POST("/alerts/rules", handlers.CreateAlertRule)
+		v1.GET("/alerts/rules/:id", handlers.GetAlertRule)
+		v1.PUT("/alerts/rules/:id", handlers.UpdateAlertRule)
+		v1.DELETE("/alerts/rules/:id", handlers.DeleteAlertRule)
+
+		// Webhooks
+		v1.GET("/webhooks/endpoints", handlers.ListWebhooks)
+		v1.POST("/webhooks/endpoints", handlers.CreateWebhook)
+		v1.GET("/webhooks/endpoints/:id", handlers.GetWebhook)
+		v1.PUT("/webhooks/endpoints/:id", handlers.UpdateWebhook)
+		v1.DELETE("/webhooks/endpoints/:id", handlers.DeleteWebhook)
+		v1.POST("/webhooks/endpoints/:id/test", handlers.TestWebhook)
+		v1.GET("/webhooks/deliveries", handlers.ListWebhookDeliveries)
+		v1.GET("/webhooks/deliveries/:id", handlers.GetWebhookDelivery)
+		v1.GET("/webhooks/dead-letters", handlers.ListWebhookDeadLetters)
+		v1.POST("/webhooks/dead-letters/:id/retry", handlers.RetryWebhookDeadLetter)
+		v1.DELETE("/webhooks/dead-letters/:id", handlers.DeleteWebhookDeadLetter)
+
+		// Streaming
+		v1.GET("/stream", handlers.StreamUpdates)
+		v1.GET("/events", handlers.StreamEvents)
 
 		// Technical indicators
 		v1.POST("/indicators", handlers.CalculateIndicators)
+
+		// Backtesting
+		v1.POST("/backtest", handlers.RunBacktest)
+		v1.POST("/backtest/optimize", handlers.OptimizeSignals)
+		v1.POST("/backtest/portfolio", handlers.RunPortfolioBacktest)
+		v1.POST("/backtest/montecarlo", handlers.RunMonteCarlo)
 	}
 
 	// Legacy API routes for backward compatibility
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -11,15 +12,82 @@ type Config struct {
 	AlphaVantageKey string
 	IEXCloudKey     string
 	Environment     string
+	PresetsFile     string
+	UniversesFile   string // Universes created through the API
+	UniversesDir    string // Read-only universe definitions, one *.json file each
+	DefaultUniverse string
+	SnapshotTTL     time.Duration // How long a universe snapshot is served before it is rebuilt
+
+	StreamInterval time.Duration // How often universes with streaming subscribers are checked for a new snapshot
+	StreamBuffer   int           // Messages buffered per streaming client before it is disconnected
+	EventsBacklog  int           // Signal events kept for clients resuming with Last-Event-ID
+
+	Replication bool // Share snapshots and refreshes with other replicas over Redis pub/sub
+
+	AlertRulesFile    string
+	AlertHistoryFile  string
+	AlertHistoryLimit int           // Fired alerts kept in the history
+	AlertsInterval    time.Duration // How often universes with alert rules are checked for a new snapshot
+
+	WebhooksFile           string
+	WebhookDeliveriesFile  string
+	WebhookDeadLettersFile string
+	WebhookLogLimit        int           // Deliveries kept in the log, and in the dead letters
+	WebhookMaxAttempts     int           // Attempts of a delivery before it fails
+	WebhookBackoff         time.Duration // Wait before the first retry, doubled for every next one
+	WebhookTimeout         time.Duration // Timeout of one webhook request
+	WebhookWorkers         int           // Deliveries attempted at once
+	WebhookAllowPrivate    bool          // Allow webhooks to loopback, private and link-local addresses
+
+	// Cache expiration per data type
+	CacheStockTTL      time.Duration
+	CacheIndicatorsTTL time.Duration
+	CacheStaleTTL      time.Duration // How long an expired entry may still be served while it refreshes
+
+	CacheMemoryEntries  int           // Capacity of the in-memory cache used without Redis
+	CacheHealthInterval time.Duration // How often to check whether Redis is back
 }
 
 func Load() *Config {
 	return &Config{
 		Port:            getEnv("PORT", "8080"),
-		RedisURL:        getEnv("REDIS_URL", "redis://localhost:6379"),
+		RedisURL:        getEnvAllowEmpty("REDIS_URL", "redis://localhost:6379"),
 		AlphaVantageKey: getEnv("ALPHA_VANTAGE_API_KEY", ""),
 		IEXCloudKey:     getEnv("IEX_CLOUD_API_KEY", ""),
 		Environment:     getEnv("ENVIRONMENT", "development"),
+		PresetsFile:     getEnv("PRESETS_FILE", "data/presets.json"),
+		UniversesFile:   getEnv("UNIVERSES_FILE", "data/universes.json"),
+		UniversesDir:    getEnv("UNIVERSES_DIR", "universes"),
+		DefaultUniverse: getEnv("DEFAULT_UNIVERSE", "default"),
+		SnapshotTTL:     getEnvAsDuration("SNAPSHOT_TTL", 30*time.Second),
+
+		StreamInterval: getEnvAsDuration("STREAM_INTERVAL", 5*time.Second),
+		StreamBuffer:   getEnvAsInt("STREAM_BUFFER", 256),
+		EventsBacklog:  getEnvAsInt("EVENTS_BACKLOG", 1000),
+
+		Replication: getEnvAsBool("REPLICATION", false),
+
+		AlertRulesFile:    getEnv("ALERT_RULES_FILE", "data/alert_rules.json"),
+		AlertHistoryFile:  getEnv("ALERT_HISTORY_FILE", "data/alert_history.json"),
+		AlertHistoryLimit: getEnvAsInt("ALERT_HISTORY_LIMIT", 1000),
+		AlertsInterval:    getEnvAsDuration("ALERTS_INTERVAL", 30*time.Second),
+
+		WebhooksFile:           getEnv("WEBHOOKS_FILE", "data/webhooks.json"),
+		WebhookDeliveriesFile:  getEnv("WEBHOOK_DELIVERIES_FILE", "data/webhook_deliveries.json"),
+		WebhookDeadLettersFile: getEnv("WEBHOOK_DEAD_LETTERS_FILE", "data/webhook_dead_letters.json"),
+		WebhookLogLimit:        getEnvAsInt("WEBHOOK_LOG_LIMIT", 1000),
+		WebhookMaxAttempts:     getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		WebhookBackoff:         getEnvAsDuration("WEBHOOK_BACKOFF", time.Second),
+		WebhookTimeout:         getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookWorkers:         getEnvAsInt("WEBHOOK_WORKERS", 4),
+		WebhookAllowPrivate:    getEnvAsBool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		CacheStockTTL:      getEnvAsDuration("CACHE_STOCK_TTL", 30*time.Second),
+		CacheIndicatorsTTL: getEnvAsDuration("CACHE_INDICATORS_TTL", 10*time.Minute),
+		CacheStaleTTL:      getEnvAsDuration("CACHE_STALE_TTL", 30*time.Second),
+
+		CacheMemoryEntries:  getEnvAsInt("CACHE_MEMORY_ENTRIES", 10000),
+		CacheHealthInterval: getEnvAsDuration("CACHE_HEALTH_INTERVAL", 5*time.Second),
 	}
 }
 
@@ -30,6 +98,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAllowEmpty is like getEnv, but keeps a variable that is set to an
+// empty string, for settings where empty means "disabled"
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -38,3 +115,21 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// AlertRule fires an alert whenever its condition becomes true for a stock
+// it targets: one symbol, or every stock of the universe matching a filter
+type AlertRule struct {
+	ID              string      `json:"id"`
+	Name            string      `json:"name"`
+	Universe        string      `json:"universe"`         // Universe the rule watches
+	Symbol          string      `json:"symbol,omitempty"` // Targets one stock; otherwise the stocks matching Filter
+	Filter          StockFilter `json:"filter"`
+	Condition       string      `json:"condition"`       // Screener expression, e.g. "rsi < 30"
+	CooldownSeconds int         `json:"cooldownSeconds"` // Minimum time between two alerts of a stock
+	Channels        []string    `json:"channels"`        // Where fired alerts are delivered
+	Enabled         bool        `json:"enabled"`
+	CreatedAt       time.Time   `json:"createdAt"`
+	UpdatedAt       time.Time   `json:"updatedAt"`
+}
+
+// AlertRuleRequest represents the request body for creating or updating an alert rule
+type AlertRuleRequest struct {
+	Name            string      `json:"name" binding:"required"`
+	Universe        string      `json:"universe"` // Empty uses the default universe
+	Symbol          string      `json:"symbol"`
+	Filter          StockFilter `json:"filter"`
+	Condition       string      `json:"condition" binding:"required"`
+	CooldownSeconds *int        `json:"cooldownSeconds"` // Defaults to one hour
+	Channels        []string    `json:"channels"`        // Defaults to ["log"]
+	Enabled         *bool       `json:"enabled"`         // Defaults to true
+}
+
+// Alert is a fired alert rule, with the values that triggered it
+type Alert struct {
+	ID        string                 `json:"id"`
+	RuleID    string                 `json:"ruleId"`
+	RuleName  string                 `json:"ruleName"`
+	Universe  string                 `json:"universe"`
+	Symbol    string                 `json:"symbol"`
+	Condition string                 `json:"condition"`
+	Values    map[string]interface{} `json:"values"` // Fields the condition reads, by name
+	Price     float64                `json:"price"`
+	Version   int64                  `json:"version"` // Snapshot the rule was evaluated on
+	Channels  []string               `json:"channels"`
+	Time      time.Time              `json:"time"`
+}
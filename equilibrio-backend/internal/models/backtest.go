@@ -0,0 +1,199 @@
+package models
+
+import (
+	"time"
+)
+
+// SignalRules represents the thresholds used to turn indicators into trades
+type SignalRules struct {
+	RSIPeriod           int     `json:"rsiPeriod"`
+	RSIBuyBelow         float64 `json:"rsiBuyBelow"`
+	RSISellAbove        float64 `json:"rsiSellAbove"`
+	EquilibriumLookback int     `json:"equilibriumLookback"` // Bars used for the high/low range
+	DiscountBelow       float64 `json:"discountBelow"`       // % below equilibrium required to buy
+	PremiumAbove        float64 `json:"premiumAbove"`        // % above equilibrium that triggers a sell
+	CommissionPct       float64 `json:"commissionPct"`       // Cost per side as a % of trade value
+}
+
+// Trade represents a single round trip produced by a backtest
+type Trade struct {
+	EntryTime  string  `json:"entryTime"`
+	ExitTime   string  `json:"exitTime"`
+	EntryPrice float64 `json:"entryPrice"`
+	ExitPrice  float64 `json:"exitPrice"`
+	ReturnPct  float64 `json:"returnPct"`
+	Bars       int     `json:"bars"`
+}
+
+// EquityPoint represents the portfolio value at a point in time
+type EquityPoint struct {
+	Time   string  `json:"time"`
+	Equity float64 `json:"equity"`
+}
+
+// BacktestMetrics represents summary statistics of a backtest
+type BacktestMetrics struct {
+	TotalReturn  float64 `json:"totalReturn"`  // %
+	MaxDrawdown  float64 `json:"maxDrawdown"`  // %, reported as a positive number
+	SharpeRatio  float64 `json:"sharpeRatio"`  // Annualized from per-bar returns
+	WinRate      float64 `json:"winRate"`      // %
+	ProfitFactor float64 `json:"profitFactor"` // Gross profit / gross loss
+	NumTrades    int     `json:"numTrades"`
+	Bars         int     `json:"bars"`
+}
+
+// BacktestRequest represents the request for a single-symbol backtest
+type BacktestRequest struct {
+	Symbol string      `json:"symbol" binding:"required"`
+	Days   int         `json:"days"`
+	Rules  SignalRules `json:"rules"`
+}
+
+// BacktestResult represents the outcome of a single-symbol backtest
+type BacktestResult struct {
+	Symbol      string          `json:"symbol"`
+	Rules       SignalRules     `json:"rules"`
+	Metrics     BacktestMetrics `json:"metrics"`
+	Trades      []Trade         `json:"trades"`
+	EquityCurve []EquityPoint   `json:"equityCurve"`
+}
+
+// ParameterGrid represents the values to sweep for each signal rule.
+// Empty dimensions keep the value from the base rules.
+type ParameterGrid struct {
+	RSIBuyBelow         []float64 `json:"rsiBuyBelow"`
+	RSISellAbove        []float64 `json:"rsiSellAbove"`
+	DiscountBelow       []float64 `json:"discountBelow"`
+	PremiumAbove        []float64 `json:"premiumAbove"`
+	EquilibriumLookback []int     `json:"equilibriumLookback"`
+}
+
+// WalkForwardConfig represents how history is split into in-sample and out-of-sample folds
+type WalkForwardConfig struct {
+	InSampleBars    int  `json:"inSampleBars"`
+	OutOfSampleBars int  `json:"outOfSampleBars"`
+	StepBars        int  `json:"stepBars"` // Defaults to OutOfSampleBars
+	Anchored        bool `json:"anchored"` // Grow the in-sample window from the start instead of rolling it
+}
+
+// OptimizationRequest represents the request for a parameter sweep
+type OptimizationRequest struct {
+	Symbol      string            `json:"symbol" binding:"required"`
+	Days        int               `json:"days"`
+	Rules       SignalRules       `json:"rules"` // Base rules; grid values override them
+	Grid        ParameterGrid     `json:"grid"`
+	WalkForward WalkForwardConfig `json:"walkForward"`
+	RankBy      string            `json:"rankBy"` // "sharpe", "return", "drawdown"
+}
+
+// ParameterSetResult represents the aggregated metrics of one parameter set across all folds
+type ParameterSetResult struct {
+	Rank                  int         `json:"rank"`
+	Rules                 SignalRules `json:"rules"`
+	InSampleSharpe        float64     `json:"inSampleSharpe"`
+	InSampleReturn        float64     `json:"inSampleReturn"`
+	OutOfSampleSharpe     float64     `json:"outOfSampleSharpe"`
+	OutOfSampleReturn     float64     `json:"outOfSampleReturn"`
+	OutOfSampleDrawdown   float64     `json:"outOfSampleDrawdown"`
+	OutOfSampleWinRate    float64     `json:"outOfSampleWinRate"`
+	OutOfSampleTrades     int         `json:"outOfSampleTrades"`
+	WalkForwardEfficiency float64     `json:"walkForwardEfficiency"` // OOS return per bar / IS return per bar
+}
+
+// OptimizationResponse represents the ranked results of a parameter sweep
+type OptimizationResponse struct {
+	Symbol      string               `json:"symbol"`
+	Folds       int                  `json:"folds"`
+	Evaluated   int                  `json:"evaluated"`
+	RankBy      string               `json:"rankBy"`
+	Results     []ParameterSetResult `json:"results"`
+	CompletedAt time.Time            `json:"completedAt"`
+}
+
+// PortfolioRequest represents the request for a portfolio-level backtest
+type PortfolioRequest struct {
+	Universe        string      `json:"universe"` // Named universe to screen; empty uses the default
+	Filter          StockFilter `json:"filter"`
+	TopN            int         `json:"topN"`
+	RankField       string      `json:"rankField"`       // Sort field used to pick the top names
+	RankOrder       string      `json:"rankOrder"`       // "asc" or "desc"
+	RebalanceEvery  int         `json:"rebalanceEvery"`  // Bars between rebalances
+	Sizing          string      `json:"sizing"`          // "equal", "volatility", "fixed"
+	RiskPerPosition float64     `json:"riskPerPosition"` // % of equity per one ATR move, for "volatility"
+	ATRPeriod       int         `json:"atrPeriod"`
+	FixedFraction   float64     `json:"fixedFraction"` // % of equity per position, for "fixed"
+	InitialCapital  float64     `json:"initialCapital"`
+	CommissionPct   float64     `json:"commissionPct"`
+	Days            int         `json:"days"`
+}
+
+// PortfolioMetrics represents summary statistics of a portfolio simulation
+type PortfolioMetrics struct {
+	TotalReturn      float64 `json:"totalReturn"`      // %
+	AnnualizedReturn float64 `json:"annualizedReturn"` // %
+	MaxDrawdown      float64 `json:"maxDrawdown"`      // %
+	SharpeRatio      float64 `json:"sharpeRatio"`
+	Rebalances       int     `json:"rebalances"`
+	AverageTurnover  float64 `json:"averageTurnover"` // Traded value as % of equity per rebalance
+	TotalTurnover    float64 `json:"totalTurnover"`
+	AverageHoldings  float64 `json:"averageHoldings"`
+}
+
+// PortfolioPoint represents the portfolio and benchmark value at a bar close
+type PortfolioPoint struct {
+	Time      string  `json:"time"`
+	Equity    float64 `json:"equity"`
+	Cash      float64 `json:"cash"`
+	Benchmark float64 `json:"benchmark"`
+}
+
+// RebalanceRecord represents the holdings chosen at a rebalance
+type RebalanceRecord struct {
+	Time     string             `json:"time"`
+	Holdings map[string]float64 `json:"holdings"` // Symbol to weight in %
+	Turnover float64            `json:"turnover"` // Traded value as % of equity
+}
+
+// PortfolioResult represents the outcome of a portfolio-level backtest
+type PortfolioResult struct {
+	Metrics     PortfolioMetrics  `json:"metrics"`
+	Benchmark   PortfolioMetrics  `json:"benchmark"` // Equal-weight across the whole universe
+	EquityCurve []PortfolioPoint  `json:"equityCurve"`
+	Rebalances  []RebalanceRecord `json:"rebalances"`
+}
+
+// MonteCarloRequest represents the request for a Monte Carlo robustness analysis
+type MonteCarloRequest struct {
+	Symbol      string      `json:"symbol" binding:"required"`
+	Days        int         `json:"days"`
+	Rules       SignalRules `json:"rules"`
+	Simulations int         `json:"simulations"`
+	Percentiles []float64   `json:"percentiles"` // Defaults to 5, 25, 50, 75, 95
+	Seed        int64       `json:"seed"`        // 0 picks a random seed
+}
+
+// PercentileValue represents a single point of a distribution
+type PercentileValue struct {
+	Percentile float64 `json:"percentile"`
+	Value      float64 `json:"value"`
+}
+
+// MonteCarloDistribution represents simulated outcomes of one resampling method
+type MonteCarloDistribution struct {
+	Method              string            `json:"method"` // "shuffle" or "bootstrap"
+	ReturnPercentiles   []PercentileValue `json:"returnPercentiles"`
+	DrawdownPercentiles []PercentileValue `json:"drawdownPercentiles"`
+	MeanReturn          float64           `json:"meanReturn"`
+	MeanDrawdown        float64           `json:"meanDrawdown"`
+	ProbabilityOfLoss   float64           `json:"probabilityOfLoss"` // % of simulations ending below the start
+}
+
+// MonteCarloResult represents the outcome of a Monte Carlo robustness analysis
+type MonteCarloResult struct {
+	Symbol      string                 `json:"symbol"`
+	Simulations int                    `json:"simulations"`
+	Seed        int64                  `json:"seed"`
+	Backtest    BacktestMetrics        `json:"backtest"`
+	Shuffle     MonteCarloDistribution `json:"shuffle"`   // Trade order reshuffled
+	Bootstrap   MonteCarloDistribution `json:"bootstrap"` // Trades resampled with replacement
+}
@@ -32,20 +32,44 @@ type StockData struct {
 	DistanceFrom52WeekHigh float64   `json:"distanceFrom52WeekHigh"`
 	DistanceFrom52WeekLow  float64   `json:"distanceFrom52WeekLow"`
 	LastUpdated            time.Time `json:"lastUpdated"`
+
+	// Cross-sectional scores computed over the whole universe on each scan.
+	// Percentiles are 0-100; sector z-scores are relative to the stock's sector.
+	RSIPercentile                float64 `json:"rsiPercentile"`
+	RSISectorZ                   float64 `json:"rsiSectorZ"`
+	PriceToEquilibriumPercentile float64 `json:"priceToEquilibriumPercentile"`
+	PriceToEquilibriumSectorZ    float64 `json:"priceToEquilibriumSectorZ"`
+	ChangePercentPercentile      float64 `json:"changePercentPercentile"`
+	ChangePercentSectorZ         float64 `json:"changePercentSectorZ"`
+	VolumePercentile             float64 `json:"volumePercentile"`
+	VolumeSectorZ                float64 `json:"volumeSectorZ"`
+	MarketCapPercentile          float64 `json:"marketCapPercentile"`
+	MarketCapSectorZ             float64 `json:"marketCapSectorZ"`
+	OpportunityScore             float64 `json:"opportunityScore"` // 0-100, higher is a deeper, more oversold discount in an intact trend
 }
 
 // StockFilter represents filtering criteria
 type StockFilter struct {
-	SearchTerm      string   `json:"searchTerm"`
-	Sectors         []string `json:"sectors"`
-	RSIMin          float64  `json:"rsiMin"`
-	RSIMax          float64  `json:"rsiMax"`
-	PriceMin        float64  `json:"priceMin"`
-	PriceMax        float64  `json:"priceMax"`
-	VolumeProfile   []string `json:"volumeProfile"`
-	Signals         []string `json:"signals"`
-	Trend           []string `json:"trend"`
-	EquilibriumZone []string `json:"equilibriumZone"`
+	SearchTerm      string        `json:"searchTerm"`
+	Sectors         []string      `json:"sectors"`
+	RSIMin          float64       `json:"rsiMin"`
+	RSIMax          float64       `json:"rsiMax"`
+	PriceMin        float64       `json:"priceMin"`
+	PriceMax        float64       `json:"priceMax"`
+	VolumeProfile   []string      `json:"volumeProfile"`
+	Signals         []string      `json:"signals"`
+	Trend           []string      `json:"trend"`
+	EquilibriumZone []string      `json:"equilibriumZone"`
+	Query           string        `json:"query"` // Screener expression, e.g. rsi < 35 and sma50 > sma200
+	Ranges          []RangeFilter `json:"ranges"`
+}
+
+// RangeFilter represents an inclusive range on any numeric StockData field.
+// A nil bound leaves that side open.
+type RangeFilter struct {
+	Field string   `json:"field"` // JSON name of the field, e.g. "marketCap"
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
 }
 
 // StockListRequest represents the request for stock data
@@ -61,36 +85,129 @@ type StockListRequest struct {
 	Signals         []string `form:"signals" json:"signals"`
 	Trend           []string `form:"trend" json:"trend"`
 	EquilibriumZone []string `form:"equilibriumZone" json:"equilibriumZone"`
+	Query           string   `form:"q" json:"q"`
+	Preset          string   `form:"preset" json:"preset"`     // Run a saved preset's filter
+	Universe        string   `form:"universe" json:"universe"` // Named universe to scan; empty uses the default
+
+	// Ranges on any numeric field, parsed from min_<field>/max_<field> parameters
+	Ranges []RangeFilter `form:"-" json:"ranges"`
 
 	// Pagination and sorting
 	SortField string `form:"sortField" json:"sortField"`
 	SortOrder string `form:"sortOrder" json:"sortOrder"` // "asc" or "desc"
+	Sort      string `form:"sort" json:"sort"`           // Multi-key, e.g. "priceToEquilibrium:asc,rsi:desc"; overrides sortField
 	Page      int    `form:"page" json:"page"`
 	PageSize  int    `form:"pageSize" json:"pageSize"`
+	Cursor    string `form:"cursor" json:"cursor"` // Opaque nextCursor of a previous page; replaces page
+
+	// Comma-separated fields to return per row; empty returns every field
+	Fields string `form:"fields" json:"fields"`
+}
+
+// SortKey represents one key of a multi-key sort
+type SortKey struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// StockPage is one page of a filtered and sorted stock list
+type StockPage struct {
+	Stocks     []StockData `json:"stocks"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	Version    int64       `json:"version"` // Snapshot version the page was read from
+	AsOf       time.Time   `json:"asOf"`    // When the snapshot's data was generated
 }
 
 // StockListResponse represents the response for stock data
 type StockListResponse struct {
-	Stocks     []StockData `json:"stocks"`
+	Stocks     interface{} `json:"stocks"` // []StockData, or rows holding only the requested fields
 	Total      int         `json:"total"`
 	Page       int         `json:"page"`
 	PageSize   int         `json:"pageSize"`
 	TotalPages int         `json:"totalPages"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+	Version    int64       `json:"version"`
+	AsOf       time.Time   `json:"asOf"`
 }
 
 // CandlestickData represents a single candlestick bar
 type CandlestickData struct {
-	Time  string  `json:"time"` // ISO 8601 date format
-	Open  float64 `json:"open"`
-	High  float64 `json:"high"`
-	Low   float64 `json:"low"`
-	Close float64 `json:"close"`
+	Time   string  `json:"time"` // ISO 8601 date, or RFC 3339 timestamp for intraday bars
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"`
 }
 
 // ChartDataResponse represents chart data for a stock
 type ChartDataResponse struct {
-	Symbol string            `json:"symbol"`
-	Data   []CandlestickData `json:"data"`
+	Symbol   string `json:"symbol"`
+	Interval string `json:"interval,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	From     string `json:"from,omitempty"` // Time of the first bar
+	To       string `json:"to,omitempty"`   // Time of the last bar
+	// BarsPerCandle is how many bars of the interval were merged into each
+	// returned bar to stay within the bar limit
+	BarsPerCandle int               `json:"barsPerCandle,omitempty"`
+	Data          []CandlestickData `json:"data,omitempty"` // Candlestick and Heikin-Ashi charts
+	// BoxSize is the price height of a Renko brick or point-and-figure box
+	BoxSize  float64             `json:"boxSize,omitempty"`
+	Reversal int                 `json:"reversal,omitempty"` // Boxes a point-and-figure column reverses on
+	Bricks   []RenkoBrick        `json:"bricks,omitempty"`
+	Columns  []PointFigureColumn `json:"columns,omitempty"`
+	// Overlays holds series aligned with Data, null where an overlay has no
+	// value yet: sma50, sma200, bbUpper, bbMiddle, bbLower and equilibrium
+	Overlays    map[string][]*float64 `json:"overlays,omitempty"`
+	Levels      []ChartLevel          `json:"levels,omitempty"`
+	Equilibrium *EquilibriumData      `json:"equilibrium,omitempty"`
+}
+
+// RenkoBrick is a brick of a Renko chart, stamped with the bar that completed it
+type RenkoBrick struct {
+	Time      string  `json:"time"`
+	Open      float64 `json:"open"`
+	Close     float64 `json:"close"`
+	Direction string  `json:"direction"` // "up" or "down"
+}
+
+// PointFigureColumn is a column of a point-and-figure chart
+type PointFigureColumn struct {
+	Type  string  `json:"type"`  // "X" for rising, "O" for falling
+	Start string  `json:"start"` // Time of the bar that started the column
+	End   string  `json:"end"`   // Time of the bar that last extended it
+	High  float64 `json:"high"`  // Price of the top box
+	Low   float64 `json:"low"`   // Price of the bottom box
+	Boxes int     `json:"boxes"`
+}
+
+// ChartLevel is a horizontal price level to annotate a chart with
+type ChartLevel struct {
+	Name  string  `json:"name"` // "support", "equilibrium" or "resistance"
+	Price float64 `json:"price"`
+}
+
+// ChartRequest selects the bars of a chart. The range is either Days back
+// from To, From to To, or the interval's whole history with MaxHistory. Days
+// counts trading sessions for intraday intervals and calendar days otherwise;
+// without a range the interval's default applies.
+type ChartRequest struct {
+	Symbol     string
+	Interval   string // 1m, 5m, 15m, 1h, 1d, 1wk or 1mo; defaults to 1d
+	Days       int
+	From       string // Date (YYYY-MM-DD) or RFC 3339 time
+	To         string // Date (YYYY-MM-DD) or RFC 3339 time; defaults to now
+	MaxHistory bool
+	MaxBars    int      // Downsampling limit; zero selects the default
+	Overlays   []string // sma50, sma200, bbands and equilibrium
+	Levels     bool     // Include support, equilibrium and resistance levels
+	Type       string   // candlestick, heikin-ashi, renko or point-and-figure
+	BoxSize    float64  // Renko and point-and-figure box size; zero sizes it by ATR
+	ATRPeriod  int      // Bars of the ATR box size; zero selects 14
+	Reversal   int      // Point-and-figure reversal in boxes; zero selects 3
+	Timezone   string   // IANA name; defaults to the exchange's
 }
 
 // PriceData represents historical price data for calculations
@@ -145,6 +262,44 @@ type Quote struct {
 	Industry      string  `json:"industry"`
 }
 
+// RefreshRequest represents the scope of a data refresh
+type RefreshRequest struct {
+	Scope    string   `json:"scope"`    // "all" (default), "symbols" or "universe"
+	Symbols  []string `json:"symbols"`  // For the "symbols" scope
+	Universe string   `json:"universe"` // For the "universe" scope; empty uses the default
+}
+
+// RefreshResult represents what a refresh regenerated
+type RefreshResult struct {
+	Scope    string   `json:"scope"`
+	Symbols  []string `json:"symbols,omitempty"`
+	Universe string   `json:"universe,omitempty"`
+}
+
+// CacheCounters counts the outcomes of cache lookups
+type CacheCounters struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Stale     uint64 `json:"stale"`     // Expired values served while reloading in the background
+	Coalesced uint64 `json:"coalesced"` // Misses that waited for another request's load
+}
+
+// CacheStats represents cache usage per data type
+type CacheStats struct {
+	Backend string                   `json:"backend"`
+	Types   map[string]CacheCounters `json:"types"`
+}
+
+// SearchResult represents a ranked symbol suggestion
+type SearchResult struct {
+	Symbol       string  `json:"symbol"`
+	Name         string  `json:"name"`
+	Sector       string  `json:"sector"`
+	Industry     string  `json:"industry"`
+	Score        float64 `json:"score"`
+	MatchedField string  `json:"matchedField"` // Field of the best matching term
+}
+
 // FilterPreset represents a saved filter configuration
 type FilterPreset struct {
 	ID          string      `json:"id"`
@@ -154,3 +309,10 @@ type FilterPreset struct {
 	CreatedAt   time.Time   `json:"createdAt"`
 	UpdatedAt   time.Time   `json:"updatedAt"`
 }
+
+// PresetRequest represents the request body for creating or updating a preset
+type PresetRequest struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Filter      StockFilter `json:"filter"`
+}
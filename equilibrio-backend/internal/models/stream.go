@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// StreamRequest is a message from a streaming client. A subscription either
+// lists symbols or, with an ID, names a screener expression over a universe.
+type StreamRequest struct {
+	Action   string   `json:"action"` // "subscribe" or "unsubscribe"
+	ID       string   `json:"id"`     // Names a screener subscription
+	Symbols  []string `json:"symbols"`
+	Universe string   `json:"universe"` // Universe a screener subscription scans; empty uses the default
+	Query    string   `json:"q"`        // Screener expression
+}
+
+// StreamMessage is a message to a streaming client
+type StreamMessage struct {
+	Type         string                 `json:"type"` // "subscribed", "unsubscribed", "tick", "indicators", "enter", "leave" or "error"
+	Subscription string                 `json:"subscription,omitempty"`
+	Symbol       string                 `json:"symbol,omitempty"`
+	Symbols      []string               `json:"symbols,omitempty"` // Symbols subscribed, or a screener's current rows
+	Stock        *StockData             `json:"stock,omitempty"`   // Rows entering a screener result
+	Changes      map[string]interface{} `json:"changes,omitempty"` // Changed fields of a tick or an indicator update
+	Version      int64                  `json:"version,omitempty"` // Snapshot the update was read from
+	AsOf         *time.Time             `json:"asOf,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// SignalEvent reports that a stock's signal, trend or equilibrium zone changed
+// between two snapshots of a universe. IDs increase with every event.
+type SignalEvent struct {
+	ID       int64     `json:"id"`
+	Type     string    `json:"type"` // "signal", "trend" or "equilibriumZone"
+	Universe string    `json:"universe"`
+	Symbol   string    `json:"symbol"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Price    float64   `json:"price"`
+	Version  int64     `json:"version"` // Snapshot the change was seen in
+	Time     time.Time `json:"time"`
+}
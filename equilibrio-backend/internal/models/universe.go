@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// UniverseSymbol represents a symbol and its reference metadata
+type UniverseSymbol struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Sector   string `json:"sector"`
+	Industry string `json:"industry"`
+	Exchange string `json:"exchange"`
+}
+
+// Universe represents a named list of symbols to scan
+type Universe struct {
+	Name        string           `json:"name"` // Identifier used by the universe parameter
+	Description string           `json:"description"`
+	Source      string           `json:"source"` // "builtin", "file" or "api"
+	SymbolCount int              `json:"symbolCount"`
+	Symbols     []UniverseSymbol `json:"symbols,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+// UniverseRequest represents the request body for creating or updating a universe
+type UniverseRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	Description string           `json:"description"`
+	Symbols     []UniverseSymbol `json:"symbols" binding:"required"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEndpoint is a URL that alerts and signal events are posted to
+type WebhookEndpoint struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`   // HMAC key of the signatures; only returned when created
+	Events    []string  `json:"events"`             // "alert", "signal", "trend" and "equilibriumZone"
+	Universe  string    `json:"universe,omitempty"` // Only events of one universe
+	Symbols   []string  `json:"symbols,omitempty"`  // Only events of these symbols
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhookEndpointRequest creates or replaces a webhook endpoint
+type WebhookEndpointRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	URL      string   `json:"url" binding:"required"`
+	Secret   string   `json:"secret"`   // Generated when creating without one, kept when updating without one
+	Events   []string `json:"events"`   // Defaults to ["alert", "signal"]
+	Universe string   `json:"universe"` // Empty sends events of every universe
+	Symbols  []string `json:"symbols"`
+	Enabled  *bool    `json:"enabled"` // Defaults to true
+}
+
+// WebhookPayload is the JSON body posted to webhook endpoints
+type WebhookPayload struct {
+	ID   string          `json:"id"`   // Delivery ID, the same across retries
+	Type string          `json:"type"` // "alert", an event type or "ping"
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"` // The Alert or SignalEvent
+}
+
+// WebhookDelivery is one payload's delivery to one endpoint and the log of
+// its attempts
+type WebhookDelivery struct {
+	ID            string           `json:"id"`
+	EndpointID    string           `json:"endpointId"`
+	URL           string           `json:"url"`
+	Type          string           `json:"type"`
+	Payload       WebhookPayload   `json:"payload"`
+	Status        string           `json:"status"` // "pending", "delivered" or "failed"
+	Attempts      []WebhookAttempt `json:"attempts"`
+	AttemptsLeft  int              `json:"attemptsLeft"` // Before the delivery fails; a retry from the dead letters starts over
+	NextAttemptAt *time.Time       `json:"nextAttemptAt,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+}
+
+// WebhookAttempt is one HTTP request of a delivery
+type WebhookAttempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
@@ -0,0 +1,519 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+var (
+	// ErrAlertRuleNotFound is returned when no alert rule has the requested ID
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	// ErrInvalidAlertRule is returned when an alert rule definition is unusable
+	ErrInvalidAlertRule = errors.New("invalid alert rule")
+)
+
+// Alert rule limits and defaults
+const (
+	defaultAlertCooldown = time.Hour
+	maxAlertCooldown     = 7 * 24 * time.Hour
+	defaultAlertChannel  = "log"
+	defaultAlertsLimit   = 100
+	maxAlertsLimit       = 1000
+	alertFlushInterval   = time.Second
+)
+
+// AlertChannel delivers fired alerts somewhere
+type AlertChannel func(alert models.Alert)
+
+// alertState is what the evaluator remembers of a rule between snapshots
+type alertState struct {
+	updatedAt time.Time            // Version of the rule the state belongs to
+	active    map[string]bool      // Targeted symbols whose condition held
+	lastFired map[string]time.Time // Last alert per symbol, for the cooldown
+}
+
+// AlertService stores alert rules and evaluates them on every snapshot the
+// market data service builds, in the background so that building snapshots
+// never waits on rules or channels. Alerts are edge-triggered: a rule fires
+// for a stock when its condition turns true, not while it stays true. The
+// first evaluation of a new or changed rule only records which conditions
+// hold. Fired alerts are kept in a bounded history, saved in batches, and
+// delivered to the rule's channels.
+type AlertService struct {
+	rules        *jsonStore[models.AlertRule]
+	history      *jsonStore[models.Alert]
+	historyLimit int
+	marketData   *MarketDataService
+	interval     time.Duration
+
+	mu     sync.Mutex // Serializes evaluations
+	states map[string]*alertState
+
+	channelsMu sync.RWMutex
+	channels   map[string]AlertChannel
+
+	pendingMu sync.Mutex
+	pending   map[string]*Snapshot // Latest unevaluated snapshot per universe
+
+	ready chan struct{} // Signals pending snapshots
+	kick  chan struct{} // Asks the poller to read the watched universes now
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewAlertService loads the stored rules and history and starts evaluating
+// the rules. Unless the interval is zero, it also polls the universes rules
+// watch so their snapshots keep being rebuilt, and right after every refresh.
+func NewAlertService(cfg *config.Config, marketData *MarketDataService) (*AlertService, error) {
+	rules, err := newJSONStore[models.AlertRule](cfg.AlertRulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert rules: %w", err)
+	}
+	history, err := newJSONStore[models.Alert](cfg.AlertHistoryFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert history: %w", err)
+	}
+
+	a := &AlertService{
+		rules:        rules,
+		history:      history,
+		historyLimit: cfg.AlertHistoryLimit,
+		marketData:   marketData,
+		interval:     cfg.AlertsInterval,
+		states:       make(map[string]*alertState),
+		channels:     make(map[string]AlertChannel),
+		pending:      make(map[string]*Snapshot),
+		ready:        make(chan struct{}, 1),
+		kick:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if a.historyLimit <= 0 {
+		a.historyLimit = 1000
+	}
+	a.RegisterChannel(defaultAlertChannel, func(alert models.Alert) {
+		log.Printf("Alert %q fired for %s at %.2f: %s %v", alert.RuleName, alert.Symbol, alert.Price, alert.Condition, alert.Values)
+	})
+
+	marketData.OnSnapshot(a.evaluate)
+	marketData.OnRefresh(func(models.RefreshResult) {
+		select {
+		case a.kick <- struct{}{}:
+		default:
+		}
+	})
+	go a.run()
+	return a, nil
+}
+
+// Close evaluates the pending snapshots, stops evaluating rules and saves the
+// history
+func (a *AlertService) Close() {
+	close(a.stop)
+	<-a.done
+}
+
+// RegisterChannel makes a delivery channel available to rules
+func (a *AlertService) RegisterChannel(name string, channel AlertChannel) {
+	a.channelsMu.Lock()
+	defer a.channelsMu.Unlock()
+	a.channels[name] = channel
+}
+
+func (a *AlertService) channel(name string) (AlertChannel, bool) {
+	a.channelsMu.RLock()
+	defer a.channelsMu.RUnlock()
+	channel, ok := a.channels[name]
+	return channel, ok
+}
+
+// run evaluates pending snapshots, polls the watched universes and saves the
+// history until the service is closed
+func (a *AlertService) run() {
+	defer close(a.done)
+
+	var poll <-chan time.Time
+	if a.interval > 0 {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	flush := time.NewTicker(alertFlushInterval)
+	defer flush.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			a.evaluatePending()
+			a.flush()
+			return
+		case <-poll:
+			a.poll()
+		case <-a.kick:
+			if a.interval > 0 {
+				a.poll()
+			}
+		case <-a.ready:
+		case <-flush.C:
+			a.flush()
+			continue
+		}
+		a.evaluatePending()
+	}
+}
+
+// poll reads the snapshots of the watched universes, which rebuilds the
+// stale ones and hands them to evaluate
+func (a *AlertService) poll() {
+	for _, universe := range a.watchedUniverses() {
+		if _, err := a.marketData.currentSnapshot(universe); err != nil {
+			log.Printf("Alerts: failed to read universe %s: %v", universe, err)
+		}
+	}
+}
+
+// flush saves the alerts recorded since the last save
+func (a *AlertService) flush() {
+	if err := a.history.flush(); err != nil {
+		log.Printf("Alerts: failed to save history: %v", err)
+	}
+}
+
+// watchedUniverses returns the universes of the enabled rules
+func (a *AlertService) watchedUniverses() []string {
+	universes := make(map[string]bool)
+	for _, rule := range a.rules.list() {
+		if rule.Enabled {
+			universes[rule.Universe] = true
+		}
+	}
+	return sortedKeys(universes)
+}
+
+// ListRules returns all rules ordered by name
+func (a *AlertService) ListRules() []models.AlertRule {
+	rules := a.rules.list()
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Name != rules[j].Name {
+			return rules[i].Name < rules[j].Name
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// GetRule returns a rule by ID
+func (a *AlertService) GetRule(id string) (*models.AlertRule, error) {
+	rule, ok := a.rules.get(id)
+	if !ok {
+		return nil, ErrAlertRuleNotFound
+	}
+	return &rule, nil
+}
+
+// CreateRule validates and stores a new rule
+func (a *AlertService) CreateRule(req models.AlertRuleRequest) (*models.AlertRule, error) {
+	now := time.Now()
+	rule := models.AlertRule{ID: newID(), CreatedAt: now}
+	if err := a.apply(&rule, req); err != nil {
+		return nil, err
+	}
+	rule.UpdatedAt = now
+
+	if err := a.rules.put(rule.ID, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule validates and replaces an existing rule. Its conditions are
+// re-baselined on the next evaluation.
+func (a *AlertService) UpdateRule(id string, req models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule, ok := a.rules.get(id)
+	if !ok {
+		return nil, ErrAlertRuleNotFound
+	}
+	if err := a.apply(&rule, req); err != nil {
+		return nil, err
+	}
+	rule.UpdatedAt = time.Now()
+
+	if err := a.rules.put(id, rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteRule removes a rule; its alerts stay in the history
+func (a *AlertService) DeleteRule(id string) error {
+	deleted, err := a.rules.delete(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAlertRuleNotFound
+	}
+	a.mu.Lock()
+	delete(a.states, id)
+	a.mu.Unlock()
+	return nil
+}
+
+// apply validates a request and copies it onto a rule
+func (a *AlertService) apply(rule *models.AlertRule, req models.AlertRuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAlertRule)
+	}
+	if _, err := ParseScreenerQuery(req.Condition); err != nil {
+		return err
+	}
+	if err := a.marketData.ValidateFilter(req.Filter); err != nil {
+		return err
+	}
+	// Unset legacy ranges mean no restriction, as for stock list requests
+	DefaultFilterBounds(&req.Filter.RSIMax, &req.Filter.PriceMax)
+
+	universe, err := a.marketData.universes.GetUniverse(req.Universe)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAlertRule, err)
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol != "" && !universeHasSymbol(universe, symbol) {
+		return fmt.Errorf("%w: %s is not in universe %s", ErrInvalidAlertRule, symbol, universe.Name)
+	}
+
+	cooldown := defaultAlertCooldown
+	if req.CooldownSeconds != nil {
+		cooldown = time.Duration(*req.CooldownSeconds) * time.Second
+	}
+	if cooldown < 0 || cooldown > maxAlertCooldown {
+		return fmt.Errorf("%w: cooldownSeconds must be between 0 and %d", ErrInvalidAlertRule, int(maxAlertCooldown.Seconds()))
+	}
+
+	channels := req.Channels
+	if len(channels) == 0 {
+		channels = []string{defaultAlertChannel}
+	}
+	for _, name := range channels {
+		if _, ok := a.channel(name); !ok {
+			return fmt.Errorf("%w: unknown channel %q", ErrInvalidAlertRule, name)
+		}
+	}
+
+	rule.Name = name
+	rule.Universe = universe.Name
+	rule.Symbol = symbol
+	rule.Filter = req.Filter
+	rule.Condition = req.Condition
+	rule.CooldownSeconds = int(cooldown.Seconds())
+	rule.Channels = channels
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+func universeHasSymbol(universe *models.Universe, symbol string) bool {
+	for _, s := range universe.Symbols {
+		if strings.EqualFold(s.Symbol, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListAlerts returns fired alerts, newest first, optionally of one rule or
+// symbol, up to limit (100 when zero)
+func (a *AlertService) ListAlerts(ruleID, symbol string, limit int) ([]models.Alert, error) {
+	if limit == 0 {
+		limit = defaultAlertsLimit
+	}
+	if limit < 0 || limit > maxAlertsLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidAlertRule, maxAlertsLimit)
+	}
+
+	symbol = strings.ToUpper(symbol)
+	alerts := []models.Alert{}
+	for _, alert := range a.history.list() {
+		if (ruleID == "" || alert.RuleID == ruleID) && (symbol == "" || alert.Symbol == symbol) {
+			alerts = append(alerts, alert)
+		}
+	}
+	sortAlerts(alerts)
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+// sortAlerts orders alerts newest first
+func sortAlerts(alerts []models.Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		if !alerts[i].Time.Equal(alerts[j].Time) {
+			return alerts[i].Time.After(alerts[j].Time)
+		}
+		if alerts[i].RuleID != alerts[j].RuleID {
+			return alerts[i].RuleID < alerts[j].RuleID
+		}
+		return alerts[i].Symbol < alerts[j].Symbol
+	})
+}
+
+// evaluate queues a snapshot for the background evaluation, replacing an
+// older one of the same universe that was not evaluated yet
+func (a *AlertService) evaluate(snapshot *Snapshot) {
+	a.pendingMu.Lock()
+	if queued, ok := a.pending[snapshot.Universe]; !ok || queued.Version <= snapshot.Version {
+		a.pending[snapshot.Universe] = snapshot
+	}
+	a.pendingMu.Unlock()
+
+	select {
+	case a.ready <- struct{}{}:
+	default:
+	}
+}
+
+// evaluatePending evaluates the queued snapshots in universe order
+func (a *AlertService) evaluatePending() {
+	a.pendingMu.Lock()
+	pending := a.pending
+	a.pending = make(map[string]*Snapshot)
+	a.pendingMu.Unlock()
+
+	universes := make([]string, 0, len(pending))
+	for universe := range pending {
+		universes = append(universes, universe)
+	}
+	sort.Strings(universes)
+	for _, universe := range universes {
+		a.check(pending[universe])
+	}
+}
+
+// check evaluates the enabled rules of a snapshot's universe and fires an
+// alert for every targeted stock whose condition turned true outside its
+// cooldown. Rules are stored per replica, so snapshots replicated from other
+// replicas are evaluated like local ones.
+func (a *AlertService) check(snapshot *Snapshot) {
+	a.mu.Lock()
+	now := time.Now().UTC()
+	var fired []models.Alert
+	for _, rule := range a.rules.list() {
+		if !rule.Enabled || rule.Universe != snapshot.Universe {
+			continue
+		}
+		alerts, err := a.evaluateRule(rule, snapshot, now)
+		if err != nil {
+			log.Printf("Alerts: failed to evaluate rule %s: %v", rule.ID, err)
+			continue
+		}
+		fired = append(fired, alerts...)
+	}
+	if len(fired) > 0 {
+		a.record(fired)
+	}
+	a.mu.Unlock()
+
+	for _, alert := range fired {
+		for _, name := range alert.Channels {
+			channel, ok := a.channel(name)
+			if !ok {
+				log.Printf("Alerts: rule %s delivers to unknown channel %q", alert.RuleID, name)
+				continue
+			}
+			channel(alert)
+		}
+	}
+}
+
+// evaluateRule updates a rule's state with a snapshot and returns the alerts
+// it fires. Callers must hold the lock.
+func (a *AlertService) evaluateRule(rule models.AlertRule, snapshot *Snapshot, now time.Time) ([]models.Alert, error) {
+	condition, err := ParseScreenerQuery(rule.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []models.StockData
+	if rule.Symbol != "" {
+		if stock, ok := snapshot.Stock(rule.Symbol); ok {
+			targets = []models.StockData{stock}
+		}
+	} else {
+		query, err := parseFilterQuery(rule.Filter)
+		if err != nil {
+			return nil, err
+		}
+		targets = a.marketData.applyFilters(snapshot.Stocks(), rule.Filter, query)
+	}
+
+	state := a.states[rule.ID]
+	baseline := state == nil || !state.updatedAt.Equal(rule.UpdatedAt)
+	if baseline {
+		state = &alertState{updatedAt: rule.UpdatedAt, lastFired: make(map[string]time.Time)}
+		a.states[rule.ID] = state
+	}
+
+	cooldown := time.Duration(rule.CooldownSeconds) * time.Second
+	active := make(map[string]bool)
+	var alerts []models.Alert
+	for i := range targets {
+		stock := &targets[i]
+		if !condition.Match(stock) {
+			continue
+		}
+		active[stock.Symbol] = true
+		if baseline || state.active[stock.Symbol] {
+			continue
+		}
+		if last, ok := state.lastFired[stock.Symbol]; ok && now.Sub(last) < cooldown {
+			continue
+		}
+		state.lastFired[stock.Symbol] = now
+		alerts = append(alerts, models.Alert{
+			ID:        newID(),
+			RuleID:    rule.ID,
+			RuleName:  rule.Name,
+			Universe:  snapshot.Universe,
+			Symbol:    stock.Symbol,
+			Condition: rule.Condition,
+			Values:    condition.Values(stock),
+			Price:     stock.Price,
+			Version:   snapshot.Version,
+			Channels:  rule.Channels,
+			Time:      now,
+		})
+	}
+	state.active = active
+	return alerts, nil
+}
+
+// record adds alerts to the history, dropping the oldest beyond the limit;
+// flush saves them. Callers must hold the lock.
+func (a *AlertService) record(alerts []models.Alert) {
+	if len(alerts) > a.historyLimit {
+		alerts = alerts[:a.historyLimit]
+	}
+	put := make(map[string]models.Alert, len(alerts))
+	for _, alert := range alerts {
+		put[alert.ID] = alert
+	}
+
+	var remove []string
+	existing := a.history.list()
+	if excess := len(existing) + len(alerts) - a.historyLimit; excess > 0 {
+		sortAlerts(existing)
+		for i := len(existing) - 1; i >= 0 && len(remove) < excess; i-- {
+			remove = append(remove, existing[i].ID)
+		}
+	}
+	a.history.stage(put, remove)
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestAlertService creates an alert service that does not poll, so that
+// snapshots are only evaluated when tests hand them over
+func newTestAlertService(t *testing.T) *AlertService {
+	cfg, marketData := newTestMarketDataService(t)
+	dir := t.TempDir()
+	cfg.AlertRulesFile = filepath.Join(dir, "alert_rules.json")
+	cfg.AlertHistoryFile = filepath.Join(dir, "alert_history.json")
+	cfg.AlertHistoryLimit = 3
+	alerts, err := NewAlertService(cfg, marketData)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(alerts.Close)
+	return alerts
+}
+
+// rsiSnapshot builds a snapshot of a universe from RSI values per symbol,
+// in symbol order so that alerts fire in a fixed order
+func rsiSnapshot(universe string, rsi map[string]float64) *Snapshot {
+	symbols := make([]string, 0, len(rsi))
+	for symbol := range rsi {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var stocks []models.StockData
+	for _, symbol := range symbols {
+		stocks = append(stocks, models.StockData{Symbol: symbol, Sector: "Technology", Price: 100, RSI: rsi[symbol]})
+	}
+	return newSnapshot(universe, time.Time{}, stocks, time.Now())
+}
+
+// TestAlertsEdgeTriggered tests that rules fire when a condition turns true, once per cooldown
+func TestAlertsEdgeTriggered(t *testing.T) {
+	alerts := newTestAlertService(t)
+
+	var delivered []models.Alert
+	alerts.RegisterChannel("test", func(alert models.Alert) { delivered = append(delivered, alert) })
+
+	cooldown := 3600
+	rule, err := alerts.CreateRule(models.AlertRuleRequest{
+		Name:            "AAPL oversold",
+		Symbol:          "aapl",
+		Condition:       "rsi < 30",
+		CooldownSeconds: &cooldown,
+		Channels:        []string{"test"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	universe := rule.Universe
+
+	// Already true when first seen: only the baseline
+	alerts.check(rsiSnapshot(universe, map[string]float64{"AAPL": 25}))
+	alerts.check(rsiSnapshot(universe, map[string]float64{"AAPL": 40}))
+	alerts.check(rsiSnapshot(universe, map[string]float64{"AAPL": 28}))
+	alerts.check(rsiSnapshot(universe, map[string]float64{"AAPL": 27})) // Still true
+	alerts.check(rsiSnapshot(universe, map[string]float64{"AAPL": 45}))
+	alerts.check(rsiSnapshot(universe, map[string]float64{"AAPL": 20})) // Within the cooldown
+
+	if len(delivered) != 1 {
+		t.Fatalf("Expected one alert, got %+v", delivered)
+	}
+	alert := delivered[0]
+	if alert.RuleID != rule.ID || alert.Symbol != "AAPL" || alert.Values["rsi"] != 28.0 {
+		t.Errorf("Expected AAPL's alert at RSI 28, got %+v", alert)
+	}
+
+	history, err := alerts.ListAlerts(rule.ID, "", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history) != 1 || history[0].ID != alert.ID {
+		t.Errorf("Expected the alert in the history, got %+v", history)
+	}
+}
+
+// TestAlertsFilterRule tests rules over the stocks matching a filter, and the history limit
+func TestAlertsFilterRule(t *testing.T) {
+	alerts := newTestAlertService(t)
+
+	noCooldown := 0
+	rule, err := alerts.CreateRule(models.AlertRuleRequest{
+		Name:            "Oversold tech",
+		Filter:          models.StockFilter{Sectors: []string{"Technology"}},
+		Condition:       "rsi < 30",
+		CooldownSeconds: &noCooldown,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rule.Channels) != 1 || rule.Channels[0] != defaultAlertChannel || !rule.Enabled {
+		t.Errorf("Expected an enabled rule delivering to the log, got %+v", rule)
+	}
+
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAA": 50, "BBB": 50, "CCC": 50}))
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAA": 20, "BBB": 20, "CCC": 50}))
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAA": 50, "BBB": 20, "CCC": 20}))
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAA": 20, "BBB": 20, "CCC": 20}))
+
+	history, err := alerts.ListAlerts("", "", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// AAA and BBB fired, then CCC, then AAA again; the limit keeps the last
+	// three, dropping BBB as alerts of one snapshot are ordered by symbol
+	if len(history) != 3 {
+		t.Fatalf("Expected the history limited to 3 alerts, got %d", len(history))
+	}
+	if history[0].Symbol != "AAA" {
+		t.Errorf("Expected the newest alert first, got %+v", history[0])
+	}
+	if bbb, _ := alerts.ListAlerts("", "bbb", 0); len(bbb) != 0 {
+		t.Errorf("Expected BBB's alert dropped, got %+v", bbb)
+	}
+
+}
+
+// TestAlertsReplicatedSnapshots tests that a rule created on one replica fires
+// for the snapshots another replica builds
+func TestAlertsReplicatedSnapshots(t *testing.T) {
+	server := miniredis.RunT(t)
+	first, _ := newTestReplica(t, server)
+	second, _ := newTestReplica(t, server)
+
+	cfg, _ := newTestMarketDataService(t)
+	cfg.AlertRulesFile = filepath.Join(t.TempDir(), "alert_rules.json")
+	cfg.AlertHistoryFile = filepath.Join(t.TempDir(), "alert_history.json")
+	alerts, err := NewAlertService(cfg, first)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(alerts.Close)
+
+	rule, err := alerts.CreateRule(models.AlertRuleRequest{
+		Name:      "AAPL has an RSI",
+		Symbol:    "AAPL",
+		Condition: "rsi <= 100",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A baseline where the condition does not hold, so the next snapshot fires
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAPL": 200}))
+
+	if _, err := second.Refresh(models.RefreshRequest{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := second.currentSnapshot(rule.Universe); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	eventually(t, "the alert", func() bool {
+		fired, _ := alerts.ListAlerts(rule.ID, "", 0)
+		return len(fired) == 1
+	})
+}
+
+// TestAlertsEvaluateInBackground tests that snapshots handed over by the
+// market data service are evaluated off its path, and that the history is
+// saved when the service closes
+func TestAlertsEvaluateInBackground(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	cfg.AlertRulesFile = filepath.Join(t.TempDir(), "alert_rules.json")
+	cfg.AlertHistoryFile = filepath.Join(t.TempDir(), "alert_history.json")
+	alerts, err := NewAlertService(cfg, marketData)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var mu sync.Mutex
+	var delivered []models.Alert
+	alerts.RegisterChannel("test", func(alert models.Alert) {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, alert)
+	})
+
+	rule, err := alerts.CreateRule(models.AlertRuleRequest{
+		Name:      "AAPL oversold",
+		Symbol:    "AAPL",
+		Condition: "rsi < 30",
+		Channels:  []string{"test"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAPL": 40}))
+	alerts.evaluate(rsiSnapshot(rule.Universe, map[string]float64{"AAPL": 20}))
+	eventually(t, "the alert", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(delivered) == 1
+	})
+
+	alerts.Close()
+	history, err := newJSONStore[models.Alert](cfg.AlertHistoryFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if saved := history.list(); len(saved) != 1 || saved[0].ID != delivered[0].ID {
+		t.Errorf("Expected the alert saved on close, got %+v", saved)
+	}
+}
+
+// TestAlertRuleValidation tests rejected rule definitions
+func TestAlertRuleValidation(t *testing.T) {
+	alerts := newTestAlertService(t)
+
+	negative := -1
+	for _, req := range []models.AlertRuleRequest{
+		{Name: " ", Condition: "rsi < 30"},
+		{Name: "Unknown symbol", Symbol: "NOPE", Condition: "rsi < 30"},
+		{Name: "Unknown universe", Universe: "nope", Condition: "rsi < 30"},
+		{Name: "Unknown channel", Condition: "rsi < 30", Channels: []string{"pager"}},
+		{Name: "Negative cooldown", Condition: "rsi < 30", CooldownSeconds: &negative},
+	} {
+		if _, err := alerts.CreateRule(req); !errors.Is(err, ErrInvalidAlertRule) {
+			t.Errorf("Expected ErrInvalidAlertRule for %+v, got %v", req, err)
+		}
+	}
+
+	var queryErr *QueryError
+	if _, err := alerts.CreateRule(models.AlertRuleRequest{Name: "Bad", Condition: "rsi <"}); !errors.As(err, &queryErr) {
+		t.Errorf("Expected a QueryError, got %v", err)
+	}
+	if _, err := alerts.UpdateRule("missing", models.AlertRuleRequest{Name: "x", Condition: "rsi < 30"}); !errors.Is(err, ErrAlertRuleNotFound) {
+		t.Errorf("Expected ErrAlertRuleNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"equilibrio-backend/internal/models"
+)
+
+// ErrInvalidBacktestConfig is returned when backtest or optimization parameters are unusable
+var ErrInvalidBacktestConfig = errors.New("invalid backtest configuration")
+
+const (
+	defaultBacktestDays = 730
+	maxBacktestDays     = 3650
+	barsPerYear         = 252
+)
+
+type BacktestService struct {
+	marketDataService *MarketDataService
+	indicatorService  *IndicatorService
+}
+
+func NewBacktestService(marketDataService *MarketDataService, indicatorService *IndicatorService) *BacktestService {
+	return &BacktestService{
+		marketDataService: marketDataService,
+		indicatorService:  indicatorService,
+	}
+}
+
+// Backtest runs the signal rules over the price history of a single symbol
+func (s *BacktestService) Backtest(req models.BacktestRequest) (*models.BacktestResult, error) {
+	rules := withDefaultRules(req.Rules)
+
+	prices, err := s.loadHistory(req.Symbol, req.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	run := simulate(prices, newSignalSeries(prices, rules), rules, 0, len(prices))
+
+	curve := make([]models.EquityPoint, len(run.equity))
+	for i, equity := range run.equity {
+		curve[i] = models.EquityPoint{
+			Time:   prices[i].Time,
+			Equity: equity,
+		}
+	}
+
+	return &models.BacktestResult{
+		Symbol:      req.Symbol,
+		Rules:       rules,
+		Metrics:     run.metrics(),
+		Trades:      run.trades,
+		EquityCurve: curve,
+	}, nil
+}
+
+// loadHistory fetches daily candles for a symbol and validates the requested length
+func (s *BacktestService) loadHistory(symbol string, days int) ([]models.CandlestickData, error) {
+	if days == 0 {
+		days = defaultBacktestDays
+	}
+	if days < 0 || days > maxBacktestDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidBacktestConfig, maxBacktestDays)
+	}
+
+	chart, err := s.marketDataService.GetStockChartWithDays(symbol, days)
+	if err != nil {
+		return nil, err
+	}
+	return chart.Data, nil
+}
+
+// withDefaultRules fills zero-valued rules with the thresholds used by DetermineSignal
+func withDefaultRules(rules models.SignalRules) models.SignalRules {
+	if rules.RSIPeriod <= 0 {
+		rules.RSIPeriod = 14
+	}
+	if rules.RSIBuyBelow == 0 {
+		rules.RSIBuyBelow = 40
+	}
+	if rules.RSISellAbove == 0 {
+		rules.RSISellAbove = 70
+	}
+	if rules.EquilibriumLookback <= 0 {
+		rules.EquilibriumLookback = 120
+	}
+	if rules.DiscountBelow == 0 {
+		rules.DiscountBelow = 10
+	}
+	if rules.PremiumAbove == 0 {
+		rules.PremiumAbove = 10
+	}
+	return rules
+}
+
+// signalSeries holds the indicator values the rules are evaluated against
+type signalSeries struct {
+	rsi         []float64
+	equilibrium []float64
+}
+
+func newSignalSeries(prices []models.CandlestickData, rules models.SignalRules) signalSeries {
+	closes := make([]float64, len(prices))
+	for i, p := range prices {
+		closes[i] = p.Close
+	}
+
+	return signalSeries{
+		rsi:         rsiSeries(closes, rules.RSIPeriod),
+		equilibrium: equilibriumSeries(prices, rules.EquilibriumLookback),
+	}
+}
+
+// backtestRun holds the raw output of a simulation over a window of bars
+type backtestRun struct {
+	trades []models.Trade
+	equity []float64 // Mark-to-market equity at each bar close, starting from 1.0
+}
+
+// simulate trades a single long-only position over bars [from, to).
+// Signals are evaluated on the bar close and filled at the next bar open so
+// that a decision never uses prices it could not have seen. Indicators are
+// computed over the whole history, which lets out-of-sample windows start
+// with warmed-up values without peeking forward.
+func simulate(prices []models.CandlestickData, series signalSeries, rules models.SignalRules, from, to int) backtestRun {
+	commission := rules.CommissionPct / 100
+
+	run := backtestRun{equity: make([]float64, 0, to-from)}
+	cash := 1.0
+	units := 0.0
+	inPosition := false
+	pendingEntry, pendingExit := false, false
+	var entryPrice float64
+	var entryIndex int
+
+	closePosition := func(i int, price float64) {
+		exitPrice := price * (1 - commission)
+		cash = units * exitPrice
+		run.trades = append(run.trades, models.Trade{
+			EntryTime:  prices[entryIndex].Time,
+			ExitTime:   prices[i].Time,
+			EntryPrice: entryPrice,
+			ExitPrice:  exitPrice,
+			ReturnPct:  (exitPrice/entryPrice - 1) * 100,
+			Bars:       i - entryIndex,
+		})
+		units = 0
+		inPosition = false
+	}
+
+	for i := from; i < to; i++ {
+		bar := prices[i]
+
+		if pendingEntry && !inPosition {
+			entryPrice = bar.Open * (1 + commission)
+			units = cash / entryPrice
+			entryIndex = i
+			inPosition = true
+		} else if pendingExit && inPosition {
+			closePosition(i, bar.Open)
+		}
+		pendingEntry, pendingExit = false, false
+
+		if inPosition {
+			run.equity = append(run.equity, units*bar.Close)
+		} else {
+			run.equity = append(run.equity, cash)
+		}
+
+		rsi, equilibrium := series.rsi[i], series.equilibrium[i]
+		if math.IsNaN(rsi) || math.IsNaN(equilibrium) || equilibrium == 0 {
+			continue
+		}
+		priceToEquilibrium := (bar.Close - equilibrium) / equilibrium * 100
+
+		if !inPosition && rsi < rules.RSIBuyBelow && priceToEquilibrium < -rules.DiscountBelow {
+			pendingEntry = true
+		} else if inPosition && (rsi > rules.RSISellAbove || priceToEquilibrium > rules.PremiumAbove) {
+			pendingExit = true
+		}
+	}
+
+	// Close any open position at the last close of the window
+	if inPosition {
+		closePosition(to-1, prices[to-1].Close)
+		run.equity[len(run.equity)-1] = cash
+	}
+
+	return run
+}
+
+// barReturns converts the equity curve into simple per-bar returns
+func (r backtestRun) barReturns() []float64 {
+	if len(r.equity) == 0 {
+		return nil
+	}
+	returns := make([]float64, len(r.equity))
+	prev := 1.0
+	for i, equity := range r.equity {
+		returns[i] = equity/prev - 1
+		prev = equity
+	}
+	return returns
+}
+
+// totalReturn returns the compounded return of the run in percent
+func (r backtestRun) totalReturn() float64 {
+	if len(r.equity) == 0 {
+		return 0
+	}
+	return (r.equity[len(r.equity)-1] - 1) * 100
+}
+
+// metrics summarizes the run
+func (r backtestRun) metrics() models.BacktestMetrics {
+	metrics := models.BacktestMetrics{
+		TotalReturn: r.totalReturn(),
+		MaxDrawdown: maxDrawdown(r.equity),
+		SharpeRatio: sharpeRatio(r.barReturns()),
+		NumTrades:   len(r.trades),
+		Bars:        len(r.equity),
+	}
+
+	metrics.WinRate, metrics.ProfitFactor = tradeStats(r.trades)
+	return metrics
+}
+
+// tradeStats returns the win rate in percent and the profit factor.
+// The profit factor is 0 when there are no losing trades.
+func tradeStats(trades []models.Trade) (float64, float64) {
+	if len(trades) == 0 {
+		return 0, 0
+	}
+
+	wins := 0
+	grossProfit, grossLoss := 0.0, 0.0
+	for _, trade := range trades {
+		if trade.ReturnPct > 0 {
+			wins++
+			grossProfit += trade.ReturnPct
+		} else {
+			grossLoss -= trade.ReturnPct
+		}
+	}
+
+	profitFactor := 0.0
+	if grossLoss > 0 {
+		profitFactor = grossProfit / grossLoss
+	}
+	return float64(wins) / float64(len(trades)) * 100, profitFactor
+}
+
+// maxDrawdown returns the largest peak-to-trough decline of an equity curve in percent
+func maxDrawdown(equity []float64) float64 {
+	peak, worst := 0.0, 0.0
+	for _, value := range equity {
+		if value > peak {
+			peak = value
+		}
+		if peak > 0 {
+			worst = math.Max(worst, (peak-value)/peak*100)
+		}
+	}
+	return worst
+}
+
+// sharpeRatio annualizes the mean-over-deviation of per-bar returns
+func sharpeRatio(returns []float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)-1))
+	if stdDev == 0 {
+		return 0
+	}
+
+	return mean / stdDev * math.Sqrt(barsPerYear)
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+// generateWaveChartData generates a deterministic oscillating price series
+func generateWaveChartData(days int) []models.CandlestickData {
+	data := make([]models.CandlestickData, days)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < days; i++ {
+		price := 100 + 20*math.Sin(float64(i)/8)
+		prev := 100 + 20*math.Sin(float64(i-1)/8)
+		data[i] = models.CandlestickData{
+			Time:  start.AddDate(0, 0, i).Format("2006-01-02"),
+			Open:  prev,
+			High:  math.Max(prev, price) + 0.5,
+			Low:   math.Min(prev, price) - 0.5,
+			Close: price,
+		}
+	}
+
+	return data
+}
+
+// TestSimulateTradesOscillation tests that the rules trade a mean-reverting series
+func TestSimulateTradesOscillation(t *testing.T) {
+	prices := generateWaveChartData(300)
+	rules := withDefaultRules(models.SignalRules{EquilibriumLookback: 60})
+
+	run := simulate(prices, newSignalSeries(prices, rules), rules, 0, len(prices))
+
+	if len(run.equity) != len(prices) {
+		t.Fatalf("Expected %d equity points, got %d", len(prices), len(run.equity))
+	}
+	if len(run.trades) == 0 {
+		t.Fatalf("Expected trades on an oscillating series")
+	}
+
+	for _, trade := range run.trades {
+		if trade.ExitTime < trade.EntryTime {
+			t.Errorf("Trade exits before it enters: %+v", trade)
+		}
+	}
+
+	metrics := run.metrics()
+	if metrics.MaxDrawdown < 0 || metrics.MaxDrawdown > 100 {
+		t.Errorf("Drawdown should be between 0 and 100, got %f", metrics.MaxDrawdown)
+	}
+}
+
+// TestSimulateFillsNextBar tests that a signal is never filled on the bar that produced it
+func TestSimulateFillsNextBar(t *testing.T) {
+	prices := generateWaveChartData(300)
+	rules := withDefaultRules(models.SignalRules{EquilibriumLookback: 60})
+	series := newSignalSeries(prices, rules)
+
+	run := simulate(prices, series, rules, 0, len(prices))
+
+	for _, trade := range run.trades {
+		for i, bar := range prices {
+			if bar.Time == trade.EntryTime && trade.EntryPrice != prices[i].Open {
+				t.Errorf("Expected entry at open %f, got %f", prices[i].Open, trade.EntryPrice)
+			}
+		}
+	}
+}
+
+// TestWalkForwardFolds tests rolling and anchored fold construction
+func TestWalkForwardFolds(t *testing.T) {
+	folds, err := walkForwardFolds(300, models.WalkForwardConfig{InSampleBars: 100, OutOfSampleBars: 50})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(folds) != 4 {
+		t.Fatalf("Expected 4 folds, got %d", len(folds))
+	}
+	for _, fold := range folds {
+		if fold.inSampleTo != fold.outOfSampleFrom {
+			t.Errorf("Out-of-sample window should follow in-sample window: %+v", fold)
+		}
+	}
+
+	anchored, _ := walkForwardFolds(300, models.WalkForwardConfig{InSampleBars: 100, OutOfSampleBars: 50, Anchored: true})
+	if anchored[len(anchored)-1].inSampleFrom != 0 {
+		t.Errorf("Anchored folds should start at bar 0")
+	}
+
+	if _, err := walkForwardFolds(100, models.WalkForwardConfig{InSampleBars: 100, OutOfSampleBars: 50}); err == nil {
+		t.Errorf("Expected an error when history is shorter than one fold")
+	}
+}
+
+// TestExpandGrid tests the cartesian product of the parameter grid
+func TestExpandGrid(t *testing.T) {
+	base := withDefaultRules(models.SignalRules{})
+	sets := expandGrid(base, models.ParameterGrid{
+		RSIBuyBelow:   []float64{30, 35, 40},
+		DiscountBelow: []float64{5, 10},
+	})
+
+	if len(sets) != 6 {
+		t.Fatalf("Expected 6 parameter sets, got %d", len(sets))
+	}
+	for _, set := range sets {
+		if set.RSISellAbove != base.RSISellAbove {
+			t.Errorf("Unswept dimensions should keep the base value")
+		}
+	}
+}
+
+// TestEvaluateParameterSetsConcurrently tests that the worker pool returns
+// the same results, in the same order, as evaluating each set on its own
+func TestEvaluateParameterSetsConcurrently(t *testing.T) {
+	prices := generateWaveChartData(400)
+	folds, err := walkForwardFolds(len(prices), models.WalkForwardConfig{InSampleBars: 100, OutOfSampleBars: 50})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sets := expandGrid(withDefaultRules(models.SignalRules{}), models.ParameterGrid{
+		RSIBuyBelow:   []float64{30, 35, 40, 45},
+		RSISellAbove:  []float64{55, 60, 65},
+		DiscountBelow: []float64{5, 10},
+	})
+
+	results := evaluateParameterSets(prices, sets, folds)
+	if len(results) != len(sets) {
+		t.Fatalf("Expected %d results, got %d", len(sets), len(results))
+	}
+	trades := 0
+	for i, set := range sets {
+		want := evaluateParameterSet(prices, set, folds)
+		if results[i] != want {
+			t.Errorf("Set %d: expected %+v, got %+v", i, want, results[i])
+		}
+		trades += results[i].OutOfSampleTrades
+	}
+	if trades == 0 {
+		t.Errorf("Expected out-of-sample trades on an oscillating series")
+	}
+}
+
+// TestOptimizeRanksEveryParameterSet tests a sweep end to end
+func TestOptimizeRanksEveryParameterSet(t *testing.T) {
+	_, md := newTestMarketDataService(t)
+	backtest := NewBacktestService(md, NewIndicatorService())
+
+	resp, err := backtest.Optimize(models.OptimizationRequest{
+		Symbol:      "AAPL",
+		Days:        400,
+		Grid:        models.ParameterGrid{RSIBuyBelow: []float64{30, 40}, DiscountBelow: []float64{5, 10}},
+		WalkForward: models.WalkForwardConfig{InSampleBars: 100, OutOfSampleBars: 50},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Evaluated != 4 || len(resp.Results) != 4 || resp.Folds == 0 || resp.RankBy != "sharpe" {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	for i, result := range resp.Results {
+		if result.Rank != i+1 {
+			t.Errorf("Expected rank %d, got %d", i+1, result.Rank)
+		}
+		if i > 0 && result.OutOfSampleSharpe > resp.Results[i-1].OutOfSampleSharpe {
+			t.Errorf("Results are not sorted by Sharpe ratio")
+		}
+	}
+}
+
+// TestOptimizeRejectsOversizedGrid tests that a huge grid is rejected before
+// it is expanded
+func TestOptimizeRejectsOversizedGrid(t *testing.T) {
+	_, md := newTestMarketDataService(t)
+	backtest := NewBacktestService(md, NewIndicatorService())
+
+	values := make([]float64, 100)
+	lookbacks := make([]int, 100)
+	for i := range values {
+		values[i] = float64(i)
+		lookbacks[i] = i + 1
+	}
+	_, err := backtest.Optimize(models.OptimizationRequest{
+		Symbol: "AAPL",
+		Grid: models.ParameterGrid{
+			RSIBuyBelow:         values,
+			RSISellAbove:        values,
+			DiscountBelow:       values,
+			PremiumAbove:        values,
+			EquilibriumLookback: lookbacks,
+		},
+	})
+	if !errors.Is(err, ErrInvalidBacktestConfig) {
+		t.Errorf("Expected ErrInvalidBacktestConfig, got %v", err)
+	}
+	if size := gridSize(models.ParameterGrid{RSIBuyBelow: values[:3], DiscountBelow: values[:2]}); size != 6 {
+		t.Errorf("Expected a grid size of 6, got %v", size)
+	}
+}
+
+// TestRankParameterSets tests ranking by out-of-sample metrics
+func TestRankParameterSets(t *testing.T) {
+	results := []models.ParameterSetResult{
+		{OutOfSampleSharpe: 0.5, OutOfSampleDrawdown: 5},
+		{OutOfSampleSharpe: 1.5, OutOfSampleDrawdown: 20},
+		{OutOfSampleSharpe: 1.0, OutOfSampleDrawdown: 10},
+	}
+
+	rankParameterSets(results, "sharpe")
+	if results[0].OutOfSampleSharpe != 1.5 || results[0].Rank != 1 {
+		t.Errorf("Expected highest Sharpe first, got %+v", results[0])
+	}
+
+	rankParameterSets(results, "drawdown")
+	if results[0].OutOfSampleDrawdown != 5 {
+		t.Errorf("Expected smallest drawdown first, got %+v", results[0])
+	}
+}
+
+// TestMonteCarloShufflePreservesReturn tests that reordering trades only changes the path
+func TestMonteCarloShufflePreservesReturn(t *testing.T) {
+	returns := []float64{0.05, -0.03, 0.08, -0.10, 0.02}
+	rng := rand.New(rand.NewSource(1))
+
+	shuffled := simulateTradeSequences("shuffle", returns, 200, defaultPercentiles, rng, shuffleReturns)
+	first := shuffled.ReturnPercentiles[0].Value
+	last := shuffled.ReturnPercentiles[len(shuffled.ReturnPercentiles)-1].Value
+	if math.Abs(first-last) > 1e-9 {
+		t.Errorf("Shuffling should not change the final return, got %f to %f", first, last)
+	}
+
+	drawdowns := shuffled.DrawdownPercentiles
+	if drawdowns[0].Value > drawdowns[len(drawdowns)-1].Value {
+		t.Errorf("Drawdown percentiles should be ascending")
+	}
+
+	bootstrapped := simulateTradeSequences("bootstrap", returns, 200, defaultPercentiles, rng, bootstrapReturns)
+	if bootstrapped.ReturnPercentiles[0].Value >= bootstrapped.ReturnPercentiles[4].Value {
+		t.Errorf("Bootstrap should produce a spread of final returns")
+	}
+}
@@ -3,35 +3,222 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// cacheNamespace prefixes every key this service writes, so the keys of
+// different data types never collide with each other or with other users of
+// the same Redis database
+const cacheNamespace = "equilibrio"
+
+// Invalidation tags. Every entry carries tagAll plus the tags of what it
+// depends on; invalidating a tag makes all entries carrying it unreachable.
+const tagAll = "all"
+
+// tagSymbol is carried by entries derived from a symbol's data
+func tagSymbol(symbol string) string {
+	return "symbol:" + strings.ToUpper(symbol)
+}
+
+// Cached data types. Each type has its own TTL in CacheTTLs.
+const (
+	cacheTypeStock      = "stock"
+	cacheTypeIndicators = "indicators"
+	cacheTypeSnapshot   = "snapshot" // Universe snapshots, held by MarketDataService
+)
+
+// CacheTTLs holds the expiration of each cached data type. Stale is how long
+// past its expiration an entry may still be served while it is reloaded.
+type CacheTTLs struct {
+	Stock      time.Duration
+	Indicators time.Duration
+	Stale      time.Duration
+}
+
+// cacheCounters counts the outcomes of lookups of one data type
+type cacheCounters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	stale     atomic.Uint64
+	coalesced atomic.Uint64
+}
+
+// cachedEntry wraps a stored value with the time it stops being fresh. The
+// backend keeps it for a further stale period so it can be served while the
+// value is reloaded in the background.
+type cachedEntry struct {
+	Value      json.RawMessage `json:"v"`
+	FreshUntil time.Time       `json:"f"`
+}
+
+// ErrCacheMiss is returned by a Cache when a key is absent or expired
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache is a key/value store with per-key expiration
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// GetMulti returns the values of several keys in one round trip, in
+	// order, with nil for absent or expired keys
+	GetMulti(ctx context.Context, keys ...string) ([][]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Close() error
+}
+
+// CacheService is the single cache used by all services. Keys follow the
+// scheme "equilibrio:<type>:<id>@<generations>" and expire after the TTL of
+// their type. The generations suffix holds the current generation of each of
+// the entry's tags, so bumping a tag's generation invalidates its entries
+// without scanning or flushing the database; the orphaned entries expire.
+//
+// Loads are coalesced per key, and expired entries are served stale for
+// CacheTTLs.Stale while a single background load replaces them.
+//
+// Generations are stored in the backend, where other replicas see them, and
+// also kept in memory, where they are never evicted. A generation evicted
+// from the in-memory backend, or lost to a Redis failover, therefore cannot
+// fall back to an older one and make invalidated entries readable again.
 type CacheService struct {
-	client *redis.Client
-	config *config.Config
+	backend Cache
+	ttls    CacheTTLs
+	flights flightGroup
+	stats   map[string]*cacheCounters
+
+	generationsMu sync.Mutex
+	generations   map[string]int64 // Latest generation of each tag seen or set here
 }
 
-func NewCacheService(cfg *config.Config) *CacheService {
-	// Initialize Redis client
-	opt, err := redis.ParseURL(cfg.RedisURL)
-	if err != nil {
-		// Fallback to default Redis connection
-		opt = &redis.Options{
-			Addr: "localhost:6379",
+// NewCacheService connects to Redis with an in-memory fallback for when it is
+// unreachable. Without a Redis URL it caches in memory only.
+func NewCacheService(cfg *config.Config) (*CacheService, error) {
+	memory := NewMemoryCache(cfg.CacheMemoryEntries)
+
+	var backend Cache = memory
+	if cfg.RedisURL == "" {
+		log.Println("REDIS_URL not set, using in-memory cache")
+	} else {
+		opt, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
 		}
+		backend = NewFailoverCache(NewRedisCache(opt), memory, cfg.CacheHealthInterval)
 	}
 
-	rdb := redis.NewClient(opt)
+	return NewCacheServiceWithBackend(backend, CacheTTLs{
+		Stock:      cfg.CacheStockTTL,
+		Indicators: cfg.CacheIndicatorsTTL,
+		Stale:      cfg.CacheStaleTTL,
+	}), nil
+}
 
+// NewCacheServiceWithBackend wraps an existing cache backend
+func NewCacheServiceWithBackend(backend Cache, ttls CacheTTLs) *CacheService {
+	stats := make(map[string]*cacheCounters)
+	for _, dataType := range []string{cacheTypeStock, cacheTypeIndicators, cacheTypeSnapshot} {
+		stats[dataType] = &cacheCounters{}
+	}
 	return &CacheService{
-		client: rdb,
-		config: cfg,
+		backend:     backend,
+		ttls:        ttls,
+		stats:       stats,
+		generations: make(map[string]int64),
+	}
+}
+
+// Backend returns the name of the cache currently serving requests
+func (c *CacheService) Backend() string {
+	switch backend := c.backend.(type) {
+	case *FailoverCache:
+		return backend.Backend()
+	case *RedisCache:
+		return "redis"
+	default:
+		return "memory"
+	}
+}
+
+// cacheKey builds a namespaced key for a data type from its identifying parts
+func cacheKey(dataType string, parts ...string) string {
+	return cacheNamespace + ":" + dataType + ":" + strings.Join(parts, ":")
+}
+
+// generationKey is where the current generation of a tag is stored
+func generationKey(tag string) string {
+	return cacheKey("generation", tag)
+}
+
+// taggedKey appends the current generation of tagAll and each tag to a key
+func (c *CacheService) taggedKey(ctx context.Context, key string, tags ...string) string {
+	generations := c.currentGenerations(ctx, append([]string{tagAll}, tags...))
+	formatted := make([]string, len(generations))
+	for i, generation := range generations {
+		formatted[i] = strconv.FormatInt(generation, 36)
+	}
+	return key + "@" + strings.Join(formatted, ".")
+}
+
+// currentGenerations returns the current generation of each tag, read from
+// the backend in one round trip: the newer of the one in the backend, which may
+// have been set by another replica, and the one kept in memory. Tags that
+// were never invalidated are at generation 0.
+func (c *CacheService) currentGenerations(ctx context.Context, tags []string) []int64 {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = generationKey(tag)
+	}
+	stored, err := c.backend.GetMulti(ctx, keys...)
+
+	c.generationsMu.Lock()
+	defer c.generationsMu.Unlock()
+	generations := make([]int64, len(tags))
+	for i, tag := range tags {
+		if err == nil && stored[i] != nil {
+			if generation, _ := strconv.ParseInt(string(stored[i]), 36, 64); generation > c.generations[tag] {
+				c.generations[tag] = generation
+			}
+		}
+		generations[i] = c.generations[tag]
+	}
+	return generations
+}
+
+// Invalidate makes every entry carrying any of the tags unreachable. Each tag
+// moves to a new, unique generation so entries written before the call are
+// never read again.
+func (c *CacheService) Invalidate(ctx context.Context, tags ...string) error {
+	generation := time.Now().UnixNano()
+
+	c.generationsMu.Lock()
+	for _, tag := range tags {
+		// Always move forward, even if the clock does not
+		if generation <= c.generations[tag] {
+			generation = c.generations[tag] + 1
+		}
+	}
+	for _, tag := range tags {
+		c.generations[tag] = generation
+	}
+	c.generationsMu.Unlock()
+
+	data := []byte(strconv.FormatInt(generation, 36))
+	for _, tag := range tags {
+		if err := c.backend.Set(ctx, generationKey(tag), data, 0); err != nil {
+			return fmt.Errorf("failed to invalidate %s: %w", tag, err)
+		}
 	}
+	return nil
 }
 
 // Set stores a value in the cache with expiration
@@ -41,72 +228,133 @@ func (c *CacheService) Set(ctx context.Context, key string, value interface{}, e
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
 
-	return c.client.Set(ctx, key, data, expiration).Err()
+	return c.backend.Set(ctx, key, data, expiration)
 }
 
 // Get retrieves a value from the cache
 func (c *CacheService) Get(ctx context.Context, key string, dest interface{}) error {
-	data, err := c.client.Get(ctx, key).Result()
+	data, err := c.backend.Get(ctx, key)
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal([]byte(data), dest)
+	return json.Unmarshal(data, dest)
 }
 
 // Delete removes a key from the cache
 func (c *CacheService) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+	return c.backend.Delete(ctx, key)
 }
 
 // Exists checks if a key exists in the cache
 func (c *CacheService) Exists(ctx context.Context, key string) (bool, error) {
-	result, err := c.client.Exists(ctx, key).Result()
-	return result > 0, err
+	_, err := c.backend.Get(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Stats returns the lookup counters of each data type
+func (c *CacheService) Stats() models.CacheStats {
+	stats := models.CacheStats{
+		Backend: c.Backend(),
+		Types:   make(map[string]models.CacheCounters, len(c.stats)),
+	}
+	for dataType, counters := range c.stats {
+		stats.Types[dataType] = models.CacheCounters{
+			Hits:      counters.hits.Load(),
+			Misses:    counters.misses.Load(),
+			Stale:     counters.stale.Load(),
+			Coalesced: counters.coalesced.Load(),
+		}
+	}
+	return stats
 }
 
-// FlushDB clears all keys in the current database
-func (c *CacheService) FlushDB(ctx context.Context) error {
-	return c.client.FlushDB(ctx).Err()
+// loadCached returns the cached value of a key, loading and caching it on a
+// miss. Concurrent misses of a key share one load; an expired entry within the
+// stale period is returned as is and reloaded once in the background. Load
+// errors are returned and not cached.
+func loadCached[T any](c *CacheService, ctx context.Context, dataType, key string, ttl time.Duration, load func() (*T, error)) (*T, error) {
+	counters := c.stats[dataType]
+
+	var entry cachedEntry
+	if err := c.Get(ctx, key, &entry); err == nil {
+		var value T
+		if err := json.Unmarshal(entry.Value, &value); err == nil {
+			if time.Now().Before(entry.FreshUntil) {
+				counters.hits.Add(1)
+			} else {
+				counters.stale.Add(1)
+				c.flights.Go(key, func() (interface{}, error) {
+					return storeLoaded(c, context.Background(), key, ttl, load)
+				})
+			}
+			return &value, nil
+		}
+	}
+
+	counters.misses.Add(1)
+	value, err, shared := c.flights.Do(key, func() (interface{}, error) {
+		return storeLoaded(c, context.Background(), key, ttl, load)
+	})
+	if shared {
+		counters.coalesced.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value.(*T), nil
 }
 
-// SetStockData caches stock data with a standard expiration
-func (c *CacheService) SetStockData(ctx context.Context, symbol string, data interface{}) error {
-	key := fmt.Sprintf("stock:%s", symbol)
-	return c.Set(ctx, key, data, 5*time.Minute)
+// storeLoaded runs a load and caches its result as fresh for ttl
+func storeLoaded[T any](c *CacheService, ctx context.Context, key string, ttl time.Duration, load func() (*T, error)) (interface{}, error) {
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	c.Set(ctx, key, cachedEntry{Value: data, FreshUntil: time.Now().Add(ttl)}, ttl+c.ttls.Stale)
+	return value, nil
 }
 
-// GetStockData retrieves cached stock data
-func (c *CacheService) GetStockData(ctx context.Context, symbol string, dest interface{}) error {
-	key := fmt.Sprintf("stock:%s", symbol)
-	return c.Get(ctx, key, dest)
+// countLookup records the outcome of a lookup served outside the backend
+func (c *CacheService) countLookup(dataType string, fresh, stale bool) {
+	switch counters := c.stats[dataType]; {
+	case fresh:
+		counters.hits.Add(1)
+	case stale:
+		counters.stale.Add(1)
+	default:
+		counters.misses.Add(1)
+	}
 }
 
-// SetStocksList caches a list of stocks with filters
-func (c *CacheService) SetStocksList(ctx context.Context, filterKey string, data interface{}) error {
-	key := fmt.Sprintf("stocks:%s", filterKey)
-	return c.Set(ctx, key, data, 30*time.Second)
+// stockKey is the key of a single stock, tagged with its symbol
+func (c *CacheService) stockKey(ctx context.Context, symbol string) string {
+	return c.taggedKey(ctx, cacheKey(cacheTypeStock, strings.ToUpper(symbol)), tagSymbol(symbol))
 }
 
-// GetStocksList retrieves cached stocks list
-func (c *CacheService) GetStocksList(ctx context.Context, filterKey string, dest interface{}) error {
-	key := fmt.Sprintf("stocks:%s", filterKey)
-	return c.Get(ctx, key, dest)
+// LoadStock returns a cached stock, loading it on a miss
+func (c *CacheService) LoadStock(ctx context.Context, symbol string, load func() (*models.StockData, error)) (*models.StockData, error) {
+	return loadCached(c, ctx, cacheTypeStock, c.stockKey(ctx, symbol), c.ttls.Stock, load)
 }
 
-// SetIndicators caches technical indicators
-func (c *CacheService) SetIndicators(ctx context.Context, symbol string, period int, data interface{}) error {
-	key := fmt.Sprintf("indicators:%s:%d", symbol, period)
-	return c.Set(ctx, key, data, 10*time.Minute)
+// indicatorsKey is the key of a symbol's indicators, tagged with the symbol
+func (c *CacheService) indicatorsKey(ctx context.Context, symbol string, period int) string {
+	return c.taggedKey(ctx, cacheKey(cacheTypeIndicators, strings.ToUpper(symbol), strconv.Itoa(period)), tagSymbol(symbol))
 }
 
-// GetIndicators retrieves cached technical indicators
-func (c *CacheService) GetIndicators(ctx context.Context, symbol string, period int, dest interface{}) error {
-	key := fmt.Sprintf("indicators:%s:%d", symbol, period)
-	return c.Get(ctx, key, dest)
+// LoadIndicators returns cached technical indicators, calculating them on a miss
+func (c *CacheService) LoadIndicators(ctx context.Context, symbol string, period int, load func() (*models.TechnicalIndicators, error)) (*models.TechnicalIndicators, error) {
+	return loadCached(c, ctx, cacheTypeIndicators, c.indicatorsKey(ctx, symbol, period), c.ttls.Indicators, load)
 }
 
-// Close closes the Redis connection
+// Close releases the cache backend
 func (c *CacheService) Close() error {
-	return c.client.Close()
+	return c.backend.Close()
 }
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process LRU cache. Once it holds maxEntries keys, the
+// least recently used key is evicted to make room for a new one.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Front is most recently used
+	entries    map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // Zero means no expiration
+}
+
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value of a key, or ErrCacheMiss if it is absent or expired
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		m.remove(elem)
+		return nil, ErrCacheMiss
+	}
+	m.order.MoveToFront(elem)
+	return entry.value, nil
+}
+
+// GetMulti returns the values of keys, with nil for absent or expired ones
+func (m *MemoryCache) GetMulti(ctx context.Context, keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i], _ = m.Get(ctx, key)
+	}
+	return values, nil
+}
+
+// Set stores a value; a ttl of zero keeps it until it is evicted
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := m.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		m.order.MoveToFront(elem)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+// Delete removes keys
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := m.entries[key]; ok {
+			m.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Close is a no-op for the in-memory cache
+func (m *MemoryCache) Close() error {
+	return nil
+}
+
+// remove unlinks an entry. Callers must hold the lock.
+func (m *MemoryCache) remove(elem *list.Element) {
+	m.order.Remove(elem)
+	delete(m.entries, elem.Value.(*memoryEntry).key)
+}
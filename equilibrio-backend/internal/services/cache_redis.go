@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisOpTimeout bounds every Redis call so an unreachable server fails fast
+// instead of stalling requests
+const redisOpTimeout = 500 * time.Millisecond
+
+// RedisCache stores values in Redis
+type RedisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(opt *redis.Options) *RedisCache {
+	opt.DialTimeout = redisOpTimeout
+	opt.ReadTimeout = redisOpTimeout
+	opt.WriteTimeout = redisOpTimeout
+	opt.PoolTimeout = redisOpTimeout
+	opt.MaxRetries = -1 // Retries would multiply the timeout while Redis is down
+
+	return &RedisCache{client: redis.NewClient(opt)}
+}
+
+// Get returns the value of a key, or ErrCacheMiss if it is absent
+func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return data, err
+}
+
+// GetMulti returns the values of keys with a single MGET, with nil for
+// absent ones
+func (r *RedisCache) GetMulti(ctx context.Context, keys ...string) ([][]byte, error) {
+	results, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(keys))
+	for i, result := range results {
+		if s, ok := result.(string); ok {
+			values[i] = []byte(s)
+		}
+	}
+	return values, nil
+}
+
+// Set stores a value; a ttl of zero keeps it until it is deleted
+func (r *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete removes keys
+func (r *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// Ping checks that Redis is reachable
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close closes the Redis connection
+func (r *RedisCache) Close() error {
+	return r.client.Close()
+}
+
+// FailoverCache serves from Redis while it is reachable and from an in-memory
+// cache while it is not. A connection error switches to memory immediately;
+// a background health check switches back once Redis answers again.
+type FailoverCache struct {
+	primary   *RedisCache
+	fallback  *MemoryCache
+	degraded  atomic.Bool
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewFailoverCache(primary *RedisCache, fallback *MemoryCache, healthInterval time.Duration) *FailoverCache {
+	f := &FailoverCache{
+		primary:  primary,
+		fallback: fallback,
+		stop:     make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+	if err := primary.Ping(ctx); err != nil {
+		log.Printf("Redis unreachable, using in-memory cache: %v", err)
+		f.degraded.Store(true)
+	}
+
+	go f.watch(healthInterval)
+	return f
+}
+
+// watch pings Redis on an interval and switches back to it when it recovers
+func (f *FailoverCache) watch(interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			if !f.degraded.Load() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+			err := f.primary.Ping(ctx)
+			cancel()
+			if err == nil {
+				log.Println("Redis reachable again, switching back from in-memory cache")
+				f.degraded.Store(false)
+			}
+		}
+	}
+}
+
+// Backend returns the name of the cache currently serving requests
+func (f *FailoverCache) Backend() string {
+	if f.degraded.Load() {
+		return "memory"
+	}
+	return "redis"
+}
+
+// failed marks Redis as unreachable when an operation fails for a reason
+// other than a missing key
+func (f *FailoverCache) failed(err error) bool {
+	if err == nil || errors.Is(err, ErrCacheMiss) {
+		return false
+	}
+	if f.degraded.CompareAndSwap(false, true) {
+		log.Printf("Redis error, falling back to in-memory cache: %v", err)
+	}
+	return true
+}
+
+// Get reads from Redis, or from memory while Redis is unreachable
+func (f *FailoverCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if !f.degraded.Load() {
+		data, err := f.primary.Get(ctx, key)
+		if !f.failed(err) {
+			return data, err
+		}
+	}
+	return f.fallback.Get(ctx, key)
+}
+
+// GetMulti reads from Redis, or from memory while Redis is unreachable
+func (f *FailoverCache) GetMulti(ctx context.Context, keys ...string) ([][]byte, error) {
+	if !f.degraded.Load() {
+		values, err := f.primary.GetMulti(ctx, keys...)
+		if !f.failed(err) {
+			return values, err
+		}
+	}
+	return f.fallback.GetMulti(ctx, keys...)
+}
+
+// Set writes to Redis, or to memory while Redis is unreachable
+func (f *FailoverCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if !f.degraded.Load() {
+		if err := f.primary.Set(ctx, key, value, ttl); !f.failed(err) {
+			return err
+		}
+	}
+	return f.fallback.Set(ctx, key, value, ttl)
+}
+
+// Delete removes keys from both caches so neither serves them after a switch
+func (f *FailoverCache) Delete(ctx context.Context, keys ...string) error {
+	f.fallback.Delete(ctx, keys...)
+	if !f.degraded.Load() {
+		if err := f.primary.Delete(ctx, keys...); !f.failed(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the health check and closes the Redis connection
+func (f *FailoverCache) Close() error {
+	f.closeOnce.Do(func() { close(f.stop) })
+	return f.primary.Close()
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestMemoryCacheLRU tests eviction of the least recently used key and expiration
+func TestMemoryCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(2)
+
+	cache.Set(ctx, "a", []byte("1"), 0)
+	cache.Set(ctx, "b", []byte("2"), 0)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := cache.Get(ctx, "b"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Expected the least recently used key to be evicted, got %v", err)
+	}
+	if value, err := cache.Get(ctx, "a"); err != nil || string(value) != "1" {
+		t.Errorf("Expected a recently used key to survive, got %q %v", value, err)
+	}
+
+	cache.Set(ctx, "d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.Get(ctx, "d"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Expected an expired key to miss, got %v", err)
+	}
+}
+
+// TestFailoverCacheWithoutRedis tests that an unreachable Redis falls back to memory
+func TestFailoverCacheWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	failover := NewFailoverCache(NewRedisCache(&redis.Options{Addr: "127.0.0.1:1"}), NewMemoryCache(10), time.Hour)
+	defer failover.Close()
+
+	if failover.Backend() != "memory" {
+		t.Fatalf("Expected the memory backend while Redis is unreachable, got %s", failover.Backend())
+	}
+
+	cache := NewCacheServiceWithBackend(failover, CacheTTLs{Stock: time.Minute})
+	if _, err := cache.LoadStock(ctx, "AAPL", func() (*models.StockData, error) {
+		return &models.StockData{Symbol: "AAPL", Price: 100}, nil
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stock, err := cache.LoadStock(ctx, "aapl", func() (*models.StockData, error) {
+		return nil, errors.New("expected a cache hit")
+	})
+	if err != nil || stock.Price != 100 {
+		t.Errorf("Expected the cached stock from memory, got %+v %v", stock, err)
+	}
+}
+
+// TestFailoverCacheRecovers tests that the cache falls back to memory while
+// Redis is down and switches back once it is reachable again
+func TestFailoverCacheRecovers(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	failover := NewFailoverCache(NewRedisCache(&redis.Options{Addr: server.Addr()}), NewMemoryCache(10), 10*time.Millisecond)
+	defer failover.Close()
+
+	if failover.Backend() != "redis" {
+		t.Fatalf("Expected the redis backend, got %s", failover.Backend())
+	}
+	if err := failover.Set(ctx, "before", []byte("redis"), 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server.Close()
+	if err := failover.Set(ctx, "during", []byte("memory"), 0); err != nil {
+		t.Fatalf("Expected the write to fall back to memory, got %v", err)
+	}
+	if failover.Backend() != "memory" {
+		t.Fatalf("Expected the memory backend while Redis is down, got %s", failover.Backend())
+	}
+	if value, err := failover.Get(ctx, "during"); err != nil || string(value) != "memory" {
+		t.Errorf("Expected the value written to memory, got %q %v", value, err)
+	}
+
+	if err := server.Restart(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	eventually(t, "the redis backend", func() bool { return failover.Backend() == "redis" })
+	if value, err := failover.Get(ctx, "before"); err != nil || string(value) != "redis" {
+		t.Errorf("Expected the value kept in Redis, got %q %v", value, err)
+	}
+}
+
+// TestNewCacheServiceRejectsBadURL tests that an invalid Redis URL is reported
+func TestNewCacheServiceRejectsBadURL(t *testing.T) {
+	if _, err := NewCacheService(&config.Config{RedisURL: "not a url"}); err == nil {
+		t.Errorf("Expected an error for an invalid REDIS_URL")
+	}
+}
+
+// TestCacheInvalidateTags tests that invalidating a tag only evicts entries carrying it
+func TestCacheInvalidateTags(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCacheServiceWithBackend(NewMemoryCache(100), CacheTTLs{Stock: time.Minute, Indicators: time.Minute})
+
+	loads := 0
+	loadStock := func(symbol string) {
+		cache.LoadStock(ctx, symbol, func() (*models.StockData, error) {
+			loads++
+			return &models.StockData{Symbol: symbol}, nil
+		})
+	}
+	loadIndicators := func(symbol string) {
+		cache.LoadIndicators(ctx, symbol, 200, func() (*models.TechnicalIndicators, error) {
+			loads++
+			return &models.TechnicalIndicators{}, nil
+		})
+	}
+	expectLoads := func(action string, want int) {
+		t.Helper()
+		if loads != want {
+			t.Errorf("%s: expected %d loads, got %d", action, want, loads)
+		}
+		loads = 0
+	}
+
+	loadStock("AAPL")
+	loadStock("MSFT")
+	loadIndicators("AAPL")
+	loadIndicators("MSFT")
+	expectLoads("first lookups", 4)
+
+	if err := cache.Invalidate(ctx, tagSymbol("AAPL")); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+	loadStock("AAPL")
+	loadIndicators("AAPL")
+	expectLoads("invalidated entries", 2)
+	loadStock("MSFT")
+	loadIndicators("MSFT")
+	expectLoads("unrelated entries", 0)
+
+	cache.Invalidate(ctx, tagAll)
+	loadStock("MSFT")
+	expectLoads("after invalidating everything", 1)
+}
+
+// TestCacheGenerationsInOneRoundTrip tests that a tagged key reads all its
+// generations from Redis with a single command, including those another
+// replica set
+func TestCacheGenerationsInOneRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	cache := NewCacheServiceWithBackend(NewRedisCache(&redis.Options{Addr: server.Addr()}), CacheTTLs{Stock: time.Minute})
+	other := NewCacheServiceWithBackend(NewRedisCache(&redis.Options{Addr: server.Addr()}), CacheTTLs{Stock: time.Minute})
+	defer cache.Close()
+	defer other.Close()
+
+	before := cache.stockKey(ctx, "AAPL")
+	if err := other.Invalidate(ctx, tagSymbol("AAPL")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	commands := server.CommandCount()
+	after := cache.stockKey(ctx, "AAPL")
+	if n := server.CommandCount() - commands; n != 1 {
+		t.Errorf("Expected one command for the generations, got %d", n)
+	}
+	if after == before || after != other.stockKey(ctx, "AAPL") {
+		t.Errorf("Expected the other replica's generation in the key, got %q after %q", after, before)
+	}
+}
+
+// TestCacheInvalidationSurvivesEviction tests that evicting a tag's
+// generation from the backend does not make invalidated entries readable again
+func TestCacheInvalidationSurvivesEviction(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryCache(2)
+	cache := NewCacheServiceWithBackend(memory, CacheTTLs{Stock: time.Minute})
+
+	loads := 0
+	loadStock := func() {
+		cache.LoadStock(ctx, "AAPL", func() (*models.StockData, error) {
+			loads++
+			return &models.StockData{Symbol: "AAPL", Price: float64(loads)}, nil
+		})
+	}
+
+	loadStock()
+	staleKey := cache.stockKey(ctx, "AAPL")
+	if err := cache.Invalidate(ctx, tagSymbol("AAPL")); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+
+	// Touch the old entry and add another, which evicts the generation
+	memory.Get(ctx, staleKey)
+	memory.Set(ctx, "other", []byte("1"), 0)
+	if _, err := memory.Get(ctx, generationKey(tagSymbol("AAPL"))); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Expected the generation to be evicted, got %v", err)
+	}
+
+	loadStock()
+	if loads != 2 {
+		t.Errorf("Expected the invalidated entry to be reloaded, got %d loads", loads)
+	}
+}
+
+// TestCacheCoalescesLoads tests that concurrent misses of a key share one load
+func TestCacheCoalescesLoads(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCacheServiceWithBackend(NewMemoryCache(100), CacheTTLs{Stock: time.Minute})
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stock, err := cache.LoadStock(ctx, "AAPL", func() (*models.StockData, error) {
+				loads.Add(1)
+				<-release
+				return &models.StockData{Symbol: "AAPL"}, nil
+			})
+			if err != nil || stock.Symbol != "AAPL" {
+				t.Errorf("Expected the loaded stock, got %v %v", stock, err)
+			}
+		}()
+	}
+	// Release the load once every other caller is waiting on it
+	key := cache.stockKey(ctx, "AAPL")
+	eventually(t, "9 callers waiting on the load", func() bool { return cache.flights.waiting(key) == 9 })
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("Expected one load, got %d", loads.Load())
+	}
+	counters := cache.Stats().Types[cacheTypeStock]
+	if counters.Misses != 10 || counters.Coalesced != 9 {
+		t.Errorf("Expected 10 misses with 9 coalesced, got %+v", counters)
+	}
+}
+
+// TestCacheServesStale tests that an expired entry is served while it reloads in the background
+func TestCacheServesStale(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCacheServiceWithBackend(NewMemoryCache(100), CacheTTLs{Stock: 50 * time.Millisecond, Stale: time.Minute})
+
+	reloaded := make(chan struct{})
+	price := 1.0
+	load := func() (*models.StockData, error) {
+		defer func() { price++ }()
+		return &models.StockData{Symbol: "AAPL", Price: price}, nil
+	}
+	cache.LoadStock(ctx, "AAPL", load)
+	key := cache.stockKey(ctx, "AAPL")
+	eventually(t, "the entry to expire", func() bool {
+		var entry cachedEntry
+		return cache.Get(ctx, key, &entry) == nil && time.Now().After(entry.FreshUntil)
+	})
+
+	stock, _ := cache.LoadStock(ctx, "AAPL", func() (*models.StockData, error) {
+		defer close(reloaded)
+		return load()
+	})
+	if stock.Price != 1 {
+		t.Errorf("Expected the stale value, got price %v", stock.Price)
+	}
+	<-reloaded
+
+	// The reload is stored after it returns; wait for the fresh entry
+	eventually(t, "the reloaded entry", func() bool {
+		var entry cachedEntry
+		return cache.Get(ctx, key, &entry) == nil && time.Now().Before(entry.FreshUntil)
+	})
+	stock, _ = cache.LoadStock(ctx, "AAPL", load)
+	if stock.Price != 2 {
+		t.Errorf("Expected the reloaded value, got price %v", stock.Price)
+	}
+	counters := cache.Stats().Types[cacheTypeStock]
+	if counters.Hits != 1 || counters.Misses != 1 || counters.Stale != 1 {
+		t.Errorf("Expected 1 hit, 1 miss and 1 stale lookup, got %+v", counters)
+	}
+}
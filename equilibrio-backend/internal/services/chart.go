@@ -0,0 +1,541 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+// ErrInvalidChart is returned when chart parameters are unusable
+var ErrInvalidChart = errors.New("invalid chart request")
+
+// defaultChartTimezone is the exchange timezone the session hours are defined in
+const defaultChartTimezone = "America/New_York"
+
+// Regular trading session, in exchange time
+const (
+	sessionOpenHour, sessionOpenMinute = 9, 30
+	sessionLength                      = 6*time.Hour + 30*time.Minute
+)
+
+// Chart size limits. Longer series are downsampled to at most maxBars bars.
+const (
+	defaultChartBars = 1500
+	maxChartBars     = 5000
+	maxChartHistory  = 3650 // Calendar days of daily history
+)
+
+// chartInterval describes a bar size. Intraday intervals count their range
+// in trading sessions; daily and longer intervals count calendar days.
+// historyDays is how far back bars of the interval are available.
+type chartInterval struct {
+	name        string
+	step        time.Duration // Bar length of intraday intervals
+	period      string        // "day", "week" or "month" for daily and longer intervals
+	defaultDays int
+	historyDays int
+}
+
+var chartIntervals = []chartInterval{
+	{name: "1m", step: time.Minute, defaultDays: 1, historyDays: 7},
+	{name: "5m", step: 5 * time.Minute, defaultDays: 5, historyDays: 60},
+	{name: "15m", step: 15 * time.Minute, defaultDays: 10, historyDays: 60},
+	{name: "1h", step: time.Hour, defaultDays: 30, historyDays: 730},
+	{name: "1d", period: "day", defaultDays: 90, historyDays: maxChartHistory},
+	{name: "1wk", period: "week", defaultDays: 365, historyDays: maxChartHistory},
+	{name: "1mo", period: "month", defaultDays: 365, historyDays: maxChartHistory},
+}
+
+// Chart overlays, with the bars of history each needs before its first value
+const (
+	chartEquilibriumLookback = 120 // Same default as backtests
+	chartBollingerWindow     = 20
+	chartBollingerWidth      = 2
+	chartLevelLookback       = 60 // Bars the support and resistance levels are found in
+)
+
+var chartOverlays = map[string]int{
+	"sma50":       50,
+	"sma200":      200,
+	"bbands":      chartBollingerWindow,
+	"equilibrium": chartEquilibriumLookback,
+}
+
+// parseChartOverlays validates overlay names and returns their longest warm-up
+func parseChartOverlays(names []string) (overlays map[string]bool, warmup int, err error) {
+	for _, name := range names {
+		bars, ok := chartOverlays[name]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: unknown overlay %q (use sma50, sma200, bbands or equilibrium)", ErrInvalidChart, name)
+		}
+		if overlays == nil {
+			overlays = make(map[string]bool)
+		}
+		overlays[name] = true
+		if bars > warmup {
+			warmup = bars
+		}
+	}
+	return overlays, warmup, nil
+}
+
+func (i chartInterval) intraday() bool {
+	return i.step > 0
+}
+
+// lookupChartInterval returns a bar size by name; an empty name selects 1d
+func lookupChartInterval(name string) (chartInterval, error) {
+	if name == "" {
+		name = "1d"
+	}
+	names := make([]string, len(chartIntervals))
+	for i, interval := range chartIntervals {
+		if interval.name == name {
+			return interval, nil
+		}
+		names[i] = interval.name
+	}
+	return chartInterval{}, fmt.Errorf("%w: interval must be one of %s, got %q", ErrInvalidChart, strings.Join(names, ", "), name)
+}
+
+// GetChart returns bars of a stock at the requested interval. Daily and
+// longer bars are dated "YYYY-MM-DD"; intraday bars carry an RFC 3339
+// timestamp in the requested timezone (the exchange's by default). Series
+// longer than the bar limit are downsampled by merging consecutive bars.
+// Renko and point-and-figure charts return their last bricks or columns up
+// to the bar limit instead.
+func (s *MarketDataService) GetChart(req models.ChartRequest) (*models.ChartDataResponse, error) {
+	interval, err := lookupChartInterval(req.Interval)
+	if err != nil {
+		return nil, err
+	}
+	if req.Timezone == "" {
+		req.Timezone = defaultChartTimezone
+	}
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidChart, req.Timezone)
+	}
+	if req.MaxBars == 0 {
+		req.MaxBars = defaultChartBars
+	}
+	if req.MaxBars < 1 || req.MaxBars > maxChartBars {
+		return nil, fmt.Errorf("%w: maxBars must be between 1 and %d", ErrInvalidChart, maxChartBars)
+	}
+	overlays, warmup, err := parseChartOverlays(req.Overlays)
+	if err != nil {
+		return nil, err
+	}
+	chartType, err := parseChartType(req)
+	if err != nil {
+		return nil, err
+	}
+	if chartType.warmup() > warmup {
+		warmup = chartType.warmup()
+	}
+	now := time.Now().In(loc)
+	from, to, err := chartRange(req, interval, now)
+	if err != nil {
+		return nil, err
+	}
+
+	stock, err := s.GetStock(req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate the warm-up bars of the overlays and the ATR box size too, so
+	// they have values from the first bar
+	start := from
+	if warmup > 0 {
+		start = warmupStart(interval, from, warmup)
+		if earliest := chartEarliest(interval, now); start.Before(earliest) {
+			start = earliest
+		}
+	}
+	var data []models.CandlestickData
+	if interval.intraday() {
+		data = s.generateMockIntradayData(stock.Price, stock.Volume, interval.step, start, to)
+	} else {
+		days := int(math.Round(to.Sub(start).Hours()/24)) + 1
+		data = resampleBars(s.generateMockChartData(stock.Price, stock.Volume, to, days), interval.period)
+	}
+
+	response := &models.ChartDataResponse{
+		Symbol:   stock.Symbol,
+		Interval: interval.name,
+		Type:     chartType.name,
+		Timezone: loc.String(),
+	}
+
+	series := overlaySeries(data, overlays)
+	if chartType.priceBased() {
+		response.BoxSize = chartType.boxSize
+		if response.BoxSize == 0 {
+			if response.BoxSize, err = atrBoxSize(data, chartType.atrPeriod); err != nil {
+				return nil, err
+			}
+		} else if minBox := minBoxSize(data); response.BoxSize < minBox {
+			return nil, fmt.Errorf("%w: boxSize must be at least %.2f, 1/%d of the highest close", ErrInvalidChart, minBox, minBoxFraction)
+		}
+	}
+	candles := data
+	if chartType.name == ChartHeikinAshi {
+		candles = heikinAshi(data)
+	}
+	first := firstBarFrom(data, from, loc)
+	data, candles = data[first:], candles[first:]
+	for name := range series {
+		series[name] = series[name][first:]
+	}
+	if req.Levels {
+		// All three levels come from the chart's own bars, so the equilibrium
+		// stays between support and resistance on every interval
+		equilibrium := NewEquilibriumCalculator(chartLevelLookback).CalculateEquilibrium(data, stock.Price)
+		response.Equilibrium = &equilibrium
+		response.Levels = []models.ChartLevel{
+			{Name: "support", Price: roundCents(equilibrium.Support)},
+			{Name: "equilibrium", Price: roundCents((equilibrium.Support + equilibrium.Resistance) / 2)},
+			{Name: "resistance", Price: roundCents(equilibrium.Resistance)},
+		}
+	}
+
+	response.From = data[0].Time
+	response.To = data[len(data)-1].Time
+	switch chartType.name {
+	case ChartRenko:
+		response.Bricks = renkoBricks(data, response.BoxSize, req.MaxBars)
+		return response, nil
+	case ChartPointAndFigure:
+		response.Reversal = chartType.reversal
+		response.Columns = pointAndFigure(data, response.BoxSize, chartType.reversal, req.MaxBars)
+		return response, nil
+	}
+
+	response.Data, response.BarsPerCandle = downsampleBars(candles, req.MaxBars)
+	if len(series) > 0 {
+		response.Overlays = make(map[string][]*float64, len(series))
+		for name, values := range series {
+			response.Overlays[name] = downsampleSeries(values, response.BarsPerCandle)
+		}
+	}
+	return response, nil
+}
+
+// overlaySeries calculates the requested overlays for every bar. Bollinger
+// Bands yield bbUpper, bbMiddle and bbLower series.
+func overlaySeries(bars []models.CandlestickData, overlays map[string]bool) map[string][]float64 {
+	series := make(map[string][]float64)
+	closes := make([]float64, len(bars))
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+	if overlays["sma50"] {
+		series["sma50"] = smaSeries(closes, 50)
+	}
+	if overlays["sma200"] {
+		series["sma200"] = smaSeries(closes, 200)
+	}
+	if overlays["bbands"] {
+		series["bbMiddle"], series["bbUpper"], series["bbLower"] = bollingerSeries(closes, chartBollingerWindow, chartBollingerWidth)
+	}
+	if overlays["equilibrium"] {
+		series["equilibrium"] = equilibriumSeries(bars, chartEquilibriumLookback)
+	}
+	return series
+}
+
+// warmupStart returns a start far enough before from to hold the given
+// number of bars of the interval
+func warmupStart(interval chartInterval, from time.Time, bars int) time.Time {
+	switch {
+	case interval.intraday():
+		perSession := int(sessionLength / interval.step)
+		return sessionsStart(from.Add(-time.Nanosecond), (bars+perSession-1)/perSession)
+	case interval.period == "week":
+		return from.AddDate(0, 0, -7*bars)
+	case interval.period == "month":
+		return from.AddDate(0, -bars, 0)
+	default:
+		return from.AddDate(0, 0, -bars)
+	}
+}
+
+// firstBarFrom returns the index of the first bar covering from: the last
+// bar, or the first one followed by a bar starting after from
+func firstBarFrom(bars []models.CandlestickData, from time.Time, loc *time.Location) int {
+	for i := 0; i < len(bars)-1; i++ {
+		if next, err := parseChartTime(bars[i+1].Time, loc, false); err == nil && next.After(from) {
+			return i
+		}
+	}
+	return len(bars) - 1
+}
+
+// lastN returns the last n items of a slice
+func lastN[T any](items []T, n int) []T {
+	if len(items) > n {
+		return items[len(items)-n:]
+	}
+	return items
+}
+
+// roundCents rounds a price to cents
+func roundCents(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+// chartRange resolves the first and last bar times of a request. Daily and
+// longer ranges are whole dates in the request's timezone; intraday ranges
+// start at the open of their first session. A to in the future is clamped to
+// now, and a range past the interval's history is rejected.
+func chartRange(req models.ChartRequest, interval chartInterval, now time.Time) (from, to time.Time, err error) {
+	loc := now.Location()
+	earliest := chartEarliest(interval, now)
+
+	if req.Days != 0 && (req.From != "" || req.MaxHistory) || req.From != "" && req.MaxHistory {
+		return from, to, fmt.Errorf("%w: use only one of days, from and days=max", ErrInvalidChart)
+	}
+	if req.Days < 0 || req.Days > interval.historyDays {
+		return from, to, fmt.Errorf("%w: days must be between 1 and %d for %s bars", ErrInvalidChart, interval.historyDays, interval.name)
+	}
+
+	to = now
+	if req.To != "" {
+		if to, err = parseChartTime(req.To, loc, true); err != nil {
+			return from, to, err
+		}
+		if to.After(now) {
+			to = now
+		}
+	}
+
+	switch {
+	case req.From != "":
+		if from, err = parseChartTime(req.From, loc, false); err != nil {
+			return from, to, err
+		}
+		if from.Before(earliest) {
+			return from, to, fmt.Errorf("%w: from is before the %d-day history of %s bars", ErrInvalidChart, interval.historyDays, interval.name)
+		}
+	case req.MaxHistory:
+		from = earliest
+	default:
+		days := req.Days
+		if days == 0 {
+			days = interval.defaultDays
+		}
+		if interval.intraday() {
+			from = sessionsStart(to, days)
+		} else {
+			from = to.AddDate(0, 0, -(days - 1))
+		}
+	}
+
+	if !interval.intraday() {
+		from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+		to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+	}
+	if from.After(to) || interval.intraday() && from.Equal(to) {
+		return from, to, fmt.Errorf("%w: from must be before to", ErrInvalidChart)
+	}
+	if interval.intraday() && len(sessionBarTimes(interval.step, from, to)) == 0 {
+		return from, to, fmt.Errorf("%w: no trading session between from and to", ErrInvalidChart)
+	}
+	return from, to, nil
+}
+
+// chartEarliest returns the start of the interval's history
+func chartEarliest(interval chartInterval, now time.Time) time.Time {
+	if interval.intraday() {
+		return sessionsStart(now, interval.historyDays)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day()-(interval.historyDays-1), 0, 0, 0, 0, now.Location())
+}
+
+// parseChartTime parses a date ("2006-01-02") in loc or an RFC 3339
+// timestamp. A date stands for its start, or for its end when end is set.
+func parseChartTime(value string, loc *time.Location, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is not a date (YYYY-MM-DD) or RFC 3339 time", ErrInvalidChart, value)
+	}
+	if end {
+		return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return date, nil
+}
+
+// sessionsStart returns the open of the earliest of the last sessions trading
+// sessions that have opened by end
+func sessionsStart(end time.Time, sessions int) time.Time {
+	exchange := exchangeLocation()
+	end = end.In(exchange)
+
+	var open time.Time
+	for day, found := end, 0; found < sessions; day = day.AddDate(0, 0, -1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		candidate := time.Date(day.Year(), day.Month(), day.Day(), sessionOpenHour, sessionOpenMinute, 0, 0, exchange)
+		if candidate.After(end) {
+			continue // Today's session has not opened yet
+		}
+		open = candidate
+		found++
+	}
+	return open.In(end.Location())
+}
+
+// exchangeLocation returns the timezone session hours are defined in
+func exchangeLocation() *time.Location {
+	exchange, err := time.LoadLocation(defaultChartTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return exchange
+}
+
+// sessionBarTimes returns the start of every bar of the given length from
+// from to end that falls in a trading session, skipping weekends. Bars are
+// aligned to the session open; the last bar of a session may be shorter.
+func sessionBarTimes(step time.Duration, from, end time.Time) []time.Time {
+	exchange := exchangeLocation()
+	from, end = from.In(exchange), end.In(exchange)
+
+	var times []time.Time
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, exchange); !day.After(end); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		open := time.Date(day.Year(), day.Month(), day.Day(), sessionOpenHour, sessionOpenMinute, 0, 0, exchange)
+		close := open.Add(sessionLength)
+		for t := open; t.Before(close) && !t.After(end); t = t.Add(step) {
+			if !t.Before(from) {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// generateMockIntradayData generates a random walk of intraday bars whose
+// volatility and volume scale with the bar length
+func (s *MarketDataService) generateMockIntradayData(currentPrice float64, dailyVolume int64, step time.Duration, from, end time.Time) []models.CandlestickData {
+	times := sessionBarTimes(step, from, end)
+	data := make([]models.CandlestickData, len(times))
+	price := currentPrice * 0.99
+	scale := math.Sqrt(float64(step) / float64(sessionLength))
+	barVolume := float64(dailyVolume) * float64(step) / float64(sessionLength)
+
+	for i, t := range times {
+		change := (rand.Float64() - 0.5) * 0.04 * scale
+		open := price
+		close := price * (1 + change)
+		volatility := 0.015 * scale
+		high := math.Max(open, close) * (1 + rand.Float64()*volatility)
+		low := math.Min(open, close) * (1 - rand.Float64()*volatility)
+
+		data[i] = models.CandlestickData{
+			Time:   t.In(end.Location()).Format(time.RFC3339),
+			Open:   math.Round(open*100) / 100,
+			High:   math.Round(high*100) / 100,
+			Low:    math.Round(low*100) / 100,
+			Close:  math.Round(close*100) / 100,
+			Volume: int64(barVolume * (0.5 + rand.Float64())),
+		}
+		price = close
+	}
+	return data
+}
+
+// resampleBars aggregates daily bars into weekly (starting Monday) or monthly
+// bars dated by the first day of the period. Daily bars are returned as is.
+func resampleBars(daily []models.CandlestickData, period string) []models.CandlestickData {
+	if period == "day" {
+		return daily
+	}
+
+	var bars []models.CandlestickData
+	lastKey := ""
+	for _, bar := range daily {
+		date, err := time.Parse("2006-01-02", bar.Time)
+		if err != nil {
+			continue
+		}
+		var start time.Time
+		if period == "week" {
+			start = date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+		} else {
+			start = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		key := start.Format("2006-01-02")
+
+		if key != lastKey {
+			bar.Time = key
+			bars = append(bars, bar)
+			lastKey = key
+			continue
+		}
+		current := &bars[len(bars)-1]
+		current.High = math.Max(current.High, bar.High)
+		current.Low = math.Min(current.Low, bar.Low)
+		current.Close = bar.Close
+		current.Volume += bar.Volume
+	}
+	return bars
+}
+
+// downsampleBars merges runs of consecutive bars so at most maxBars remain.
+// Each merged bar keeps the time and open of its first bar, the close of its
+// last, the extremes of all and their total volume. It also returns how many
+// bars were merged into each.
+func downsampleBars(bars []models.CandlestickData, maxBars int) ([]models.CandlestickData, int) {
+	if len(bars) <= maxBars {
+		return bars, 1
+	}
+	size := (len(bars) + maxBars - 1) / maxBars
+	merged := make([]models.CandlestickData, 0, (len(bars)+size-1)/size)
+	for start := 0; start < len(bars); start += size {
+		end := start + size
+		if end > len(bars) {
+			end = len(bars)
+		}
+		bar := bars[start]
+		for _, next := range bars[start+1 : end] {
+			bar.High = math.Max(bar.High, next.High)
+			bar.Low = math.Min(bar.Low, next.Low)
+			bar.Close = next.Close
+			bar.Volume += next.Volume
+		}
+		merged = append(merged, bar)
+	}
+	return merged, size
+}
+
+// downsampleSeries aligns an overlay with downsampled bars by keeping its
+// value at the last bar of each merged run. NaN values become nulls.
+func downsampleSeries(values []float64, size int) []*float64 {
+	out := make([]*float64, 0, (len(values)+size-1)/size)
+	for start := 0; start < len(values); start += size {
+		end := start + size
+		if end > len(values) {
+			end = len(values)
+		}
+		if v := values[end-1]; !math.IsNaN(v) {
+			v = roundCents(v)
+			out = append(out, &v)
+		} else {
+			out = append(out, nil)
+		}
+	}
+	return out
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+// TestSessionBarTimes tests that intraday bars cover regular sessions only
+func TestSessionBarTimes(t *testing.T) {
+	exchange, err := time.LoadLocation(defaultChartTimezone)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Monday 10:00, before which the last two sessions are Friday and Monday
+	end := time.Date(2024, time.March, 4, 10, 0, 0, 0, exchange)
+	from := sessionsStart(end, 2)
+	if from.Weekday() != time.Friday {
+		t.Errorf("Expected the range to start on Friday, got %v", from)
+	}
+	times := sessionBarTimes(time.Hour, from, end)
+
+	// Friday 9:30 to 15:30 is 7 bars, Monday has only its 9:30 bar so far
+	if len(times) != 8 {
+		t.Fatalf("Expected 8 bars, got %d", len(times))
+	}
+	if first := times[0]; first.Weekday() != time.Friday || first.Hour() != 9 || first.Minute() != 30 {
+		t.Errorf("Expected the first bar at Friday 9:30, got %v", first)
+	}
+	if last := times[len(times)-1]; last.Weekday() != time.Monday || last.Hour() != 9 {
+		t.Errorf("Expected the last bar at Monday 9:30, got %v", last)
+	}
+	for _, bar := range times {
+		if bar.Weekday() == time.Saturday || bar.Weekday() == time.Sunday {
+			t.Errorf("Expected no weekend bars, got %v", bar)
+		}
+	}
+}
+
+// TestResampleBars tests weekly and monthly aggregation of daily bars
+func TestResampleBars(t *testing.T) {
+	daily := []models.CandlestickData{
+		{Time: "2024-01-30", Open: 10, High: 11, Low: 9, Close: 10.5, Volume: 100},  // Tuesday
+		{Time: "2024-01-31", Open: 10.5, High: 13, Low: 10, Close: 12, Volume: 200}, // Wednesday
+		{Time: "2024-02-01", Open: 12, High: 12.5, Low: 8, Close: 9, Volume: 300},   // Thursday
+		{Time: "2024-02-05", Open: 9, High: 10, Low: 8.5, Close: 9.5, Volume: 400},  // Monday
+	}
+
+	weekly := resampleBars(daily, "week")
+	if len(weekly) != 2 {
+		t.Fatalf("Expected 2 weekly bars, got %d", len(weekly))
+	}
+	want := models.CandlestickData{Time: "2024-01-29", Open: 10, High: 13, Low: 8, Close: 9, Volume: 600}
+	if weekly[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, weekly[0])
+	}
+	if weekly[1].Time != "2024-02-05" {
+		t.Errorf("Expected the second week to start 2024-02-05, got %s", weekly[1].Time)
+	}
+
+	monthly := resampleBars(daily, "month")
+	if len(monthly) != 2 || monthly[0].Time != "2024-01-01" || monthly[1].Time != "2024-02-01" {
+		t.Fatalf("Expected January and February bars, got %+v", monthly)
+	}
+	if monthly[0].Close != 12 || monthly[1].Open != 12 || monthly[1].Low != 8 {
+		t.Errorf("Unexpected monthly bars %+v", monthly)
+	}
+}
+
+// TestDownsampleBars tests that long series are merged down to the bar limit
+func TestDownsampleBars(t *testing.T) {
+	bars := make([]models.CandlestickData, 10)
+	for i := range bars {
+		price := float64(i + 1)
+		bars[i] = models.CandlestickData{Time: string(rune('a' + i)), Open: price, High: price + 1, Low: price - 1, Close: price, Volume: 10}
+	}
+
+	merged, size := downsampleBars(bars, 4)
+	if size != 3 || len(merged) != 4 {
+		t.Fatalf("Expected 4 bars of 3, got %d bars of %d", len(merged), size)
+	}
+	want := models.CandlestickData{Time: "a", Open: 1, High: 4, Low: 0, Close: 3, Volume: 30}
+	if merged[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, merged[0])
+	}
+	if last := merged[3]; last.Time != "j" || last.Volume != 10 {
+		t.Errorf("Expected the last bar to hold only the tenth, got %+v", last)
+	}
+
+	if same, size := downsampleBars(bars, 10); size != 1 || len(same) != 10 {
+		t.Errorf("Expected a short series to be returned as is, got %d bars of %d", len(same), size)
+	}
+}
+
+// TestChartRange tests how from, to and days resolve into a range
+func TestChartRange(t *testing.T) {
+	exchange := exchangeLocation()
+	now := time.Date(2024, time.March, 6, 12, 0, 0, 0, exchange) // Wednesday
+	daily, _ := lookupChartInterval("1d")
+	hourly, _ := lookupChartInterval("1h")
+
+	from, to, err := chartRange(models.ChartRequest{From: "2024-01-01", To: "2024-01-31"}, daily, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if from.Format("2006-01-02") != "2024-01-01" || to.Format("2006-01-02") != "2024-01-31" {
+		t.Errorf("Expected January, got %v to %v", from, to)
+	}
+
+	from, _, err = chartRange(models.ChartRequest{MaxHistory: true}, daily, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if days := int(now.Sub(from).Hours() / 24); days < maxChartHistory-2 || days > maxChartHistory {
+		t.Errorf("Expected about %d days of history, got %d", maxChartHistory, days)
+	}
+
+	_, to, err = chartRange(models.ChartRequest{To: "2030-01-01"}, daily, now)
+	if err != nil || to.Format("2006-01-02") != "2024-03-06" {
+		t.Errorf("Expected a future to to be clamped to today, got %v (%v)", to, err)
+	}
+
+	from, _, err = chartRange(models.ChartRequest{Days: 2}, hourly, now)
+	if err != nil || from.Weekday() != time.Tuesday || from.Hour() != 9 {
+		t.Errorf("Expected two sessions to start Tuesday 9:30, got %v (%v)", from, err)
+	}
+
+	for _, req := range []models.ChartRequest{
+		{From: "2024-02-01", To: "2024-01-01"},
+		{From: "01/02/2024"},
+		{From: "2000-01-01"},
+		{Days: 30, From: "2024-01-01"},
+		{Days: maxChartHistory + 1},
+		{Days: 1, MaxHistory: true},
+	} {
+		if _, _, err := chartRange(req, daily, now); !errors.Is(err, ErrInvalidChart) {
+			t.Errorf("Expected ErrInvalidChart for %+v, got %v", req, err)
+		}
+	}
+	weekend := models.ChartRequest{From: "2024-03-02T10:00:00-05:00", To: "2024-03-03T10:00:00-05:00"}
+	if _, _, err := chartRange(weekend, hourly, now); !errors.Is(err, ErrInvalidChart) {
+		t.Errorf("Expected ErrInvalidChart for a weekend range, got %v", err)
+	}
+}
+
+// TestGetChart tests interval selection, timestamps and parameter validation
+func TestGetChart(t *testing.T) {
+	_, marketData := newTestMarketDataService(t)
+
+	chart, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL", Interval: "15m", Days: 3, Timezone: "Europe/London"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if chart.Interval != "15m" || chart.Timezone != "Europe/London" {
+		t.Errorf("Expected 15m bars in Europe/London, got %s in %s", chart.Interval, chart.Timezone)
+	}
+	// Three sessions of 26 bars, the last possibly still in progress
+	if len(chart.Data) <= 2*26 || len(chart.Data) > 3*26 {
+		t.Errorf("Expected up to 78 bars, got %d", len(chart.Data))
+	}
+	london, _ := time.LoadLocation("Europe/London")
+	for _, bar := range chart.Data {
+		ts, err := time.Parse(time.RFC3339, bar.Time)
+		if err != nil {
+			t.Fatalf("Expected an RFC 3339 timestamp, got %q", bar.Time)
+		}
+		_, offset := ts.Zone()
+		if _, londonOffset := ts.In(london).Zone(); offset != londonOffset {
+			t.Errorf("Expected a London offset, got %q", bar.Time)
+		}
+	}
+
+	daily, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if daily.Interval != "1d" || len(daily.Data) != 90 {
+		t.Errorf("Expected 90 daily bars, got %d %s bars", len(daily.Data), daily.Interval)
+	}
+	if _, err := time.Parse("2006-01-02", daily.Data[0].Time); err != nil {
+		t.Errorf("Expected daily bars to be dated, got %q", daily.Data[0].Time)
+	}
+	if daily.Data[0].Volume <= 0 || daily.From != daily.Data[0].Time {
+		t.Errorf("Expected bars with volume starting at from, got %+v from %s", daily.Data[0], daily.From)
+	}
+
+	history, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL", MaxHistory: true, MaxBars: 500})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history.Data) > 500 || history.BarsPerCandle < 2 {
+		t.Errorf("Expected history downsampled to 500 bars, got %d bars of %d", len(history.Data), history.BarsPerCandle)
+	}
+
+	for _, req := range []models.ChartRequest{
+		{Symbol: "AAPL", Interval: "2h"},
+		{Symbol: "AAPL", Timezone: "Mars/Olympus"},
+		{Symbol: "AAPL", Interval: "1m", Days: 30},
+		{Symbol: "AAPL", MaxBars: maxChartBars + 1},
+	} {
+		if _, err := marketData.GetChart(req); !errors.Is(err, ErrInvalidChart) {
+			t.Errorf("Expected ErrInvalidChart for %+v, got %v", req, err)
+		}
+	}
+}
+
+// TestChartOverlays tests that overlays align with the bars and start warmed up
+func TestChartOverlays(t *testing.T) {
+	_, marketData := newTestMarketDataService(t)
+
+	for _, req := range []models.ChartRequest{
+		{Symbol: "AAPL", Days: 30, Overlays: []string{"sma200", "bbands", "equilibrium"}, Levels: true},
+		{Symbol: "AAPL", Interval: "5m", Days: 1, Overlays: []string{"sma50"}},
+		{Symbol: "AAPL", MaxHistory: true, MaxBars: 300, Overlays: []string{"sma50"}},
+	} {
+		chart, err := marketData.GetChart(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(chart.Overlays) == 0 {
+			t.Fatalf("Expected overlays for %+v", req)
+		}
+		for name, values := range chart.Overlays {
+			if len(values) != len(chart.Data) {
+				t.Errorf("Expected %s to have %d values, got %d", name, len(chart.Data), len(values))
+			}
+			if values[len(values)-1] == nil {
+				t.Errorf("Expected %s to have a value at the last bar", name)
+			}
+			if !req.MaxHistory && values[0] == nil {
+				t.Errorf("Expected %s to be warmed up at the first bar for %+v", name, req)
+			}
+		}
+		if req.MaxHistory && chart.Overlays["sma50"][0] != nil {
+			t.Errorf("Expected no sma50 value at the start of the history")
+		}
+	}
+
+	chart, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL", Overlays: []string{"bbands"}, Levels: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range chart.Data {
+		upper, middle, lower := chart.Overlays["bbUpper"][i], chart.Overlays["bbMiddle"][i], chart.Overlays["bbLower"][i]
+		if *upper < *middle || *middle < *lower {
+			t.Fatalf("Expected ordered bands at bar %d, got %v %v %v", i, *upper, *middle, *lower)
+		}
+	}
+	if len(chart.Levels) != 3 || chart.Equilibrium == nil {
+		t.Fatalf("Expected three levels and the equilibrium zone, got %+v", chart.Levels)
+	}
+	if chart.Levels[0].Name != "support" || chart.Levels[2].Name != "resistance" || chart.Levels[0].Price > chart.Levels[2].Price {
+		t.Errorf("Expected support below resistance, got %+v", chart.Levels)
+	}
+	if mid := (chart.Levels[0].Price + chart.Levels[2].Price) / 2; chart.Levels[1].Name != "equilibrium" || math.Abs(chart.Levels[1].Price-mid) > 0.01 {
+		t.Errorf("Expected the equilibrium midway between support and resistance, got %+v", chart.Levels)
+	}
+
+	if _, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL", Overlays: []string{"vwap"}}); !errors.Is(err, ErrInvalidChart) {
+		t.Errorf("Expected ErrInvalidChart for an unknown overlay, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"equilibrio-backend/internal/models"
+)
+
+// Chart types. Candlestick and Heikin-Ashi charts are time-based bars in
+// Data; Renko and point-and-figure charts only move with price and come back
+// as Bricks and Columns.
+const (
+	ChartCandlestick    = "candlestick"
+	ChartHeikinAshi     = "heikin-ashi"
+	ChartRenko          = "renko"
+	ChartPointAndFigure = "point-and-figure"
+)
+
+// Defaults of price-based chart types
+const (
+	defaultBoxATRPeriod = 14
+	maxBoxATRPeriod     = 200
+	defaultReversal     = 3
+	maxReversal         = 10
+	minBoxFraction      = 1000 // Boxes are at least 1/1000th of the highest close
+)
+
+// chartType holds the validated type parameters of a chart request
+type chartType struct {
+	name      string
+	boxSize   float64 // Zero to size boxes by ATR
+	atrPeriod int
+	reversal  int
+}
+
+// priceBased reports whether the type discards time, which rules out overlays
+func (t chartType) priceBased() bool {
+	return t.name == ChartRenko || t.name == ChartPointAndFigure
+}
+
+// parseChartType validates the type parameters of a chart request
+func parseChartType(req models.ChartRequest) (chartType, error) {
+	t := chartType{name: req.Type, boxSize: req.BoxSize, atrPeriod: req.ATRPeriod, reversal: req.Reversal}
+	switch t.name {
+	case "":
+		t.name = ChartCandlestick
+	case ChartCandlestick, ChartHeikinAshi, ChartRenko, ChartPointAndFigure:
+	default:
+		return chartType{}, fmt.Errorf("%w: unknown chart type %q (use %s, %s, %s or %s)",
+			ErrInvalidChart, t.name, ChartCandlestick, ChartHeikinAshi, ChartRenko, ChartPointAndFigure)
+	}
+
+	if t.boxSize < 0 || math.IsNaN(t.boxSize) || math.IsInf(t.boxSize, 0) {
+		return chartType{}, fmt.Errorf("%w: boxSize must be a positive price", ErrInvalidChart)
+	}
+	if t.atrPeriod == 0 {
+		t.atrPeriod = defaultBoxATRPeriod
+	}
+	if t.atrPeriod < 1 || t.atrPeriod > maxBoxATRPeriod {
+		return chartType{}, fmt.Errorf("%w: atrPeriod must be between 1 and %d", ErrInvalidChart, maxBoxATRPeriod)
+	}
+	if t.reversal == 0 {
+		t.reversal = defaultReversal
+	}
+	if t.reversal < 1 || t.reversal > maxReversal {
+		return chartType{}, fmt.Errorf("%w: reversal must be between 1 and %d boxes", ErrInvalidChart, maxReversal)
+	}
+	if t.priceBased() && len(req.Overlays) > 0 {
+		return chartType{}, fmt.Errorf("%w: overlays need a candlestick or heikin-ashi chart", ErrInvalidChart)
+	}
+	return t, nil
+}
+
+// warmup returns the bars of history needed before the range, for the ATR
+// that sizes boxes
+func (t chartType) warmup() int {
+	if t.priceBased() && t.boxSize == 0 {
+		return t.atrPeriod + 1
+	}
+	return 0
+}
+
+// minBoxSize returns the smallest box size allowed for the bars. Tiny boxes
+// would turn every price move into a flood of bricks or boxes.
+func minBoxSize(bars []models.CandlestickData) float64 {
+	highest := 0.0
+	for _, bar := range bars {
+		highest = math.Max(highest, bar.Close)
+	}
+	return math.Max(math.Ceil(highest/minBoxFraction*100)/100, 0.01)
+}
+
+// atrBoxSize sizes boxes by the latest Average True Range of the bars,
+// rounded to cents and raised to the minimum box size
+func atrBoxSize(bars []models.CandlestickData, period int) (float64, error) {
+	atr := atrSeries(bars, period)
+	for i := len(atr) - 1; i >= 0; i-- {
+		if !math.IsNaN(atr[i]) {
+			return math.Max(roundCents(atr[i]), minBoxSize(bars)), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: not enough bars for a %d-bar ATR box size, pass boxSize", ErrInvalidChart, period)
+}
+
+// tail collects items but only keeps the last limit of them, trimming in
+// batches so that collecting stays linear
+type tail[T any] struct {
+	items []T
+	limit int
+}
+
+func (t *tail[T]) add(item T) {
+	t.items = append(t.items, item)
+	if len(t.items) >= 2*t.limit {
+		n := copy(t.items, t.items[len(t.items)-t.limit:])
+		t.items = t.items[:n]
+	}
+}
+
+// last returns the kept items
+func (t *tail[T]) last() []T {
+	return lastN(t.items, t.limit)
+}
+
+// heikinAshi turns candles into Heikin-Ashi candles: each closes at the
+// average of its bar's prices and opens at the midpoint of the previous
+// Heikin-Ashi candle's body, which smooths out noise within a trend
+func heikinAshi(bars []models.CandlestickData) []models.CandlestickData {
+	out := make([]models.CandlestickData, len(bars))
+	var open, close float64
+	for i, bar := range bars {
+		if i == 0 {
+			open = (bar.Open + bar.Close) / 2
+		} else {
+			open = (open + close) / 2
+		}
+		close = (bar.Open + bar.High + bar.Low + bar.Close) / 4
+		out[i] = models.CandlestickData{
+			Time:   bar.Time,
+			Open:   roundCents(open),
+			High:   roundCents(math.Max(bar.High, math.Max(open, close))),
+			Low:    roundCents(math.Min(bar.Low, math.Min(open, close))),
+			Close:  roundCents(close),
+			Volume: bar.Volume,
+		}
+	}
+	return out
+}
+
+// renkoBricks lays bricks of one box size along the closes. A brick in the
+// trend's direction needs a close one box past the last brick; a reversal
+// needs a close two boxes past it, as the new brick starts at the far end
+// of the last one. Each brick is stamped with the bar that completed it.
+// Only the last limit bricks are returned.
+func renkoBricks(bars []models.CandlestickData, box float64, limit int) []models.RenkoBrick {
+	if len(bars) == 0 || box <= 0 || limit <= 0 {
+		return nil
+	}
+
+	bricks := tail[models.RenkoBrick]{limit: limit}
+	top, bottom := bars[0].Close, bars[0].Close // Extent of the last brick
+	for _, bar := range bars[1:] {
+		for bar.Close >= top+box {
+			bricks.add(models.RenkoBrick{Time: bar.Time, Open: roundCents(top), Close: roundCents(top + box), Direction: "up"})
+			bottom, top = top, top+box
+		}
+		for bar.Close <= bottom-box {
+			bricks.add(models.RenkoBrick{Time: bar.Time, Open: roundCents(bottom), Close: roundCents(bottom - box), Direction: "down"})
+			top, bottom = bottom, bottom-box
+		}
+	}
+	return bricks.last()
+}
+
+// pointAndFigure plots closes on a grid of boxes. A column of Xs extends
+// while closes fill new boxes above it and gives way to a column of Os once
+// a close falls reversal boxes below its top, and vice versa. Only the last
+// limit columns are returned.
+func pointAndFigure(bars []models.CandlestickData, box float64, reversal, limit int) []models.PointFigureColumn {
+	if len(bars) == 0 || box <= 0 || limit <= 0 {
+		return nil
+	}
+
+	// Work in whole boxes; the epsilon keeps closes on a box line from
+	// falling below it through rounding
+	const epsilon = 1e-9
+	boxesAbove := func(price float64) int { return int(math.Floor(price/box + epsilon)) }
+	boxesBelow := func(price float64) int { return int(math.Ceil(price/box - epsilon)) }
+
+	type column struct {
+		rising     bool
+		high, low  int
+		start, end string
+	}
+	columns := tail[column]{limit: limit}
+	base := boxesAbove(bars[0].Close)
+
+	for _, bar := range bars[1:] {
+		up, down := boxesAbove(bar.Close), boxesBelow(bar.Close)
+		if len(columns.items) == 0 {
+			switch {
+			case up > base:
+				columns.add(column{rising: true, low: base + 1, high: up, start: bar.Time, end: bar.Time})
+			case down < base:
+				columns.add(column{rising: false, high: base - 1, low: down, start: bar.Time, end: bar.Time})
+			}
+			continue
+		}
+
+		current := &columns.items[len(columns.items)-1]
+		switch {
+		case current.rising && up > current.high:
+			current.high, current.end = up, bar.Time
+		case current.rising && down <= current.high-reversal:
+			columns.add(column{rising: false, high: current.high - 1, low: down, start: bar.Time, end: bar.Time})
+		case !current.rising && down < current.low:
+			current.low, current.end = down, bar.Time
+		case !current.rising && up >= current.low+reversal:
+			columns.add(column{rising: true, low: current.low + 1, high: up, start: bar.Time, end: bar.Time})
+		}
+	}
+
+	kept := columns.last()
+	out := make([]models.PointFigureColumn, len(kept))
+	for i, c := range kept {
+		out[i] = models.PointFigureColumn{
+			Type:  "O",
+			Start: c.start,
+			End:   c.end,
+			High:  roundCents(float64(c.high) * box),
+			Low:   roundCents(float64(c.low) * box),
+			Boxes: c.high - c.low + 1,
+		}
+		if c.rising {
+			out[i].Type = "X"
+		}
+	}
+	return out
+}
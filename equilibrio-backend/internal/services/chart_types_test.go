@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"equilibrio-backend/internal/models"
+)
+
+// closesToBars builds bars that only move at the close
+func closesToBars(closes ...float64) []models.CandlestickData {
+	bars := make([]models.CandlestickData, len(closes))
+	for i, close := range closes {
+		bars[i] = models.CandlestickData{Time: string(rune('a' + i)), Open: close, High: close, Low: close, Close: close}
+	}
+	return bars
+}
+
+// TestHeikinAshi tests the Heikin-Ashi open and close recurrences
+func TestHeikinAshi(t *testing.T) {
+	bars := []models.CandlestickData{
+		{Time: "a", Open: 10, High: 12, Low: 9, Close: 11, Volume: 5},
+		{Time: "b", Open: 11, High: 14, Low: 10, Close: 13, Volume: 7},
+	}
+	ha := heikinAshi(bars)
+
+	// First: open (10+11)/2 = 10.5, close (10+12+9+11)/4 = 10.5
+	want := models.CandlestickData{Time: "a", Open: 10.5, High: 12, Low: 9, Close: 10.5, Volume: 5}
+	if ha[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, ha[0])
+	}
+	// Second: open (10.5+10.5)/2 = 10.5, close (11+14+10+13)/4 = 12
+	want = models.CandlestickData{Time: "b", Open: 10.5, High: 14, Low: 10, Close: 12, Volume: 7}
+	if ha[1] != want {
+		t.Errorf("Expected %+v, got %+v", want, ha[1])
+	}
+}
+
+// TestRenkoBricks tests continuation bricks and two-box reversals
+func TestRenkoBricks(t *testing.T) {
+	bricks := renkoBricks(closesToBars(100, 101.5, 103, 102, 101.1, 99), 1, 100)
+
+	want := []models.RenkoBrick{
+		{Time: "b", Open: 100, Close: 101, Direction: "up"},
+		{Time: "c", Open: 101, Close: 102, Direction: "up"},
+		{Time: "c", Open: 102, Close: 103, Direction: "up"},
+		// 102 and 101.1 do not reach 101, two boxes below the top
+		{Time: "f", Open: 102, Close: 101, Direction: "down"},
+		{Time: "f", Open: 101, Close: 100, Direction: "down"},
+		{Time: "f", Open: 100, Close: 99, Direction: "down"},
+	}
+	if len(bricks) != len(want) {
+		t.Fatalf("Expected %d bricks, got %+v", len(want), bricks)
+	}
+	for i := range want {
+		if bricks[i] != want[i] {
+			t.Errorf("Expected brick %d to be %+v, got %+v", i, want[i], bricks[i])
+		}
+	}
+
+	// Only the last bricks are kept, however many the closes lay
+	bricks = renkoBricks(closesToBars(100, 101.5, 103, 102, 101.1, 99), 1, 2)
+	if len(bricks) != 2 || bricks[0] != want[4] || bricks[1] != want[5] {
+		t.Errorf("Expected the last two bricks, got %+v", bricks)
+	}
+}
+
+// TestPointAndFigure tests column extension and reversals
+func TestPointAndFigure(t *testing.T) {
+	columns := pointAndFigure(closesToBars(100, 103.5, 105, 103, 102, 106, 107), 1, 3, 100)
+
+	want := []models.PointFigureColumn{
+		{Type: "X", Start: "b", End: "c", High: 105, Low: 101, Boxes: 5},
+		// 103 is only two boxes down; 102 completes the reversal
+		{Type: "O", Start: "e", End: "e", High: 104, Low: 102, Boxes: 3},
+		{Type: "X", Start: "f", End: "g", High: 107, Low: 103, Boxes: 5},
+	}
+	if len(columns) != len(want) {
+		t.Fatalf("Expected %d columns, got %+v", len(want), columns)
+	}
+	for i := range want {
+		if columns[i] != want[i] {
+			t.Errorf("Expected column %d to be %+v, got %+v", i, want[i], columns[i])
+		}
+	}
+}
+
+// TestChartTypes tests the chart types through GetChart
+func TestChartTypes(t *testing.T) {
+	_, marketData := newTestMarketDataService(t)
+
+	ha, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL", Type: ChartHeikinAshi, Overlays: []string{"sma50"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ha.Type != ChartHeikinAshi || len(ha.Data) != 90 || len(ha.Overlays["sma50"]) != 90 {
+		t.Errorf("Expected 90 Heikin-Ashi candles with overlays, got %d %s candles", len(ha.Data), ha.Type)
+	}
+
+	renko, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL", Type: ChartRenko, Days: 365})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if renko.BoxSize <= 0 || len(renko.Bricks) == 0 || renko.Data != nil {
+		t.Errorf("Expected ATR-sized bricks only, got box %v, %d bricks, %d candles", renko.BoxSize, len(renko.Bricks), len(renko.Data))
+	}
+
+	// The box must stay above 1/1000 of the random closes
+	pnf, err := marketData.GetChart(models.ChartRequest{Symbol: "AAPL", Type: ChartPointAndFigure, Days: 365, BoxSize: 5, MaxBars: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pnf.BoxSize != 5 || pnf.Reversal != defaultReversal || len(pnf.Columns) == 0 || len(pnf.Columns) > 5 {
+		t.Errorf("Expected up to 5 columns of 5 boxes, got box %v, %d columns", pnf.BoxSize, len(pnf.Columns))
+	}
+
+	for _, req := range []models.ChartRequest{
+		{Symbol: "AAPL", Type: "kagi"},
+		{Symbol: "AAPL", Type: ChartRenko, Overlays: []string{"sma50"}},
+		{Symbol: "AAPL", Type: ChartRenko, BoxSize: -1},
+		{Symbol: "AAPL", Type: ChartRenko, BoxSize: 0.0001, MaxHistory: true},
+		{Symbol: "AAPL", Type: ChartPointAndFigure, Reversal: maxReversal + 1},
+	} {
+		if _, err := marketData.GetChart(req); !errors.Is(err, ErrInvalidChart) {
+			t.Errorf("Expected ErrInvalidChart for %+v, got %v", req, err)
+		}
+	}
+}
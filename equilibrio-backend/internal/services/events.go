@@ -0,0 +1,353 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+// ErrInvalidEventFilter is returned when an event filter is unusable
+var ErrInvalidEventFilter = errors.New("invalid event filter")
+
+// Event types, named after the field whose change they report
+const (
+	EventSignal          = "signal"
+	EventTrend           = "trend"
+	EventEquilibriumZone = "equilibriumZone"
+)
+
+// EventFilter selects the events a subscriber receives; empty fields match all
+type EventFilter struct {
+	Universe string
+	Symbols  map[string]bool
+	Types    map[string]bool
+}
+
+// ParseEventFilter builds a filter from comma-separated symbols and types
+func ParseEventFilter(universe, symbols, types string) (EventFilter, error) {
+	filter := EventFilter{Universe: universe}
+	for _, symbol := range strings.Split(symbols, ",") {
+		if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+			if filter.Symbols == nil {
+				filter.Symbols = make(map[string]bool)
+			}
+			filter.Symbols[symbol] = true
+		}
+	}
+	for _, kind := range strings.Split(types, ",") {
+		switch kind = strings.TrimSpace(kind); kind {
+		case "":
+		case EventSignal, EventTrend, EventEquilibriumZone:
+			if filter.Types == nil {
+				filter.Types = make(map[string]bool)
+			}
+			filter.Types[kind] = true
+		default:
+			return EventFilter{}, fmt.Errorf("%w: unknown event type %q (use %s, %s or %s)",
+				ErrInvalidEventFilter, kind, EventSignal, EventTrend, EventEquilibriumZone)
+		}
+	}
+	return filter, nil
+}
+
+func (f EventFilter) matches(event *models.SignalEvent) bool {
+	return (f.Universe == "" || f.Universe == event.Universe) &&
+		(f.Symbols == nil || f.Symbols[event.Symbol]) &&
+		(f.Types == nil || f.Types[event.Type])
+}
+
+// EventService turns snapshot changes into signal, trend and equilibrium zone
+// events. Events are numbered in order and kept in a bounded backlog so a
+// subscriber that reconnects can resume after the last event it received.
+// Numbering starts at the time the service started in microseconds, so the
+// IDs of a restarted server follow those of the previous one.
+type EventService struct {
+	marketData *MarketDataService
+	interval   time.Duration
+	buffer     int
+
+	mu          sync.Mutex
+	firstID     int64 // ID of the first event of this process
+	nextID      int64
+	backlog     []models.SignalEvent // Ring buffer of the latest events
+	start       int                  // Index of the oldest event in the backlog
+	size        int
+	last        map[string]*Snapshot // Last snapshot compared per universe
+	subscribers map[*EventSubscription]struct{}
+	listeners   []func(models.SignalEvent)
+
+	stop chan struct{}
+}
+
+// NewEventService creates an event service fed by every snapshot the market
+// data service swaps in. It polls the universes of its subscribers, and the
+// default universe, so their snapshots keep being rebuilt.
+func NewEventService(cfg *config.Config, marketData *MarketDataService) *EventService {
+	backlog := cfg.EventsBacklog
+	if backlog <= 0 {
+		backlog = 1000
+	}
+	firstID := time.Now().UnixMicro()
+	e := &EventService{
+		marketData:  marketData,
+		interval:    cfg.StreamInterval,
+		buffer:      cfg.StreamBuffer,
+		firstID:     firstID,
+		nextID:      firstID,
+		backlog:     make([]models.SignalEvent, backlog),
+		last:        make(map[string]*Snapshot),
+		subscribers: make(map[*EventSubscription]struct{}),
+		stop:        make(chan struct{}),
+	}
+	if e.buffer <= 0 {
+		e.buffer = 256
+	}
+	marketData.OnSnapshot(e.observe)
+	if e.interval > 0 {
+		go e.poll()
+	}
+	return e
+}
+
+// Close stops polling and ends every subscription
+func (e *EventService) Close() {
+	close(e.stop)
+
+	e.mu.Lock()
+	subscriptions := make([]*EventSubscription, 0, len(e.subscribers))
+	for sub := range e.subscribers {
+		subscriptions = append(subscriptions, sub)
+	}
+	e.mu.Unlock()
+
+	for _, sub := range subscriptions {
+		sub.Close()
+	}
+}
+
+// OnEvent registers a function called with every event, including those of
+// snapshots replicated from other replicas
+func (e *EventService) OnEvent(listener func(models.SignalEvent)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.listeners = append(e.listeners, listener)
+}
+
+func (e *EventService) poll() {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			for _, universe := range e.watchedUniverses() {
+				if _, err := e.marketData.currentSnapshot(universe); err != nil {
+					log.Printf("Events: failed to read universe %s: %v", universe, err)
+				}
+			}
+		}
+	}
+}
+
+// watchedUniverses returns the default universe and those subscribers filter on
+func (e *EventService) watchedUniverses() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	universes := map[string]bool{e.marketData.universes.defaultName: true}
+	for sub := range e.subscribers {
+		if sub.filter.Universe != "" {
+			universes[sub.filter.Universe] = true
+		}
+	}
+	return sortedKeys(universes)
+}
+
+// observe compares a new snapshot with the last one of its universe and
+// publishes an event per changed signal, trend and equilibrium zone. The first
+// snapshot of a universe only becomes the baseline.
+func (e *EventService) observe(snapshot *Snapshot) {
+	e.mu.Lock()
+	prev := e.last[snapshot.Universe]
+	if prev != nil && prev.Version >= snapshot.Version {
+		e.mu.Unlock()
+		return
+	}
+	e.last[snapshot.Universe] = snapshot
+
+	var events []models.SignalEvent
+	if prev != nil {
+		now := time.Now().UTC()
+		for i := range snapshot.stocks {
+			after := &snapshot.stocks[i]
+			before, ok := prev.Stock(after.Symbol)
+			if !ok {
+				continue
+			}
+			for _, change := range []struct{ kind, from, to string }{
+				{EventSignal, before.Signal, after.Signal},
+				{EventTrend, before.Trend, after.Trend},
+				{EventEquilibriumZone, equilibriumZone(&before), equilibriumZone(after)},
+			} {
+				if change.from == change.to {
+					continue
+				}
+				event := models.SignalEvent{
+					ID:       e.nextID,
+					Type:     change.kind,
+					Universe: snapshot.Universe,
+					Symbol:   after.Symbol,
+					From:     change.from,
+					To:       change.to,
+					Price:    after.Price,
+					Version:  snapshot.Version,
+					Time:     now,
+				}
+				e.nextID++
+				e.append(event)
+				events = append(events, event)
+			}
+		}
+	}
+
+	// Fan out while holding the lock, so that concurrent snapshots cannot
+	// interleave and every subscriber receives the events in ID order.
+	// Enqueueing never blocks; subscribers that are full are removed here
+	// and ended once the lock is released.
+	var slow []*EventSubscription
+	for sub := range e.subscribers {
+		for i := range events {
+			if sub.filter.matches(&events[i]) && !sub.enqueue(events[i]) {
+				delete(e.subscribers, sub)
+				slow = append(slow, sub)
+				break
+			}
+		}
+	}
+	listeners := e.listeners
+	e.mu.Unlock()
+
+	for _, sub := range slow {
+		sub.end(ErrSlowConsumer)
+	}
+	for _, listener := range listeners {
+		for _, event := range events {
+			listener(event)
+		}
+	}
+}
+
+// append adds an event to the backlog, dropping the oldest when it is full
+func (e *EventService) append(event models.SignalEvent) {
+	if e.size < len(e.backlog) {
+		e.backlog[(e.start+e.size)%len(e.backlog)] = event
+		e.size++
+		return
+	}
+	e.backlog[e.start] = event
+	e.start = (e.start + 1) % len(e.backlog)
+}
+
+// Subscribe starts delivering events matching the filter. With a last event
+// ID it first returns the backlogged events after it; gap reports that some
+// of the events after it have already left the backlog, or that the ID was
+// not issued by this process, whose events may have been lost.
+func (e *EventService) Subscribe(lastEventID int64, filter EventFilter) (sub *EventSubscription, missed []models.SignalEvent, gap bool) {
+	sub = &EventSubscription{
+		service: e,
+		filter:  filter,
+		events:  make(chan models.SignalEvent, e.buffer),
+		done:    make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if lastEventID > 0 {
+		foreign := lastEventID < e.firstID-1 || lastEventID >= e.nextID
+		if foreign || e.size > 0 && e.backlog[e.start].ID > lastEventID+1 {
+			gap = true
+		}
+		for i := 0; i < e.size; i++ {
+			event := e.backlog[(e.start+i)%len(e.backlog)]
+			if event.ID > lastEventID && filter.matches(&event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+	e.subscribers[sub] = struct{}{}
+	return sub, missed, gap
+}
+
+// EventSubscription delivers events to one subscriber until it is closed. A
+// subscriber that falls a full buffer behind is closed with ErrSlowConsumer;
+// it can resume from its last event ID.
+type EventSubscription struct {
+	service *EventService
+	filter  EventFilter
+	events  chan models.SignalEvent
+	done    chan struct{}
+
+	closeOnce sync.Once
+	err       error
+}
+
+// Events returns the subscription's events
+func (s *EventSubscription) Events() <-chan models.SignalEvent {
+	return s.events
+}
+
+// Done is closed when the subscription ends
+func (s *EventSubscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the service ended the subscription, or nil
+func (s *EventSubscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the subscription
+func (s *EventSubscription) Close() {
+	s.end(nil)
+}
+
+func (s *EventSubscription) end(err error) {
+	s.closeOnce.Do(func() {
+		s.err = err
+		s.service.mu.Lock()
+		delete(s.service.subscribers, s)
+		s.service.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// enqueue buffers an event without blocking. It reports false when the
+// buffer is full and the subscriber should be ended as a slow consumer.
+func (s *EventSubscription) enqueue(event models.SignalEvent) bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+	}
+
+	select {
+	case s.events <- event:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+// testSnapshot builds a snapshot of the "test" universe from signals per symbol
+func testSnapshot(signals map[string]string) *Snapshot {
+	var stocks []models.StockData
+	for symbol, signal := range signals {
+		stocks = append(stocks, models.StockData{Symbol: symbol, Signal: signal, Trend: "neutral"})
+	}
+	return newSnapshot("test", time.Time{}, stocks, time.Now())
+}
+
+// TestEventsResume tests that signal changes become numbered events a subscriber can resume from
+func TestEventsResume(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	cfg.EventsBacklog = 2
+	events := NewEventService(cfg, marketData)
+	defer events.Close()
+
+	first := events.nextID
+	live, _, _ := events.Subscribe(0, EventFilter{})
+	events.observe(testSnapshot(map[string]string{"AAA": "hold", "BBB": "hold"}))
+	events.observe(testSnapshot(map[string]string{"AAA": "buy", "BBB": "hold"}))
+
+	event := <-live.Events()
+	if event.ID != first || event.Type != EventSignal || event.Symbol != "AAA" || event.From != "hold" || event.To != "buy" {
+		t.Errorf("Expected AAA's signal change as the first event, got %+v", event)
+	}
+
+	events.observe(testSnapshot(map[string]string{"AAA": "sell", "BBB": "buy"}))
+
+	_, missed, gap := events.Subscribe(first, EventFilter{})
+	if gap || len(missed) != 2 || missed[0].ID != first+1 || missed[1].ID != first+2 {
+		t.Errorf("Expected the second and third events after the first, got %+v (gap %v)", missed, gap)
+	}
+	_, missed, _ = events.Subscribe(first, EventFilter{Symbols: map[string]bool{"BBB": true}})
+	if len(missed) != 1 || missed[0].Symbol != "BBB" {
+		t.Errorf("Expected only BBB's event, got %+v", missed)
+	}
+	if _, missed, gap := events.Subscribe(0, EventFilter{}); gap || missed != nil {
+		t.Errorf("Expected a new subscriber to get no backlog, got %+v (gap %v)", missed, gap)
+	}
+}
+
+// TestEventsGap tests that resuming from an event no longer in the backlog is reported
+func TestEventsGap(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	cfg.EventsBacklog = 1
+	events := NewEventService(cfg, marketData)
+	defer events.Close()
+
+	first := events.nextID
+	events.observe(testSnapshot(map[string]string{"AAA": "hold"}))
+	events.observe(testSnapshot(map[string]string{"AAA": "buy"}))
+	events.observe(testSnapshot(map[string]string{"AAA": "sell"}))
+	events.observe(testSnapshot(map[string]string{"AAA": "hold"}))
+
+	_, missed, gap := events.Subscribe(first, EventFilter{})
+	if !gap || len(missed) != 1 || missed[0].ID != first+2 {
+		t.Errorf("Expected a gap before the third event, got %+v (gap %v)", missed, gap)
+	}
+}
+
+// TestEventsResumeAfterRestart tests that resuming with an ID this process did
+// not issue is reported as a gap, with the whole backlog for IDs of an
+// earlier process
+func TestEventsResumeAfterRestart(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	events := NewEventService(cfg, marketData)
+	defer events.Close()
+
+	// The previous process had issued 500 events
+	if _, missed, gap := events.Subscribe(500, EventFilter{}); !gap || missed != nil {
+		t.Errorf("Expected a gap and no backlog, got %+v (gap %v)", missed, gap)
+	}
+
+	events.observe(testSnapshot(map[string]string{"AAA": "hold"}))
+	events.observe(testSnapshot(map[string]string{"AAA": "buy"}))
+	if _, missed, gap := events.Subscribe(events.firstID-1000, EventFilter{}); !gap || len(missed) != 1 {
+		t.Errorf("Expected a gap and the backlog, got %+v (gap %v)", missed, gap)
+	}
+	if _, missed, gap := events.Subscribe(events.nextID+1000, EventFilter{}); !gap || missed != nil {
+		t.Errorf("Expected a gap and no backlog for an ID not issued yet, got %+v (gap %v)", missed, gap)
+	}
+}
+
+// TestEventsListenersSeeReplicatedSnapshots tests that listeners receive the
+// events of snapshots other replicas built
+func TestEventsListenersSeeReplicatedSnapshots(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	events := NewEventService(cfg, marketData)
+	defer events.Close()
+
+	var received []models.SignalEvent
+	events.OnEvent(func(event models.SignalEvent) { received = append(received, event) })
+
+	events.observe(testSnapshot(map[string]string{"AAA": "hold"}))
+	replicated := testSnapshot(map[string]string{"AAA": "buy"})
+	replicated.replicated = true
+	events.observe(replicated)
+
+	if len(received) != 1 || received[0].Symbol != "AAA" || received[0].To != "buy" {
+		t.Errorf("Expected AAA's signal change from the replicated snapshot, got %+v", received)
+	}
+}
+
+// TestEventsOrderedAcrossUniverses tests that a subscriber receives events in
+// ID order while snapshots of several universes are observed concurrently
+func TestEventsOrderedAcrossUniverses(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	cfg.StreamBuffer = 10000
+	events := NewEventService(cfg, marketData)
+	defer events.Close()
+
+	sub, _, _ := events.Subscribe(0, EventFilter{})
+
+	var wg sync.WaitGroup
+	for u := 0; u < 4; u++ {
+		wg.Add(1)
+		go func(universe string) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				stocks := make([]models.StockData, 50)
+				for j := range stocks {
+					stocks[j] = models.StockData{Symbol: fmt.Sprintf("S%02d", j), Signal: []string{"hold", "buy"}[i%2], Trend: "neutral"}
+				}
+				events.observe(newSnapshot(universe, time.Time{}, stocks, time.Now()))
+			}
+		}(fmt.Sprintf("u%d", u))
+	}
+	wg.Wait()
+
+	var last int64
+	received := 0
+	for done := false; !done; {
+		select {
+		case event := <-sub.Events():
+			if event.ID <= last {
+				t.Fatalf("Expected event IDs in order, got %d after %d", event.ID, last)
+			}
+			last = event.ID
+			received++
+		default:
+			done = true
+		}
+	}
+	if received == 0 || int64(received) != events.nextID-events.firstID {
+		t.Errorf("Expected all %d events, got %d", events.nextID-events.firstID, received)
+	}
+}
+
+// TestEventsSlowConsumer tests that a subscriber with a full buffer is ended
+func TestEventsSlowConsumer(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	cfg.StreamBuffer = 1
+	events := NewEventService(cfg, marketData)
+	defer events.Close()
+
+	sub, _, _ := events.Subscribe(0, EventFilter{})
+	events.observe(testSnapshot(map[string]string{"AAA": "hold", "BBB": "hold"}))
+	events.observe(testSnapshot(map[string]string{"AAA": "buy", "BBB": "buy"}))
+
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the subscription to end")
+	}
+	if !errors.Is(sub.Err(), ErrSlowConsumer) {
+		t.Errorf("Expected ErrSlowConsumer, got %v", sub.Err())
+	}
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if _, ok := events.subscribers[sub]; ok {
+		t.Errorf("Expected the slow subscriber to be removed")
+	}
+}
+
+// TestParseEventFilter tests parsing of event filters
+func TestParseEventFilter(t *testing.T) {
+	filter, err := ParseEventFilter("dow30", "aapl, msft", "signal,trend")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !filter.Symbols["AAPL"] || !filter.Symbols["MSFT"] || !filter.Types[EventTrend] || filter.Types[EventEquilibriumZone] {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+	if _, err := ParseEventFilter("", "", "price"); !errors.Is(err, ErrInvalidEventFilter) {
+		t.Errorf("Expected ErrInvalidEventFilter, got %v", err)
+	}
+}
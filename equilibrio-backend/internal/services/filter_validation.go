@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"equilibrio-backend/internal/models"
+)
+
+// ErrInvalidFilter is returned when a StockFilter contains unusable criteria
+var ErrInvalidFilter = errors.New("invalid filter")
+
+var (
+	validVolumeProfiles   = []string{"high", "medium", "low"}
+	validSignals          = []string{"buy", "sell", "hold"}
+	validTrends           = []string{"bullish", "bearish", "neutral"}
+	validEquilibriumZones = []string{"discount", "equilibrium", "premium"}
+)
+
+// ValidateFilter checks that a filter's ranges, enum values, sectors and
+// screener expression are all usable. Zero-valued ranges mean "no limit".
+func (s *MarketDataService) ValidateFilter(filter models.StockFilter) error {
+	if filter.RSIMin < 0 || filter.RSIMax < 0 || filter.RSIMin > 100 || filter.RSIMax > 100 {
+		return fmt.Errorf("%w: rsiMin and rsiMax must be between 0 and 100", ErrInvalidFilter)
+	}
+	if filter.RSIMax != 0 && filter.RSIMin > filter.RSIMax {
+		return fmt.Errorf("%w: rsiMin must not exceed rsiMax", ErrInvalidFilter)
+	}
+	if filter.PriceMin < 0 || filter.PriceMax < 0 {
+		return fmt.Errorf("%w: priceMin and priceMax must not be negative", ErrInvalidFilter)
+	}
+	if filter.PriceMax != 0 && filter.PriceMin > filter.PriceMax {
+		return fmt.Errorf("%w: priceMin must not exceed priceMax", ErrInvalidFilter)
+	}
+
+	sectors := s.universes.allSectors()
+
+	checks := []struct {
+		name    string
+		values  []string
+		allowed []string
+	}{
+		{"sectors", filter.Sectors, sectors},
+		{"volumeProfile", filter.VolumeProfile, validVolumeProfiles},
+		{"signals", filter.Signals, validSignals},
+		{"trend", filter.Trend, validTrends},
+		{"equilibriumZone", filter.EquilibriumZone, validEquilibriumZones},
+	}
+	for _, check := range checks {
+		for _, value := range check.values {
+			if !containsString(check.allowed, value) {
+				return fmt.Errorf("%w: unknown %s value %q", ErrInvalidFilter, check.name, value)
+			}
+		}
+	}
+
+	if err := validateRanges(filter.Ranges); err != nil {
+		return err
+	}
+
+	if _, err := parseFilterQuery(filter); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+
+	return nil
+}
+
+// DefaultFilterBounds sets an unset RSI or price maximum to the highest
+// value it can take. Each maximum defaults on its own, so a filter that only
+// sets a minimum stays open above it.
+func DefaultFilterBounds(rsiMax, priceMax *float64) {
+	if *rsiMax == 0 {
+		*rsiMax = 100
+	}
+	if *priceMax == 0 {
+		*priceMax = 10000
+	}
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
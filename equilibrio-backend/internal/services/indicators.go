@@ -6,6 +6,10 @@ import (
 	"equilibrio-backend/internal/models"
 )
 
+// MaxIndicatorPeriod is the longest indicator period. Each period is cached
+// under its own key, so the range of periods is bounded.
+const MaxIndicatorPeriod = 500
+
 type IndicatorService struct{}
 
 func NewIndicatorService() *IndicatorService {
@@ -127,3 +131,147 @@ func (s *IndicatorService) DetermineVolumeProfile(volume int64) string {
 	}
 	return "medium"
 }
+
+// rsiSeries calculates Wilder's RSI for every bar. Bars before the first
+// full period are NaN.
+func rsiSeries(closes []float64, period int) []float64 {
+	out := make([]float64, len(closes))
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	if period <= 0 || len(closes) <= period {
+		return out
+	}
+
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		diff := closes[i] - closes[i-1]
+		if diff > 0 {
+			gain += diff
+		} else {
+			loss -= diff
+		}
+	}
+	avgGain := gain / float64(period)
+	avgLoss := loss / float64(period)
+	out[period] = rsiFromAverages(avgGain, avgLoss)
+
+	for i := period + 1; i < len(closes); i++ {
+		diff := closes[i] - closes[i-1]
+		g, l := 0.0, 0.0
+		if diff > 0 {
+			g = diff
+		} else {
+			l = -diff
+		}
+		avgGain = (avgGain*float64(period-1) + g) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + l) / float64(period)
+		out[i] = rsiFromAverages(avgGain, avgLoss)
+	}
+
+	return out
+}
+
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		if avgGain == 0 {
+			return 50
+		}
+		return 100
+	}
+	rs := avgGain / avgLoss
+	return 100 - 100/(1+rs)
+}
+
+// equilibriumSeries calculates the midpoint of the highest high and lowest
+// low over the trailing lookback window. Bars before a full window are NaN.
+func equilibriumSeries(prices []models.CandlestickData, lookback int) []float64 {
+	out := make([]float64, len(prices))
+	for i := range out {
+		out[i] = math.NaN()
+		if lookback <= 0 || i+1 < lookback {
+			continue
+		}
+		high, low := prices[i].High, prices[i].Low
+		for j := i - lookback + 1; j < i; j++ {
+			high = math.Max(high, prices[j].High)
+			low = math.Min(low, prices[j].Low)
+		}
+		out[i] = (high + low) / 2
+	}
+	return out
+}
+
+// smaSeries calculates the simple moving average for every bar. Bars before
+// the first full window are NaN.
+func smaSeries(values []float64, window int) []float64 {
+	out := make([]float64, len(values))
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if window > 0 && i >= window {
+			sum -= values[i-window]
+		}
+		if window <= 0 || i+1 < window {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = sum / float64(window)
+	}
+	return out
+}
+
+// bollingerSeries calculates Bollinger Bands: the simple moving average of
+// the window and the bands width population standard deviations above and
+// below it. Bars before the first full window are NaN.
+func bollingerSeries(values []float64, window int, width float64) (middle, upper, lower []float64) {
+	middle = smaSeries(values, window)
+	upper = make([]float64, len(values))
+	lower = make([]float64, len(values))
+	for i, mean := range middle {
+		if math.IsNaN(mean) {
+			upper[i], lower[i] = math.NaN(), math.NaN()
+			continue
+		}
+		variance := 0.0
+		for _, v := range values[i-window+1 : i+1] {
+			variance += (v - mean) * (v - mean)
+		}
+		deviation := math.Sqrt(variance / float64(window))
+		upper[i] = mean + width*deviation
+		lower[i] = mean - width*deviation
+	}
+	return middle, upper, lower
+}
+
+// atrSeries calculates Wilder's Average True Range for every bar. Bars
+// before the first full period are NaN.
+func atrSeries(prices []models.CandlestickData, period int) []float64 {
+	out := make([]float64, len(prices))
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	if period <= 0 || len(prices) <= period {
+		return out
+	}
+
+	trueRange := func(i int) float64 {
+		prevClose := prices[i-1].Close
+		return math.Max(prices[i].High-prices[i].Low,
+			math.Max(math.Abs(prices[i].High-prevClose), math.Abs(prices[i].Low-prevClose)))
+	}
+
+	sum := 0.0
+	for i := 1; i <= period; i++ {
+		sum += trueRange(i)
+	}
+	atr := sum / float64(period)
+	out[period] = atr
+
+	for i := period + 1; i < len(prices); i++ {
+		atr = (atr*float64(period-1) + trueRange(i)) / float64(period)
+		out[i] = atr
+	}
+
+	return out
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// jsonStore keeps a set of records in memory and persists them to a single
+// JSON file on every change. Records that change too often to rewrite the
+// file every time can be staged in memory and flushed in batches instead.
+type jsonStore[T any] struct {
+	path  string
+	mode  os.FileMode // Permissions of the file, 0644 unless set before the first save
+	mu    sync.RWMutex
+	items map[string]T
+	dirty bool // Whether changes were staged since the last save
+}
+
+// newJSONStore loads the records stored at path. A missing file starts an empty store.
+func newJSONStore[T any](path string) (*jsonStore[T], error) {
+	store := &jsonStore[T]{
+		path:  path,
+		mode:  0o644,
+		items: make(map[string]T),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &store.items); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	return store, nil
+}
+
+// list returns all records
+func (s *jsonStore[T]) list() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]T, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	return items
+}
+
+// get returns the record with the given ID
+func (s *jsonStore[T]) get(id string) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	return item, ok
+}
+
+// put creates or replaces a record and persists the store
+func (s *jsonStore[T]) put(id string, item T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.items[id]
+	s.items[id] = item
+	if err := s.save(); err != nil {
+		if existed {
+			s.items[id] = previous
+		} else {
+			delete(s.items, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// delete removes a record and persists the store. It reports whether the record existed.
+func (s *jsonStore[T]) delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.items[id]
+	if !existed {
+		return false, nil
+	}
+	delete(s.items, id)
+	if err := s.save(); err != nil {
+		s.items[id] = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// update creates or replaces some records and removes others, and persists
+// the store once. On failure the store is left unchanged.
+func (s *jsonStore[T]) update(put map[string]T, remove []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[string]T, len(s.items))
+	for id, item := range s.items {
+		previous[id] = item
+	}
+	for id, item := range put {
+		s.items[id] = item
+	}
+	for _, id := range remove {
+		delete(s.items, id)
+	}
+	if err := s.save(); err != nil {
+		s.items = previous
+		return err
+	}
+	return nil
+}
+
+// stage creates or replaces some records and removes others in memory only;
+// flush persists them
+func (s *jsonStore[T]) stage(put map[string]T, remove []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, item := range put {
+		s.items[id] = item
+	}
+	for _, id := range remove {
+		delete(s.items, id)
+	}
+	if len(put) > 0 || len(remove) > 0 {
+		s.dirty = true
+	}
+}
+
+// flush persists the staged changes, if any. On failure they stay staged.
+func (s *jsonStore[T]) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+// save writes the store to a temporary file and renames it into place so a
+// crash never leaves a half-written file behind. Callers must hold the lock.
+func (s *jsonStore[T]) save() error {
+	data, err := json.MarshalIndent(s.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", s.path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", s.path, err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, s.mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	// WriteFile keeps the permissions of a leftover temporary file
+	if err := os.Chmod(tmp, s.mode); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", s.path, err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// newID returns a random identifier for stored records
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
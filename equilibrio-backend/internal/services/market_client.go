@@ -14,6 +14,10 @@ type MarketDataProvider interface {
 	// GetHistoricalPrices fetches historical price data
 	GetHistoricalPrices(ctx context.Context, symbol string, days int) ([]models.CandlestickData, error)
 
+	// GetPriceHistory fetches bars of an interval (1m, 5m, 15m, 1h, 1d, 1wk or
+	// 1mo) between from and to, timestamped in the location of from
+	GetPriceHistory(ctx context.Context, symbol, interval string, from, to time.Time) ([]models.CandlestickData, error)
+
 	// SearchSymbols searches for symbols matching criteria
 	SearchSymbols(ctx context.Context, query string) ([]string, error)
 
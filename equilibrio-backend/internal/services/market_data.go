@@ -2,61 +2,59 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"equilibrio-backend/internal/config"
 	"equilibrio-backend/internal/models"
-
-	"github.com/redis/go-redis/v9"
 )
 
 type MarketDataService struct {
-	config *config.Config
-	cache  *redis.Client
-}
+	config    *config.Config
+	cache     *CacheService
+	universes *UniverseService
 
-func NewMarketDataService(cfg *config.Config) *MarketDataService {
-	// Initialize Redis client
-	opt, err := redis.ParseURL(cfg.RedisURL)
-	if err != nil {
-		// Fallback to default Redis connection
-		opt = &redis.Options{
-			Addr: "localhost:6379",
-		}
-	}
+	snapshotsMu sync.Mutex
+	snapshots   map[string]*Snapshot
+	flights     flightGroup // Coalesces rebuilds of a universe's snapshot
 
-	rdb := redis.NewClient(opt)
+	listenersMu      sync.Mutex
+	listeners        []func(*Snapshot)
+	refreshListeners []func(models.RefreshResult)
 
+	searchMu sync.Mutex
+	search   map[string]*searchIndex
+}
+
+func NewMarketDataService(cfg *config.Config, cache *CacheService, universes *UniverseService) *MarketDataService {
 	return &MarketDataService{
-		config: cfg,
-		cache:  rdb,
+		config:    cfg,
+		cache:     cache,
+		universes: universes,
+		snapshots: make(map[string]*Snapshot),
+		search:    make(map[string]*searchIndex),
 	}
 }
 
-// GetStocks retrieves and filters stocks based on the request
-func (s *MarketDataService) GetStocks(req models.StockListRequest) ([]models.StockData, int, error) {
-	// Try to get from cache first
-	cacheKey := fmt.Sprintf("stocks:%s", s.generateCacheKey(req))
-	cached, err := s.cache.Get(context.Background(), cacheKey).Result()
-	if err == nil {
-		var cachedData struct {
-			Stocks []models.StockData `json:"stocks"`
-			Total  int                `json:"total"`
-		}
-		if json.Unmarshal([]byte(cached), &cachedData) == nil {
-			return cachedData.Stocks, cachedData.Total, nil
-		}
+// GetStocks filters, sorts and paginates the current snapshot of a universe.
+// A request with a cursor continues after the last row of the page that
+// issued it; otherwise Page selects the page.
+func (s *MarketDataService) GetStocks(req models.StockListRequest) (*models.StockPage, error) {
+	snapshot, err := s.currentSnapshot(req.Universe)
+	if err != nil {
+		return nil, err
 	}
+	return s.buildStockPage(req, snapshot)
+}
 
-	// Generate mock data (replace with real API calls)
-	stocks := s.generateMockStockData()
-
+// buildStockPage filters, sorts and paginates a snapshot's stocks
+func (s *MarketDataService) buildStockPage(req models.StockListRequest, snapshot *Snapshot) (*models.StockPage, error) {
 	// Create filter from request
 	filter := models.StockFilter{
 		SearchTerm:      req.SearchTerm,
@@ -69,80 +67,174 @@ func (s *MarketDataService) GetStocks(req models.StockListRequest) ([]models.Sto
 		Signals:         req.Signals,
 		Trend:           req.Trend,
 		EquilibriumZone: req.EquilibriumZone,
+		Query:           req.Query,
+		Ranges:          req.Ranges,
+	}
+
+	if err := validateRanges(filter.Ranges); err != nil {
+		return nil, err
+	}
+
+	query, err := parseFilterQuery(filter)
+	if err != nil {
+		return nil, err
 	}
 
+	sortKeys, err := requestSortKeys(req)
+	if err != nil {
+		return nil, err
+	}
+	columns := sortColumns(sortKeys)
+	fingerprint := s.requestFingerprint(req)
+
 	// Apply filters
-	filteredStocks := s.applyFilters(stocks, filter)
+	filteredStocks := s.applyFilters(snapshot.stocks, filter, query)
 
 	// Apply sorting
-	sortedStocks := s.applySorting(filteredStocks, req.SortField, req.SortOrder)
+	sortedStocks := s.applySorting(filteredStocks, sortKeys)
 
 	// Apply pagination
 	total := len(sortedStocks)
 	start := (req.Page - 1) * req.PageSize
-	end := start + req.PageSize
+	if req.Cursor != "" {
+		anchor, err := decodeCursor(req.Cursor, fingerprint, columns)
+		if err != nil {
+			return nil, err
+		}
+		start = pageAfter(sortedStocks, columns, anchor)
+	}
 
-	if start >= total {
-		return []models.StockData{}, total, nil
+	page := &models.StockPage{Stocks: []models.StockData{}, Total: total, Version: snapshot.Version, AsOf: snapshot.AsOf}
+	if start < total {
+		end := start + req.PageSize
+		if end > total {
+			end = total
+		}
+		page.Stocks = sortedStocks[start:end]
+		if end < total {
+			page.NextCursor = encodeCursor(snapshot.Version, fingerprint, columns, &sortedStocks[end-1])
+		}
 	}
 
-	if end > total {
-		end = total
+	return page, nil
+}
+
+// currentSnapshot returns the current snapshot of a universe; an empty name
+// selects the default universe. A new snapshot is built once the current one
+// is older than the snapshot TTL, after a refresh, or when the universe
+// changes. Concurrent builds of a universe are coalesced, and a snapshot
+// expired less than the cache's stale period ago is returned while its
+// replacement is built in the background.
+func (s *MarketDataService) currentSnapshot(universeName string) (*Snapshot, error) {
+	universe, err := s.universes.GetUniverse(universeName)
+	if err != nil {
+		return nil, err
 	}
 
-	paginatedStocks := sortedStocks[start:end]
+	s.snapshotsMu.Lock()
+	current := s.snapshots[universe.Name]
+	s.snapshotsMu.Unlock()
+
+	build := func() (interface{}, error) {
+		return s.buildSnapshot(universe), nil
+	}
+	if current != nil && current.universeUpdatedAt.Equal(universe.UpdatedAt) {
+		age := time.Since(current.AsOf)
+		fresh := age <= s.config.SnapshotTTL
+		stale := !fresh && age <= s.config.SnapshotTTL+s.cache.ttls.Stale
+		s.cache.countLookup(cacheTypeSnapshot, fresh, stale)
+		if stale {
+			s.flights.Go(universe.Name, build)
+		}
+		if fresh || stale {
+			return current, nil
+		}
+	} else {
+		s.cache.countLookup(cacheTypeSnapshot, false, false)
+	}
 
-	// Cache the result
-	cacheData := struct {
-		Stocks []models.StockData `json:"stocks"`
-		Total  int                `json:"total"`
-	}{
-		Stocks: paginatedStocks,
-		Total:  total,
+	value, _, shared := s.flights.Do(universe.Name, build)
+	if shared {
+		s.cache.stats[cacheTypeSnapshot].coalesced.Add(1)
 	}
+	return value.(*Snapshot), nil
+}
+
+// buildSnapshot generates a universe's data and swaps in a snapshot of it
+func (s *MarketDataService) buildSnapshot(universe *models.Universe) *Snapshot {
+	stocks := s.generateMockStockData(universe.Symbols)
+	applyCrossSectionalScores(stocks)
+	snapshot := newSnapshot(universe.Name, universe.UpdatedAt, stocks, time.Now())
 
-	if data, err := json.Marshal(cacheData); err == nil {
-		s.cache.Set(context.Background(), cacheKey, data, 30*time.Second)
+	s.snapshotsMu.Lock()
+	s.snapshots[universe.Name] = snapshot
+	s.snapshotsMu.Unlock()
+
+	s.notifySnapshot(snapshot)
+	return snapshot
+}
+
+// OnSnapshot registers a function called with every snapshot swapped in. It
+// is called outside any lock, possibly from several goroutines at once.
+func (s *MarketDataService) OnSnapshot(listener func(*Snapshot)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// installSnapshot swaps in a snapshot built by another instance unless the
+// current snapshot of its universe is as recent, reporting whether it did
+func (s *MarketDataService) installSnapshot(snapshot *Snapshot) bool {
+	s.snapshotsMu.Lock()
+	if current := s.snapshots[snapshot.Universe]; current != nil && current.Version >= snapshot.Version {
+		s.snapshotsMu.Unlock()
+		return false
 	}
+	s.snapshots[snapshot.Universe] = snapshot
+	s.snapshotsMu.Unlock()
+
+	s.notifySnapshot(snapshot)
+	return true
+}
+
+// notifySnapshot calls the snapshot listeners
+func (s *MarketDataService) notifySnapshot(snapshot *Snapshot) {
+	s.listenersMu.Lock()
+	listeners := s.listeners
+	s.listenersMu.Unlock()
 
-	return paginatedStocks, total, nil
+	for _, listener := range listeners {
+		listener(snapshot)
+	}
 }
 
 // GetStock retrieves a single stock by symbol
 func (s *MarketDataService) GetStock(symbol string) (*models.StockData, error) {
-	// Try cache first
-	cacheKey := fmt.Sprintf("stock:%s", strings.ToUpper(symbol))
-	cached, err := s.cache.Get(context.Background(), cacheKey).Result()
-	if err == nil {
-		var stock models.StockData
-		if json.Unmarshal([]byte(cached), &stock) == nil {
-			return &stock, nil
+	return s.cache.LoadStock(context.Background(), symbol, func() (*models.StockData, error) {
+		// Read the symbol from the data of a universe that contains it
+		universeName, _, ok := s.universes.findSymbol(symbol)
+		if !ok {
+			return nil, fmt.Errorf("stock not found: %s", symbol)
 		}
-	}
-
-	// Generate mock data for the symbol
-	stocks := s.generateMockStockData()
-	for _, stock := range stocks {
-		if strings.ToUpper(stock.Symbol) == strings.ToUpper(symbol) {
-			// Cache the result
-			if data, err := json.Marshal(stock); err == nil {
-				s.cache.Set(context.Background(), cacheKey, data, 30*time.Second)
-			}
-			return &stock, nil
+		snapshot, err := s.currentSnapshot(universeName)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	return nil, fmt.Errorf("stock not found: %s", symbol)
+		stock, ok := snapshot.Stock(symbol)
+		if !ok {
+			return nil, fmt.Errorf("stock not found: %s", symbol)
+		}
+		return &stock, nil
+	})
 }
 
-// GetSectors returns all available sectors
-func (s *MarketDataService) GetSectors() ([]string, error) {
-	sectors := []string{
-		"Technology", "Healthcare", "Financial", "Consumer Cyclical",
-		"Energy", "Industrials", "Consumer Defensive", "Real Estate",
-		"Communication Services", "Utilities", "Basic Materials",
+// GetSectors returns the sectors of a universe; an empty name selects the default universe
+func (s *MarketDataService) GetSectors(universeName string) ([]string, error) {
+	universe, err := s.universes.GetUniverse(universeName)
+	if err != nil {
+		return nil, err
 	}
-	return sectors, nil
+	return universeSectors(universe), nil
 }
 
 // GetStockChart returns candlestick chart data for a stock (default 90 days)
@@ -159,7 +251,7 @@ func (s *MarketDataService) GetStockChartWithDays(symbol string, days int) (*mod
 	}
 
 	// Generate mock candlestick data for specified days
-	data := s.generateMockChartData(stock.Price, days)
+	data := s.generateMockChartData(stock.Price, stock.Volume, time.Now(), days)
 
 	response := &models.ChartDataResponse{
 		Symbol: symbol,
@@ -169,16 +261,15 @@ func (s *MarketDataService) GetStockChartWithDays(symbol string, days int) (*mod
 	return response, nil
 }
 
-// generateMockChartData generates realistic candlestick data
-func (s *MarketDataService) generateMockChartData(currentPrice float64, days int) []models.CandlestickData {
+// generateMockChartData generates realistic daily candlestick data ending on
+// the date of end, with volume around the stock's daily volume
+func (s *MarketDataService) generateMockChartData(currentPrice float64, dailyVolume int64, end time.Time, days int) []models.CandlestickData {
 	data := make([]models.CandlestickData, days)
 	price := currentPrice * 0.95 // Start 5% below current price
 
-	now := time.Now()
-
 	for i := 0; i < days; i++ {
-		// Calculate date (going backwards from today)
-		date := now.AddDate(0, 0, -(days - i - 1))
+		// Calculate date (going backwards from end)
+		date := end.AddDate(0, 0, -(days - i - 1))
 
 		// Random price movement
 		change := (rand.Float64() - 0.5) * 0.04 // +/- 2% daily change
@@ -191,11 +282,12 @@ func (s *MarketDataService) generateMockChartData(currentPrice float64, days int
 		low := math.Min(open, close) * (1 - rand.Float64()*volatility)
 
 		data[i] = models.CandlestickData{
-			Time:  date.Format("2006-01-02"),
-			Open:  math.Round(open*100) / 100,
-			High:  math.Round(high*100) / 100,
-			Low:   math.Round(low*100) / 100,
-			Close: math.Round(close*100) / 100,
+			Time:   date.Format("2006-01-02"),
+			Open:   math.Round(open*100) / 100,
+			High:   math.Round(high*100) / 100,
+			Low:    math.Round(low*100) / 100,
+			Close:  math.Round(close*100) / 100,
+			Volume: int64(float64(dailyVolume) * (0.5 + rand.Float64())),
 		}
 
 		// Update price for next day
@@ -205,47 +297,162 @@ func (s *MarketDataService) generateMockChartData(currentPrice float64, days int
 	return data
 }
 
-// RefreshAllData refreshes all stock data
-func (s *MarketDataService) RefreshAllData() error {
-	// Clear cache
-	s.cache.FlushDB(context.Background())
+// ErrInvalidRefresh is returned when a refresh scope is unusable
+var ErrInvalidRefresh = errors.New("invalid refresh")
+
+// Refresh regenerates the data in scope and evicts only the cache entries
+// derived from it:
+//   - "all" (the default) rebuilds every snapshot and invalidates everything
+//   - "symbols" regenerates the listed symbols in every snapshot holding them
+//     and invalidates their entries
+//   - "universe" rebuilds one universe's snapshot and invalidates the entries
+//     of its symbols
+func (s *MarketDataService) Refresh(req models.RefreshRequest) (*models.RefreshResult, error) {
+	result, err := s.refresh(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.listenersMu.Lock()
+	listeners := s.refreshListeners
+	s.listenersMu.Unlock()
+	for _, listener := range listeners {
+		listener(*result)
+	}
+	return result, nil
+}
 
-	// In a real implementation, this would fetch fresh data from APIs
-	// For now, we'll just return success
-	return nil
+// OnRefresh registers a function called with the scope of every refresh
+// requested of this service
+func (s *MarketDataService) OnRefresh(listener func(models.RefreshResult)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.refreshListeners = append(s.refreshListeners, listener)
 }
 
-// generateMockStockData creates mock stock data (replace with real API integration)
-func (s *MarketDataService) generateMockStockData() []models.StockData {
-	tickers := []struct {
-		symbol string
-		name   string
-		sector string
-	}{
-		{"AAPL", "Apple Inc.", "Technology"},
-		{"MSFT", "Microsoft Corp.", "Technology"},
-		{"GOOGL", "Alphabet Inc.", "Communication Services"},
-		{"AMZN", "Amazon.com Inc.", "Consumer Cyclical"},
-		{"NVDA", "NVIDIA Corp.", "Technology"},
-		{"TSLA", "Tesla Inc.", "Consumer Cyclical"},
-		{"META", "Meta Platforms", "Communication Services"},
-		{"BRK.B", "Berkshire Hathaway", "Financial"},
-		{"JNJ", "Johnson & Johnson", "Healthcare"},
-		{"JPM", "JPMorgan Chase", "Financial"},
-		{"V", "Visa Inc.", "Financial"},
-		{"PG", "Procter & Gamble", "Consumer Defensive"},
-		{"MA", "Mastercard Inc.", "Financial"},
-		{"HD", "Home Depot", "Consumer Cyclical"},
-		{"BAC", "Bank of America", "Financial"},
-		{"XOM", "Exxon Mobil", "Energy"},
-		{"CVX", "Chevron Corp.", "Energy"},
-		{"ABBV", "AbbVie Inc.", "Healthcare"},
-		{"KO", "Coca-Cola Co.", "Consumer Defensive"},
-		{"PFE", "Pfizer Inc.", "Healthcare"},
+// refresh applies a refresh. A refresh replicated from another instance
+// (local false) only evicts: the instance that ran it publishes the snapshots
+// it regenerates.
+func (s *MarketDataService) refresh(req models.RefreshRequest, local bool) (*models.RefreshResult, error) {
+	ctx := context.Background()
+	if req.Scope == "" {
+		req.Scope = "all"
+	}
+	result := &models.RefreshResult{Scope: req.Scope}
+
+	switch req.Scope {
+	case "all":
+		if err := s.cache.Invalidate(ctx, tagAll); err != nil {
+			return nil, err
+		}
+		s.snapshotsMu.Lock()
+		s.snapshots = make(map[string]*Snapshot)
+		s.snapshotsMu.Unlock()
+
+	case "symbols":
+		if len(req.Symbols) == 0 {
+			return nil, fmt.Errorf("%w: symbols scope needs at least one symbol", ErrInvalidRefresh)
+		}
+		symbols := make(map[string]bool, len(req.Symbols))
+		var tags []string
+		for _, symbol := range req.Symbols {
+			symbol = strings.ToUpper(strings.TrimSpace(symbol))
+			if _, _, ok := s.universes.findSymbol(symbol); !ok && local {
+				return nil, fmt.Errorf("%w: unknown symbol %q", ErrInvalidRefresh, symbol)
+			}
+			symbols[symbol] = true
+			tags = append(tags, tagSymbol(symbol))
+		}
+		if err := s.cache.Invalidate(ctx, tags...); err != nil {
+			return nil, err
+		}
+		if local {
+			s.regenerateSymbols(symbols)
+		}
+		result.Symbols = sortedKeys(symbols)
+
+	case "universe":
+		universe, err := s.universes.GetUniverse(req.Universe)
+		if err != nil {
+			return nil, err
+		}
+		var tags []string
+		for _, sym := range universe.Symbols {
+			tags = append(tags, tagSymbol(sym.Symbol))
+		}
+		if err := s.cache.Invalidate(ctx, tags...); err != nil {
+			return nil, err
+		}
+		s.snapshotsMu.Lock()
+		delete(s.snapshots, universe.Name)
+		s.snapshotsMu.Unlock()
+		result.Universe = universe.Name
+
+	default:
+		return nil, fmt.Errorf("%w: scope must be all, symbols or universe, got %q", ErrInvalidRefresh, req.Scope)
+	}
+
+	// In a real implementation, this would fetch fresh data from APIs for the scope
+	return result, nil
+}
+
+// regenerateSymbols swaps in a new snapshot of every universe holding any of
+// the symbols, with those symbols regenerated and the universe rescored
+func (s *MarketDataService) regenerateSymbols(symbols map[string]bool) {
+	var swapped []*Snapshot
+	defer func() {
+		for _, snapshot := range swapped {
+			s.notifySnapshot(snapshot)
+		}
+	}()
+
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+
+	for name, current := range s.snapshots {
+		var stale []models.UniverseSymbol
+		for symbol := range symbols {
+			if stock, ok := current.Stock(symbol); ok {
+				stale = append(stale, models.UniverseSymbol{
+					Symbol: stock.Symbol, Name: stock.Name, Sector: stock.Sector, Industry: stock.Industry,
+				})
+			}
+		}
+		if len(stale) == 0 {
+			continue
+		}
+
+		stocks := current.Stocks()
+		for _, stock := range s.generateMockStockData(stale) {
+			stocks[current.bySymbol[stock.Symbol]] = stock
+		}
+		applyCrossSectionalScores(stocks)
+
+		s.snapshots[name] = newSnapshot(name, current.universeUpdatedAt, stocks, current.AsOf)
+		swapped = append(swapped, s.snapshots[name])
 	}
+}
 
+// equilibriumZone classifies a stock's price against its equilibrium level as
+// "discount" (more than 5% below), "premium" (more than 5% above) or
+// "equilibrium", or "" when the distance is unknown
+func equilibriumZone(stock *models.StockData) string {
+	switch {
+	case math.IsNaN(stock.PriceToEquilibrium):
+		return ""
+	case stock.PriceToEquilibrium < -5:
+		return "discount"
+	case stock.PriceToEquilibrium > 5:
+		return "premium"
+	default:
+		return "equilibrium"
+	}
+}
+
+// generateMockStockData creates mock stock data (replace with real API integration)
+func (s *MarketDataService) generateMockStockData(symbols []models.UniverseSymbol) []models.StockData {
 	var stocks []models.StockData
-	for _, ticker := range tickers {
+	for _, ticker := range symbols {
 		basePrice := rand.Float64()*500 + 50
 		changePercent := (rand.Float64() - 0.5) * 10
 		rsi := rand.Float64() * 100
@@ -301,14 +508,14 @@ func (s *MarketDataService) generateMockStockData() []models.StockData {
 		}
 
 		stock := models.StockData{
-			Symbol:                 ticker.symbol,
-			Name:                   ticker.name,
+			Symbol:                 ticker.Symbol,
+			Name:                   ticker.Name,
 			Price:                  basePrice,
 			Change:                 basePrice * (changePercent / 100),
 			ChangePercent:          changePercent,
 			Volume:                 int64(avgVolume),
-			Sector:                 ticker.sector,
-			Industry:               s.getIndustryForSector(ticker.sector),
+			Sector:                 ticker.Sector,
+			Industry:               ticker.Industry,
 			MarketCap:              basePrice * (rand.Float64()*1000000000 + 100000000),
 			RSI:                    rsi,
 			StochRSI:               rand.Float64() * 100,
@@ -329,6 +536,10 @@ func (s *MarketDataService) generateMockStockData() []models.StockData {
 			LastUpdated:            time.Now(),
 		}
 
+		if stock.Industry == "" {
+			stock.Industry = s.getIndustryForSector(ticker.Sector)
+		}
+
 		stocks = append(stocks, stock)
 	}
 
@@ -357,11 +568,26 @@ func (s *MarketDataService) getIndustryForSector(sector string) string {
 	return "General"
 }
 
-// applyFilters applies the filter criteria to the stock list
-func (s *MarketDataService) applyFilters(stocks []models.StockData, filter models.StockFilter) []models.StockData {
+// parseFilterQuery parses the screener expression of a filter, if any
+func parseFilterQuery(filter models.StockFilter) (*ScreenerQuery, error) {
+	if strings.TrimSpace(filter.Query) == "" {
+		return nil, nil
+	}
+	return ParseScreenerQuery(filter.Query)
+}
+
+// applyFilters applies the filter criteria and the parsed screener query,
+// which may be nil, to the stock list
+func (s *MarketDataService) applyFilters(stocks []models.StockData, filter models.StockFilter, query *ScreenerQuery) []models.StockData {
 	var filtered []models.StockData
+	ranges := filterRanges(filter)
 
 	for _, stock := range stocks {
+		// Screener expression
+		if query != nil && !query.Match(&stock) {
+			continue
+		}
+
 		// Search term filter
 		if filter.SearchTerm != "" {
 			searchLower := strings.ToLower(filter.SearchTerm)
@@ -385,13 +611,15 @@ func (s *MarketDataService) applyFilters(stocks []models.StockData, filter model
 			}
 		}
 
-		// RSI filter
-		if stock.RSI < filter.RSIMin || stock.RSI > filter.RSIMax {
-			continue
+		// Numeric range filters, including RSI and price
+		inRange := true
+		for _, r := range ranges {
+			if !r.contains(&stock) {
+				inRange = false
+				break
+			}
 		}
-
-		// Price filter
-		if stock.Price < filter.PriceMin || stock.Price > filter.PriceMax {
+		if !inRange {
 			continue
 		}
 
@@ -439,15 +667,10 @@ func (s *MarketDataService) applyFilters(stocks []models.StockData, filter model
 
 		// Equilibrium zone filter
 		if len(filter.EquilibriumZone) > 0 {
-			inDiscount := stock.PriceToEquilibrium < -5
-			inEquilibrium := stock.PriceToEquilibrium >= -5 && stock.PriceToEquilibrium <= 5
-			inPremium := stock.PriceToEquilibrium > 5
-
+			zone := equilibriumZone(&stock)
 			found := false
-			for _, zone := range filter.EquilibriumZone {
-				if (zone == "discount" && inDiscount) ||
-					(zone == "equilibrium" && inEquilibrium) ||
-					(zone == "premium" && inPremium) {
+			for _, z := range filter.EquilibriumZone {
+				if z == zone {
 					found = true
 					break
 				}
@@ -463,72 +686,137 @@ func (s *MarketDataService) applyFilters(stocks []models.StockData, filter model
 	return filtered
 }
 
-// applySorting applies sorting to the stock list
-func (s *MarketDataService) applySorting(stocks []models.StockData, sortField, sortOrder string) []models.StockData {
-	sort.Slice(stocks, func(i, j int) bool {
-		var aVal, bVal interface{}
-
-		switch sortField {
-		case "symbol":
-			aVal, bVal = stocks[i].Symbol, stocks[j].Symbol
-		case "name":
-			aVal, bVal = stocks[i].Name, stocks[j].Name
-		case "price":
-			aVal, bVal = stocks[i].Price, stocks[j].Price
-		case "changePercent":
-			aVal, bVal = stocks[i].ChangePercent, stocks[j].ChangePercent
-		case "rsi":
-			aVal, bVal = stocks[i].RSI, stocks[j].RSI
-		case "trend":
-			aVal, bVal = stocks[i].Trend, stocks[j].Trend
-		case "signal":
-			aVal, bVal = stocks[i].Signal, stocks[j].Signal
-		case "sector":
-			aVal, bVal = stocks[i].Sector, stocks[j].Sector
-		default:
-			aVal, bVal = stocks[i].Symbol, stocks[j].Symbol
-		}
-
-		// Handle numeric comparison
-		if aNum, aOk := aVal.(float64); aOk {
-			if bNum, bOk := bVal.(float64); bOk {
-				if sortOrder == "desc" {
-					return aNum > bNum
-				}
-				return aNum < bNum
-			}
+// ErrInvalidSort is returned when a sort specification names an unknown field or order
+var ErrInvalidSort = errors.New("invalid sort")
+
+// ParseSortKeys parses a sort specification such as
+// "priceToEquilibrium:asc,rsi:desc". The order defaults to ascending.
+func ParseSortKeys(spec string) ([]models.SortKey, error) {
+	var keys []models.SortKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, order, _ := strings.Cut(part, ":")
+		key, err := newSortKey(field, order)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: sort needs at least one field", ErrInvalidSort)
+	}
+	return keys, nil
+}
+
+// newSortKey validates a field and order and returns the sort key
+func newSortKey(field, order string) (models.SortKey, error) {
+	sf, ok := lookupStockField(strings.TrimSpace(field))
+	if !ok {
+		return models.SortKey{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidSort, field)
+	}
+	switch strings.ToLower(strings.TrimSpace(order)) {
+	case "", "asc":
+		return models.SortKey{Field: sf.name}, nil
+	case "desc":
+		return models.SortKey{Field: sf.name, Desc: true}, nil
+	default:
+		return models.SortKey{}, fmt.Errorf("%w: sort order for %s must be asc or desc, got %q", ErrInvalidSort, sf.name, order)
+	}
+}
+
+// requestSortKeys returns the sort keys of a request, preferring the
+// multi-key sort parameter over the legacy sortField/sortOrder pair
+func requestSortKeys(req models.StockListRequest) ([]models.SortKey, error) {
+	if req.Sort != "" {
+		return ParseSortKeys(req.Sort)
+	}
+	if req.SortField == "" {
+		return []models.SortKey{{Field: "symbol"}}, nil
+	}
+	key, err := newSortKey(req.SortField, req.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	return []models.SortKey{key}, nil
+}
+
+// sortColumn is a resolved sort key
+type sortColumn struct {
+	field stockField
+	desc  bool
+}
+
+// sortColumns resolves sort keys against the field registry and appends the
+// symbol so that ties on every key still have a deterministic order
+func sortColumns(keys []models.SortKey) []sortColumn {
+	columns := make([]sortColumn, 0, len(keys)+1)
+	for _, key := range keys {
+		if field, ok := lookupStockField(key.Field); ok {
+			columns = append(columns, sortColumn{field: field, desc: key.Desc})
 		}
+	}
+	symbol, _ := lookupStockField("symbol")
+	return append(columns, sortColumn{field: symbol})
+}
 
-		// Handle string comparison
-		aStr := fmt.Sprintf("%v", aVal)
-		bStr := fmt.Sprintf("%v", bVal)
-		if sortOrder == "desc" {
-			return aStr > bStr
+// compareStocks returns a negative number when a sorts before b, a positive
+// number when it sorts after, and 0 when the stocks tie on every column
+func compareStocks(columns []sortColumn, a, b *models.StockData) int {
+	for _, column := range columns {
+		c := compareField(column.field, a, b)
+		if c == 0 {
+			continue
 		}
-		return aStr < bStr
-	})
+		if column.desc && !isNaNField(column.field, a) && !isNaNField(column.field, b) {
+			return -c
+		}
+		return c
+	}
+	return 0
+}
 
+// applySorting sorts the stock list by each key in turn, comparing values by
+// their field type. Ties on every key fall back to the symbol so the order is
+// deterministic.
+func (s *MarketDataService) applySorting(stocks []models.StockData, keys []models.SortKey) []models.StockData {
+	columns := sortColumns(keys)
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return compareStocks(columns, &stocks[i], &stocks[j]) < 0
+	})
 	return stocks
 }
 
-// generateCacheKey creates a cache key from the request
-func (s *MarketDataService) generateCacheKey(req models.StockListRequest) string {
-	// Create a hash of the request parameters for caching
-	key := fmt.Sprintf("%s_%s_%d_%d_%s_%.1f_%.1f_%.1f_%.1f_%s_%s_%s_%s_%s",
-		req.SortField,
-		req.SortOrder,
-		req.Page,
-		req.PageSize,
-		req.SearchTerm,
-		req.RSIMin,
-		req.RSIMax,
-		req.PriceMin,
-		req.PriceMax,
-		strings.Join(req.Sectors, ","),
-		strings.Join(req.Signals, ","),
-		strings.Join(req.Trend, ","),
-		strings.Join(req.VolumeProfile, ","),
-		strings.Join(req.EquilibriumZone, ","),
-	)
-	return key
+// isNaNField reports whether a numeric field holds NaN
+func isNaNField(field stockField, stock *models.StockData) bool {
+	return field.kind == fieldNumber && math.IsNaN(field.number(stock))
+}
+
+// compareField returns -1, 0 or 1 comparing a field of two stocks. NaN
+// numbers compare greater than every other value; compareStocks keeps them
+// last in descending order too.
+func compareField(field stockField, a, b *models.StockData) int {
+	switch field.kind {
+	case fieldString:
+		return strings.Compare(strings.ToLower(field.text(a)), strings.ToLower(field.text(b)))
+	case fieldTime:
+		return field.time(a).Compare(field.time(b))
+	default:
+		x, y := field.number(a), field.number(b)
+		switch {
+		case math.IsNaN(x) && math.IsNaN(y):
+			return 0
+		case math.IsNaN(x):
+			return 1
+		case math.IsNaN(y):
+			return -1
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
 }
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"math"
 	"math/rand"
 	"testing"
@@ -54,37 +55,33 @@ func generateMockStock(symbol string, index int) models.StockData {
 
 	volumeProfiles := []string{"low", "normal", "high", "extreme"}
 	trends := []string{"bullish", "bearish", "sideways"}
-	equilibriumZones := []string{"support", "resistance", "neutral"}
+
+	week52High := price * (1 + rand.Float64()*0.3)
+	week52Low := price * (1 - rand.Float64()*0.3)
+	equilibrium := (week52High + week52Low) / 2
 
 	return models.StockData{
-		Symbol:              symbol,
-		Name:                "Company " + symbol,
-		Sector:              sectors[rand.Intn(len(sectors))],
-		Industry:            industries[rand.Intn(len(industries))],
-		Price:               math.Round(price*100) / 100,
-		Change:              math.Round(change*100) / 100,
-		ChangePercent:       math.Round(changePercent*100) / 100,
-		Volume:              int64(rand.Intn(10000000) + 1000000),
-		MarketCap:           int64(rand.Intn(1000000000000) + 1000000000),
-		PERatio:             math.Round((5+rand.Float64()*45)*100) / 100,
-		DividendYield:       math.Round(rand.Float64()*5*100) / 100,
-		Week52High:          math.Round((price*(1+rand.Float64()*0.3))*100) / 100,
-		Week52Low:           math.Round((price*(1-rand.Float64()*0.3))*100) / 100,
-		RSI:                 math.Round(rsi*100) / 100,
-		MACD:                math.Round((rand.Float64()-0.5)*10*100) / 100,
-		MovingAvg50:         math.Round((price*(1+(rand.Float64()-0.5)*0.1))*100) / 100,
-		MovingAvg200:        math.Round((price*(1+(rand.Float64()-0.5)*0.2))*100) / 100,
-		BollingerUpper:      math.Round((price*1.1)*100) / 100,
-		BollingerLower:      math.Round((price*0.9)*100) / 100,
-		ATR:                 math.Round(rand.Float64()*5*100) / 100,
-		Signal:              signal,
-		VolumeProfile:       volumeProfiles[rand.Intn(len(volumeProfiles))],
-		Trend:               trends[rand.Intn(len(trends))],
-		EquilibriumZone:     equilibriumZones[rand.Intn(len(equilibriumZones))],
-		EquilibriumStrength: math.Round((0.3+rand.Float64()*0.7)*100) / 100,
-		SupportLevel:        math.Round((price*0.95)*100) / 100,
-		ResistanceLevel:     math.Round((price*1.05)*100) / 100,
-		LastUpdated:         time.Now(),
+		Symbol:                 symbol,
+		Name:                   "Company " + symbol,
+		Sector:                 sectors[rand.Intn(len(sectors))],
+		Industry:               industries[rand.Intn(len(industries))],
+		Price:                  math.Round(price*100) / 100,
+		Change:                 math.Round(change*100) / 100,
+		ChangePercent:          math.Round(changePercent*100) / 100,
+		Volume:                 int64(rand.Intn(10000000) + 1000000),
+		MarketCap:              float64(rand.Intn(1000000000000) + 1000000000),
+		RSI:                    math.Round(rsi*100) / 100,
+		MACD:                   math.Round((rand.Float64()-0.5)*10*100) / 100,
+		SMA50:                  math.Round((price*(1+(rand.Float64()-0.5)*0.1))*100) / 100,
+		SMA200:                 math.Round((price*(1+(rand.Float64()-0.5)*0.2))*100) / 100,
+		EquilibriumLevel:       math.Round(equilibrium*100) / 100,
+		PriceToEquilibrium:     math.Round(((price-equilibrium)/equilibrium*100)*100) / 100,
+		Signal:                 signal,
+		VolumeProfile:          volumeProfiles[rand.Intn(len(volumeProfiles))],
+		Trend:                  trends[rand.Intn(len(trends))],
+		DistanceFrom52WeekHigh: math.Round(((price-week52High)/week52High*100)*100) / 100,
+		DistanceFrom52WeekLow:  math.Round(((price-week52Low)/week52Low*100)*100) / 100,
+		LastUpdated:            time.Now(),
 	}
 }
 
@@ -158,3 +155,220 @@ func TestGenerateMockChartData(t *testing.T) {
 		}
 	}
 }
+
+// TestApplyFiltersRanges tests generic min_/max_ ranges on numeric fields
+func TestApplyFiltersRanges(t *testing.T) {
+	ranges, err := ParseRangeParams(map[string][]string{
+		"min_marketCap":          {"1e9"},
+		"max_priceToEquilibrium": {"0"},
+		"sectors":                {"Technology"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ranges) != 2 {
+		t.Fatalf("Expected 2 ranges, got %d", len(ranges))
+	}
+
+	stocks := []models.StockData{
+		{Symbol: "BIG", Price: 50, RSI: 40, MarketCap: 5e9, PriceToEquilibrium: -5},
+		{Symbol: "SMALL", Price: 50, RSI: 40, MarketCap: 5e8, PriceToEquilibrium: -5},
+		{Symbol: "RICH", Price: 50, RSI: 40, MarketCap: 5e9, PriceToEquilibrium: 5},
+	}
+
+	s := &MarketDataService{}
+	filtered := s.applyFilters(stocks, models.StockFilter{RSIMax: 100, PriceMax: 10000, Ranges: ranges}, nil)
+	if len(filtered) != 1 || filtered[0].Symbol != "BIG" {
+		t.Errorf("Expected only BIG to match, got %+v", filtered)
+	}
+
+	invalid := []map[string][]string{
+		{"min_unknown": {"1"}},
+		{"min_sector": {"1"}},
+		{"max_rsi": {"high"}},
+		{"min_rsi": {"50"}, "max_rsi": {"30"}},
+	}
+	for _, params := range invalid {
+		if _, err := ParseRangeParams(params); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for %v, got %v", params, err)
+		}
+	}
+}
+
+// TestDefaultFilterBounds tests that a filter setting only a minimum stays
+// open above it
+func TestDefaultFilterBounds(t *testing.T) {
+	filter := models.StockFilter{RSIMin: 20, PriceMin: 100}
+	DefaultFilterBounds(&filter.RSIMax, &filter.PriceMax)
+	if filter.RSIMax != 100 || filter.PriceMax != 10000 {
+		t.Fatalf("Expected default maximums, got %+v", filter)
+	}
+
+	stocks := []models.StockData{
+		{Symbol: "LOW", Price: 150, RSI: 10},
+		{Symbol: "MID", Price: 150, RSI: 50},
+		{Symbol: "CHEAP", Price: 50, RSI: 50},
+	}
+	s := &MarketDataService{}
+	filtered := s.applyFilters(stocks, filter, nil)
+	if len(filtered) != 1 || filtered[0].Symbol != "MID" {
+		t.Errorf("Expected only MID to match, got %+v", filtered)
+	}
+
+	set := models.StockFilter{RSIMax: 30, PriceMax: 500}
+	DefaultFilterBounds(&set.RSIMax, &set.PriceMax)
+	if set.RSIMax != 30 || set.PriceMax != 500 {
+		t.Errorf("Expected set maximums to be kept, got %+v", set)
+	}
+}
+
+// TestApplySortingMultiKey tests multi-key, type-aware, stable sorting
+func TestApplySortingMultiKey(t *testing.T) {
+	keys, err := ParseSortKeys("priceToEquilibrium:asc, RSI:desc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if keys[1].Field != "rsi" || !keys[1].Desc {
+		t.Errorf("Expected rsi descending as second key, got %+v", keys[1])
+	}
+
+	stocks := []models.StockData{
+		{Symbol: "CCC", PriceToEquilibrium: -5, RSI: 30},
+		{Symbol: "AAA", PriceToEquilibrium: -5, RSI: 60},
+		{Symbol: "BBB", PriceToEquilibrium: -10, RSI: 50},
+		{Symbol: "DDD", PriceToEquilibrium: -5, RSI: 60},
+	}
+
+	s := &MarketDataService{}
+	sorted := s.applySorting(stocks, keys)
+	want := []string{"BBB", "AAA", "DDD", "CCC"}
+	for i, symbol := range want {
+		if sorted[i].Symbol != symbol {
+			t.Errorf("Position %d: expected %s, got %s", i, symbol, sorted[i].Symbol)
+		}
+	}
+
+	for _, spec := range []string{"unknown:asc", "rsi:sideways", ""} {
+		if _, err := ParseSortKeys(spec); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("Expected ErrInvalidSort for %q, got %v", spec, err)
+		}
+	}
+}
+
+// TestCursorPagination tests that a cursor resumes after the last row served,
+// even when rows are added before it in a newer data version
+func TestCursorPagination(t *testing.T) {
+	keys, _ := ParseSortKeys("rsi:desc")
+	columns := sortColumns(keys)
+
+	s := &MarketDataService{}
+	stocks := s.applySorting([]models.StockData{
+		{Symbol: "AAA", RSI: 70},
+		{Symbol: "BBB", RSI: 50},
+		{Symbol: "CCC", RSI: 50},
+		{Symbol: "DDD", RSI: 20},
+	}, keys)
+
+	cursor := encodeCursor(1, "fp", columns, &stocks[1])
+	if _, err := decodeCursor(cursor, "other", columns); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for a different fingerprint, got %v", err)
+	}
+	if _, err := decodeCursor("not-a-cursor", "fp", columns); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for a malformed cursor, got %v", err)
+	}
+
+	anchor, err := decodeCursor(cursor, "fp", columns)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if start := pageAfter(stocks, columns, anchor); stocks[start].Symbol != "CCC" {
+		t.Errorf("Expected next page to start at CCC, got %s", stocks[start].Symbol)
+	}
+
+	refreshed := s.applySorting(append(stocks, models.StockData{Symbol: "AAB", RSI: 90}), keys)
+	if start := pageAfter(refreshed, columns, anchor); refreshed[start].Symbol != "CCC" {
+		t.Errorf("Expected next page to still start at CCC, got %s", refreshed[start].Symbol)
+	}
+}
+
+// TestRequestFingerprintPrecision tests that filters differing below one
+// decimal place get different fingerprints
+func TestRequestFingerprintPrecision(t *testing.T) {
+	s := &MarketDataService{}
+	low, high := 30.01, 30.04
+	pairs := [][2]models.StockListRequest{
+		{{RSIMin: 30.01}, {RSIMin: 30.04}},
+		{{PriceMax: 100.02}, {PriceMax: 100.04}},
+		{{Ranges: []models.RangeFilter{{Field: "rsi", Min: &low}}}, {Ranges: []models.RangeFilter{{Field: "rsi", Min: &high}}}},
+	}
+	for _, pair := range pairs {
+		if s.requestFingerprint(pair[0]) == s.requestFingerprint(pair[1]) {
+			t.Errorf("Expected different fingerprints for %+v and %+v", pair[0], pair[1])
+		}
+	}
+	if s.requestFingerprint(pairs[0][0]) != s.requestFingerprint(models.StockListRequest{RSIMin: 30.01}) {
+		t.Errorf("Expected equal requests to share a fingerprint")
+	}
+}
+
+// TestProjectStocks tests field projection
+func TestProjectStocks(t *testing.T) {
+	fields, err := ParseFieldList("price, RSI")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rows := ProjectStocks([]models.StockData{{Symbol: "AAA", Price: 10, RSI: 40, Name: "A"}}, fields)
+	if len(rows[0]) != 3 || rows[0]["symbol"] != "AAA" || rows[0]["rsi"] != 40.0 {
+		t.Errorf("Unexpected projection: %+v", rows[0])
+	}
+
+	if _, err := ParseFieldList("price,bogus"); !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("Expected ErrInvalidFields, got %v", err)
+	}
+}
+
+// TestStockSnapshot tests that pages read one immutable snapshot until a refresh replaces it
+func TestStockSnapshot(t *testing.T) {
+	_, s := newTestMarketDataService(t)
+
+	first, err := s.GetStocks(models.StockListRequest{Page: 1, PageSize: 5, Sort: "price:desc", RSIMax: 100, PriceMax: 10000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := s.GetStocks(models.StockListRequest{Page: 2, PageSize: 5, RSIMax: 100, PriceMax: 10000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.Version != second.Version || !first.AsOf.Equal(second.AsOf) || first.AsOf.IsZero() {
+		t.Errorf("Expected pages of one snapshot, got versions %d and %d", first.Version, second.Version)
+	}
+
+	snapshot, _ := s.currentSnapshot("")
+	for i := 1; i < snapshot.Len(); i++ {
+		if snapshot.stocks[i-1].Symbol >= snapshot.stocks[i].Symbol {
+			t.Fatalf("Expected sorting a page to leave the snapshot in symbol order")
+		}
+	}
+
+	if _, err := s.Refresh(models.RefreshRequest{Scope: "symbols", Symbols: []string{"aapl"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	refreshed, _ := s.currentSnapshot("")
+	if refreshed.Version == snapshot.Version || !refreshed.AsOf.Equal(snapshot.AsOf) {
+		t.Errorf("Expected a symbol refresh to swap in a new version of the same cycle")
+	}
+	if mustStock(t, snapshot, "MSFT").Price != mustStock(t, refreshed, "MSFT").Price {
+		t.Errorf("Expected untouched symbols to carry over")
+	}
+}
+
+func mustStock(t *testing.T, snapshot *Snapshot, symbol string) models.StockData {
+	t.Helper()
+	stock, ok := snapshot.Stock(symbol)
+	if !ok {
+		t.Fatalf("Expected %s in the snapshot", symbol)
+	}
+	return stock
+}
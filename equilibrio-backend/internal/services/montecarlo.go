@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+const (
+	defaultSimulations = 1000
+	maxSimulations     = 20000
+)
+
+var defaultPercentiles = []float64{5, 25, 50, 75, 95}
+
+// MonteCarlo backtests the rules on a symbol and resamples the resulting trade
+// list to estimate the spread of returns and drawdowns the strategy could
+// plausibly have produced
+func (s *BacktestService) MonteCarlo(req models.MonteCarloRequest) (*models.MonteCarloResult, error) {
+	if req.Simulations == 0 {
+		req.Simulations = defaultSimulations
+	}
+	if req.Simulations < 0 || req.Simulations > maxSimulations {
+		return nil, fmt.Errorf("%w: simulations must be between 1 and %d", ErrInvalidBacktestConfig, maxSimulations)
+	}
+	if len(req.Percentiles) == 0 {
+		req.Percentiles = defaultPercentiles
+	}
+	for _, p := range req.Percentiles {
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("%w: percentiles must be between 0 and 100", ErrInvalidBacktestConfig)
+		}
+	}
+	if req.Seed == 0 {
+		req.Seed = time.Now().UnixNano()
+	}
+
+	backtest, err := s.Backtest(models.BacktestRequest{
+		Symbol: req.Symbol,
+		Days:   req.Days,
+		Rules:  req.Rules,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(backtest.Trades) < 2 {
+		return nil, fmt.Errorf("%w: backtest produced %d trades, need at least 2 to resample",
+			ErrInvalidBacktestConfig, len(backtest.Trades))
+	}
+
+	returns := make([]float64, len(backtest.Trades))
+	for i, trade := range backtest.Trades {
+		returns[i] = trade.ReturnPct / 100
+	}
+
+	rng := rand.New(rand.NewSource(req.Seed))
+
+	return &models.MonteCarloResult{
+		Symbol:      req.Symbol,
+		Simulations: req.Simulations,
+		Seed:        req.Seed,
+		Backtest:    backtest.Metrics,
+		Shuffle:     simulateTradeSequences("shuffle", returns, req.Simulations, req.Percentiles, rng, shuffleReturns),
+		Bootstrap:   simulateTradeSequences("bootstrap", returns, req.Simulations, req.Percentiles, rng, bootstrapReturns),
+	}, nil
+}
+
+// resampleFunc fills dst with a resampled sequence of the trade returns
+type resampleFunc func(dst, returns []float64, rng *rand.Rand)
+
+// shuffleReturns permutes the trade order. The final return is unchanged;
+// only the path, and with it the drawdown, varies.
+func shuffleReturns(dst, returns []float64, rng *rand.Rand) {
+	copy(dst, returns)
+	rng.Shuffle(len(dst), func(i, j int) {
+		dst[i], dst[j] = dst[j], dst[i]
+	})
+}
+
+// bootstrapReturns draws trades with replacement, varying both the final
+// return and the path
+func bootstrapReturns(dst, returns []float64, rng *rand.Rand) {
+	for i := range dst {
+		dst[i] = returns[rng.Intn(len(returns))]
+	}
+}
+
+// simulateTradeSequences compounds resampled trade sequences and summarizes
+// the distribution of final returns and maximum drawdowns
+func simulateTradeSequences(method string, returns []float64, simulations int, percentiles []float64, rng *rand.Rand, resample resampleFunc) models.MonteCarloDistribution {
+	finalReturns := make([]float64, simulations)
+	drawdowns := make([]float64, simulations)
+	sequence := make([]float64, len(returns))
+	equity := make([]float64, len(returns)+1) // equity[0] is the starting value
+	equity[0] = 1
+
+	losses := 0
+	for sim := 0; sim < simulations; sim++ {
+		resample(sequence, returns, rng)
+
+		value := 1.0
+		for i, r := range sequence {
+			value *= 1 + r
+			equity[i+1] = value
+		}
+
+		finalReturns[sim] = (value - 1) * 100
+		drawdowns[sim] = maxDrawdown(equity)
+		if value < 1 {
+			losses++
+		}
+	}
+
+	return models.MonteCarloDistribution{
+		Method:              method,
+		ReturnPercentiles:   percentileValues(finalReturns, percentiles),
+		DrawdownPercentiles: percentileValues(drawdowns, percentiles),
+		MeanReturn:          mean(finalReturns),
+		MeanDrawdown:        mean(drawdowns),
+		ProbabilityOfLoss:   float64(losses) / float64(simulations) * 100,
+	}
+}
+
+// percentileValues returns the linearly interpolated percentiles of values
+func percentileValues(values []float64, percentiles []float64) []models.PercentileValue {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	out := make([]models.PercentileValue, len(percentiles))
+	for i, p := range percentiles {
+		out[i] = models.PercentileValue{Percentile: p, Value: percentile(sorted, p)}
+	}
+	return out
+}
+
+// percentile returns the p-th percentile of an ascending slice
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
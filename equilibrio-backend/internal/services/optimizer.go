@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+const maxParameterSets = 5000
+
+// walkForwardFold is a pair of adjacent in-sample and out-of-sample bar windows
+type walkForwardFold struct {
+	inSampleFrom, inSampleTo       int
+	outOfSampleFrom, outOfSampleTo int
+}
+
+// Optimize sweeps the parameter grid and scores every parameter set on
+// walk-forward out-of-sample windows. Parameter sets are evaluated
+// concurrently across all CPU cores.
+func (s *BacktestService) Optimize(req models.OptimizationRequest) (*models.OptimizationResponse, error) {
+	rankBy := req.RankBy
+	if rankBy == "" {
+		rankBy = "sharpe"
+	}
+	if rankBy != "sharpe" && rankBy != "return" && rankBy != "drawdown" {
+		return nil, fmt.Errorf("%w: rankBy must be one of sharpe, return, drawdown", ErrInvalidBacktestConfig)
+	}
+
+	// Check the size before expanding, as a large grid would not fit in memory
+	if size := gridSize(req.Grid); size > maxParameterSets {
+		return nil, fmt.Errorf("%w: grid expands to %.0f parameter sets, limit is %d",
+			ErrInvalidBacktestConfig, size, maxParameterSets)
+	}
+	sets := expandGrid(withDefaultRules(req.Rules), req.Grid)
+
+	prices, err := s.loadHistory(req.Symbol, req.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	folds, err := walkForwardFolds(len(prices), req.WalkForward)
+	if err != nil {
+		return nil, err
+	}
+
+	results := evaluateParameterSets(prices, sets, folds)
+	rankParameterSets(results, rankBy)
+
+	return &models.OptimizationResponse{
+		Symbol:      req.Symbol,
+		Folds:       len(folds),
+		Evaluated:   len(results),
+		RankBy:      rankBy,
+		Results:     results,
+		CompletedAt: time.Now(),
+	}, nil
+}
+
+// evaluateParameterSets evaluates parameter sets concurrently across all CPU
+// cores, returning their results in the order of the sets
+func evaluateParameterSets(prices []models.CandlestickData, sets []models.SignalRules, folds []walkForwardFold) []models.ParameterSetResult {
+	results := make([]models.ParameterSetResult, len(sets))
+	jobs := make(chan int)
+
+	workers := runtime.NumCPU()
+	if workers > len(sets) {
+		workers = len(sets)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = evaluateParameterSet(prices, sets[i], folds)
+			}
+		}()
+	}
+	for i := range sets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// gridSize returns the number of parameter sets a grid expands to, as a
+// float so that the product of huge dimensions cannot overflow
+func gridSize(grid models.ParameterGrid) float64 {
+	size := 1.0
+	for _, n := range []int{len(grid.RSIBuyBelow), len(grid.RSISellAbove), len(grid.DiscountBelow), len(grid.PremiumAbove), len(grid.EquilibriumLookback)} {
+		if n > 0 {
+			size *= float64(n)
+		}
+	}
+	return size
+}
+
+// expandGrid returns the cartesian product of the grid, using the base rules
+// for any dimension left empty
+func expandGrid(base models.SignalRules, grid models.ParameterGrid) []models.SignalRules {
+	orBase := func(values []float64, fallback float64) []float64 {
+		if len(values) == 0 {
+			return []float64{fallback}
+		}
+		return values
+	}
+
+	lookbacks := grid.EquilibriumLookback
+	if len(lookbacks) == 0 {
+		lookbacks = []int{base.EquilibriumLookback}
+	}
+
+	var sets []models.SignalRules
+	for _, buyBelow := range orBase(grid.RSIBuyBelow, base.RSIBuyBelow) {
+		for _, sellAbove := range orBase(grid.RSISellAbove, base.RSISellAbove) {
+			for _, discount := range orBase(grid.DiscountBelow, base.DiscountBelow) {
+				for _, premium := range orBase(grid.PremiumAbove, base.PremiumAbove) {
+					for _, lookback := range lookbacks {
+						rules := base
+						rules.RSIBuyBelow = buyBelow
+						rules.RSISellAbove = sellAbove
+						rules.DiscountBelow = discount
+						rules.PremiumAbove = premium
+						rules.EquilibriumLookback = lookback
+						sets = append(sets, rules)
+					}
+				}
+			}
+		}
+	}
+
+	return sets
+}
+
+// walkForwardFolds splits bars into consecutive in-sample/out-of-sample windows
+func walkForwardFolds(bars int, cfg models.WalkForwardConfig) ([]walkForwardFold, error) {
+	if cfg.InSampleBars == 0 {
+		cfg.InSampleBars = 180
+	}
+	if cfg.OutOfSampleBars == 0 {
+		cfg.OutOfSampleBars = 60
+	}
+	if cfg.StepBars == 0 {
+		cfg.StepBars = cfg.OutOfSampleBars
+	}
+	if cfg.InSampleBars < 0 || cfg.OutOfSampleBars < 0 || cfg.StepBars < 0 {
+		return nil, fmt.Errorf("%w: walk-forward window sizes must be positive", ErrInvalidBacktestConfig)
+	}
+
+	var folds []walkForwardFold
+	for start := 0; start+cfg.InSampleBars+cfg.OutOfSampleBars <= bars; start += cfg.StepBars {
+		fold := walkForwardFold{
+			inSampleFrom:    start,
+			inSampleTo:      start + cfg.InSampleBars,
+			outOfSampleFrom: start + cfg.InSampleBars,
+			outOfSampleTo:   start + cfg.InSampleBars + cfg.OutOfSampleBars,
+		}
+		if cfg.Anchored {
+			fold.inSampleFrom = 0
+		}
+		folds = append(folds, fold)
+	}
+
+	if len(folds) == 0 {
+		return nil, fmt.Errorf("%w: %d bars is not enough for a %d/%d walk-forward split",
+			ErrInvalidBacktestConfig, bars, cfg.InSampleBars, cfg.OutOfSampleBars)
+	}
+	return folds, nil
+}
+
+// evaluateParameterSet backtests one parameter set on every fold and
+// aggregates the in-sample and out-of-sample results
+func evaluateParameterSet(prices []models.CandlestickData, rules models.SignalRules, folds []walkForwardFold) models.ParameterSetResult {
+	series := newSignalSeries(prices, rules)
+
+	var inSampleSharpe, inSampleReturn float64
+	var inSampleBars, outOfSampleBars int
+	var outOfSample backtestRun
+	outOfSampleEquity := 1.0
+	worstDrawdown := 0.0
+
+	for _, fold := range folds {
+		inSample := simulate(prices, series, rules, fold.inSampleFrom, fold.inSampleTo)
+		inSampleSharpe += sharpeRatio(inSample.barReturns())
+		inSampleReturn += inSample.totalReturn()
+		inSampleBars += len(inSample.equity)
+
+		run := simulate(prices, series, rules, fold.outOfSampleFrom, fold.outOfSampleTo)
+		worstDrawdown = math.Max(worstDrawdown, maxDrawdown(run.equity))
+		outOfSampleBars += len(run.equity)
+
+		// Chain the out-of-sample windows into one continuous equity curve
+		for _, equity := range run.equity {
+			outOfSample.equity = append(outOfSample.equity, outOfSampleEquity*equity)
+		}
+		outOfSampleEquity *= run.equity[len(run.equity)-1]
+		outOfSample.trades = append(outOfSample.trades, run.trades...)
+	}
+
+	folded := float64(len(folds))
+	winRate, _ := tradeStats(outOfSample.trades)
+
+	result := models.ParameterSetResult{
+		Rules:               rules,
+		InSampleSharpe:      inSampleSharpe / folded,
+		InSampleReturn:      inSampleReturn / folded,
+		OutOfSampleSharpe:   sharpeRatio(outOfSample.barReturns()),
+		OutOfSampleReturn:   outOfSample.totalReturn(),
+		OutOfSampleDrawdown: worstDrawdown,
+		OutOfSampleWinRate:  winRate,
+		OutOfSampleTrades:   len(outOfSample.trades),
+	}
+
+	inSamplePerBar := inSampleReturn / float64(inSampleBars)
+	if inSamplePerBar > 0 {
+		result.WalkForwardEfficiency = (result.OutOfSampleReturn / float64(outOfSampleBars)) / inSamplePerBar
+	}
+
+	return result
+}
+
+// rankParameterSets orders results best-first and assigns their rank
+func rankParameterSets(results []models.ParameterSetResult, rankBy string) {
+	better := func(a, b models.ParameterSetResult) bool {
+		switch rankBy {
+		case "return":
+			return a.OutOfSampleReturn > b.OutOfSampleReturn
+		case "drawdown":
+			return a.OutOfSampleDrawdown < b.OutOfSampleDrawdown
+		default:
+			return a.OutOfSampleSharpe > b.OutOfSampleSharpe
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if better(results[i], results[j]) {
+			return true
+		}
+		if better(results[j], results[i]) {
+			return false
+		}
+		return results[i].OutOfSampleReturn > results[j].OutOfSampleReturn
+	})
+
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"equilibrio-backend/internal/models"
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed or was issued for a
+// different filter or sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// stockCursor is the decoded form of an opaque page cursor. It records the
+// sort values of the last row served, so the next page starts strictly after
+// that row whichever data version it is read from; the version it was
+// issued at is kept for diagnostics.
+type stockCursor struct {
+	Version     int64    `json:"v"`
+	Fingerprint string   `json:"f"`
+	After       []string `json:"a"` // One value per sort column, symbol last
+}
+
+// encodeCursor returns the cursor pointing after the given stock
+func encodeCursor(version int64, fingerprint string, columns []sortColumn, last *models.StockData) string {
+	cursor := stockCursor{Version: version, Fingerprint: fingerprint, After: make([]string, len(columns))}
+	for i, column := range columns {
+		cursor.After[i] = column.field.format(last)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor and rebuilds the stock it points after
+func decodeCursor(raw, fingerprint string, columns []sortColumn) (*models.StockData, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	var cursor stockCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+	if cursor.Fingerprint != fingerprint || len(cursor.After) != len(columns) {
+		return nil, fmt.Errorf("%w: cursor was issued for a different filter or sort", ErrInvalidCursor)
+	}
+
+	var anchor models.StockData
+	for i, column := range columns {
+		if err := column.field.parse(&anchor, cursor.After[i]); err != nil {
+			return nil, fmt.Errorf("%w: bad value for %s", ErrInvalidCursor, column.field.name)
+		}
+	}
+	return &anchor, nil
+}
+
+// requestFingerprint hashes the filter and sort of a request, leaving out
+// the page position, so a cursor can only continue the listing it came from
+func (s *MarketDataService) requestFingerprint(req models.StockListRequest) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s_%s_%s_%s_%s_%s_%s_%s_%s_%s_%s_%s_%s_%s_%s_%s",
+		req.Universe,
+		req.SortField,
+		req.SortOrder,
+		req.Sort,
+		req.SearchTerm,
+		formatKeyFloat(req.RSIMin),
+		formatKeyFloat(req.RSIMax),
+		formatKeyFloat(req.PriceMin),
+		formatKeyFloat(req.PriceMax),
+		strings.Join(req.Sectors, ","),
+		strings.Join(req.Signals, ","),
+		strings.Join(req.Trend, ","),
+		strings.Join(req.VolumeProfile, ","),
+		strings.Join(req.EquilibriumZone, ","),
+		req.Query,
+		rangesKey(req.Ranges),
+	)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// pageAfter returns the index of the first sorted stock that comes after the anchor
+func pageAfter(sorted []models.StockData, columns []sortColumn, anchor *models.StockData) int {
+	lo, hi := 0, len(sorted)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if compareStocks(columns, &sorted[mid], anchor) <= 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
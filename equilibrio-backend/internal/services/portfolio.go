@@ -0,0 +1,373 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"equilibrio-backend/internal/models"
+)
+
+const (
+	// portfolioWarmupBars leaves room for the 52-week equilibrium range and SMA200
+	portfolioWarmupBars = 252
+	portfolioRSIPeriod  = 14
+)
+
+// symbolHistory holds a symbol's price history and the indicator series needed
+// to rebuild its StockData row as of any bar
+type symbolHistory struct {
+	ticker      models.UniverseSymbol
+	prices      []models.CandlestickData
+	rsi         []float64
+	sma50       []float64
+	sma200      []float64
+	equilibrium []float64
+	atr         []float64
+}
+
+// portfolioRun holds the raw output of a portfolio simulation
+type portfolioRun struct {
+	equity     []float64
+	cash       []float64
+	rebalances []models.RebalanceRecord
+	holdings   int // Sum of position counts across rebalances
+}
+
+// targetFunc returns the target dollar value per symbol at a rebalance bar
+type targetFunc func(bar int, investable float64) map[string]float64
+
+// Portfolio simulates a screener strategy that holds the top N names of a
+// filter, rebalanced on a fixed schedule, against an equal-weight benchmark
+// of the whole universe.
+func (s *BacktestService) Portfolio(req models.PortfolioRequest) (*models.PortfolioResult, error) {
+	req, err := withPortfolioDefaults(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateRanges(req.Filter.Ranges); err != nil {
+		return nil, err
+	}
+	query, err := parseFilterQuery(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	rankKey, err := newSortKey(req.RankField, req.RankOrder)
+	if err != nil {
+		return nil, err
+	}
+	rankKeys := []models.SortKey{rankKey}
+
+	histories, err := s.loadUniverseHistory(req.Universe, req.Days, req.ATRPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := len(histories[0].prices)
+	if bars <= portfolioWarmupBars {
+		return nil, fmt.Errorf("%w: %d bars is not enough history, need more than %d",
+			ErrInvalidBacktestConfig, bars, portfolioWarmupBars)
+	}
+
+	return s.simulatePortfolio(histories, req, query, rankKeys), nil
+}
+
+// simulatePortfolio runs the strategy and its benchmark over loaded histories
+func (s *BacktestService) simulatePortfolio(histories []*symbolHistory, req models.PortfolioRequest, query *ScreenerQuery, rankKeys []models.SortKey) *models.PortfolioResult {
+	strategy := s.runPortfolio(histories, req, func(bar int, investable float64) map[string]float64 {
+		return s.strategyTargets(histories, req, query, rankKeys, bar, investable)
+	})
+	benchmark := s.runPortfolio(histories, req, func(bar int, investable float64) map[string]float64 {
+		targets := make(map[string]float64, len(histories))
+		for _, h := range histories {
+			targets[h.ticker.Symbol] = investable / float64(len(histories))
+		}
+		return targets
+	})
+
+	curve := make([]models.PortfolioPoint, len(strategy.equity))
+	for i := range strategy.equity {
+		curve[i] = models.PortfolioPoint{
+			Time:      histories[0].prices[portfolioWarmupBars+i].Time,
+			Equity:    strategy.equity[i],
+			Cash:      strategy.cash[i],
+			Benchmark: benchmark.equity[i],
+		}
+	}
+
+	return &models.PortfolioResult{
+		Metrics:     strategy.metrics(req.InitialCapital),
+		Benchmark:   benchmark.metrics(req.InitialCapital),
+		EquityCurve: curve,
+		Rebalances:  strategy.rebalances,
+	}
+}
+
+// withPortfolioDefaults fills in unset request fields and validates the rest
+func withPortfolioDefaults(req models.PortfolioRequest) (models.PortfolioRequest, error) {
+	if req.TopN <= 0 {
+		req.TopN = 5
+	}
+	if req.RankField == "" {
+		req.RankField = "rsi"
+	}
+	if req.RankOrder == "" {
+		req.RankOrder = "asc"
+	}
+	if req.RebalanceEvery <= 0 {
+		req.RebalanceEvery = 20
+	}
+	if req.Sizing == "" {
+		req.Sizing = "equal"
+	}
+	if req.RiskPerPosition <= 0 {
+		req.RiskPerPosition = 1
+	}
+	if req.ATRPeriod <= 0 {
+		req.ATRPeriod = 14
+	}
+	if req.FixedFraction <= 0 {
+		req.FixedFraction = 10
+	}
+	if req.InitialCapital <= 0 {
+		req.InitialCapital = 100000
+	}
+
+	// Match the defaults GetStocks applies to an empty filter
+	DefaultFilterBounds(&req.Filter.RSIMax, &req.Filter.PriceMax)
+
+	switch req.Sizing {
+	case "equal", "volatility", "fixed":
+	default:
+		return req, fmt.Errorf("%w: sizing must be one of equal, volatility, fixed", ErrInvalidBacktestConfig)
+	}
+	if req.FixedFraction > 100 {
+		return req, fmt.Errorf("%w: fixedFraction must be at most 100", ErrInvalidBacktestConfig)
+	}
+	if req.CommissionPct < 0 {
+		return req, fmt.Errorf("%w: commissionPct must not be negative", ErrInvalidBacktestConfig)
+	}
+	// Historical bars carry no volume, so a volume profile filter would
+	// never match anything
+	if len(req.Filter.VolumeProfile) > 0 {
+		return req, fmt.Errorf("%w: volumeProfile filters are not supported in portfolio backtests", ErrInvalidBacktestConfig)
+	}
+
+	return req, nil
+}
+
+// loadUniverseHistory fetches price history for every symbol in the universe
+// and precomputes the indicator series
+func (s *BacktestService) loadUniverseHistory(universeName string, days, atrPeriod int) ([]*symbolHistory, error) {
+	universe, err := s.marketDataService.universes.GetUniverse(universeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var histories []*symbolHistory
+	for _, ticker := range universe.Symbols {
+		prices, err := s.loadHistory(ticker.Symbol, days)
+		if err != nil {
+			return nil, err
+		}
+		histories = append(histories, newSymbolHistory(ticker, prices, atrPeriod))
+	}
+	return histories, nil
+}
+
+// newSymbolHistory precomputes the indicator series of a price history
+func newSymbolHistory(ticker models.UniverseSymbol, prices []models.CandlestickData, atrPeriod int) *symbolHistory {
+	closes := make([]float64, len(prices))
+	for i, p := range prices {
+		closes[i] = p.Close
+	}
+
+	return &symbolHistory{
+		ticker:      ticker,
+		prices:      prices,
+		rsi:         rsiSeries(closes, portfolioRSIPeriod),
+		sma50:       smaSeries(closes, 50),
+		sma200:      smaSeries(closes, 200),
+		equilibrium: equilibriumSeries(prices, portfolioWarmupBars),
+		atr:         atrSeries(prices, atrPeriod),
+	}
+}
+
+// stockAt rebuilds the StockData row of a symbol as it looked at a given bar
+func (s *BacktestService) stockAt(h *symbolHistory, bar int) models.StockData {
+	price := h.prices[bar].Close
+	prevClose := h.prices[bar-1].Close
+	equilibrium := h.equilibrium[bar]
+	priceToEquilibrium := s.indicatorService.CalculatePriceToEquilibrium(price, equilibrium)
+
+	return models.StockData{
+		Symbol:             h.ticker.Symbol,
+		Name:               h.ticker.Name,
+		Sector:             h.ticker.Sector,
+		Industry:           h.ticker.Industry,
+		Price:              price,
+		Change:             price - prevClose,
+		ChangePercent:      (price - prevClose) / prevClose * 100,
+		RSI:                h.rsi[bar],
+		SMA50:              h.sma50[bar],
+		SMA200:             h.sma200[bar],
+		EquilibriumLevel:   equilibrium,
+		PriceToEquilibrium: priceToEquilibrium,
+		Trend:              s.indicatorService.DetermineTrend(price, h.sma50[bar], h.sma200[bar]),
+		Signal:             s.indicatorService.DetermineSignal(h.rsi[bar], priceToEquilibrium),
+	}
+}
+
+// strategyTargets screens the universe at a bar, keeps the top N names and
+// sizes them according to the request
+func (s *BacktestService) strategyTargets(histories []*symbolHistory, req models.PortfolioRequest, query *ScreenerQuery, rankKeys []models.SortKey, bar int, investable float64) map[string]float64 {
+	rows := make([]models.StockData, 0, len(histories))
+	bySymbol := make(map[string]*symbolHistory, len(histories))
+	for _, h := range histories {
+		rows = append(rows, s.stockAt(h, bar))
+		bySymbol[h.ticker.Symbol] = h
+	}
+	applyCrossSectionalScores(rows)
+
+	selected := s.marketDataService.applySorting(
+		s.marketDataService.applyFilters(rows, req.Filter, query), rankKeys)
+	if len(selected) > req.TopN {
+		selected = selected[:req.TopN]
+	}
+
+	targets := make(map[string]float64, len(selected))
+	switch req.Sizing {
+	case "equal":
+		for _, stock := range selected {
+			targets[stock.Symbol] = investable / float64(len(selected))
+		}
+	case "fixed":
+		for _, stock := range selected {
+			targets[stock.Symbol] = investable * req.FixedFraction / 100
+		}
+	case "volatility":
+		// Size each position so that a one-ATR move costs RiskPerPosition % of equity
+		for _, stock := range selected {
+			atr := bySymbol[stock.Symbol].atr[bar]
+			if math.IsNaN(atr) || atr <= 0 {
+				continue
+			}
+			targets[stock.Symbol] = investable * req.RiskPerPosition / 100 * stock.Price / atr
+		}
+	}
+
+	// Never lever up: scale down when the targets exceed available equity
+	total := 0.0
+	for _, value := range targets {
+		total += value
+	}
+	if total > investable {
+		for symbol := range targets {
+			targets[symbol] *= investable / total
+		}
+	}
+
+	return targets
+}
+
+// runPortfolio marks the portfolio to market every bar after warm-up and
+// trades to the target values on each rebalance bar
+func (s *BacktestService) runPortfolio(histories []*symbolHistory, req models.PortfolioRequest, targets targetFunc) portfolioRun {
+	commission := req.CommissionPct / 100
+	cash := req.InitialCapital
+	shares := make(map[string]float64)
+	closes := make(map[string]float64, len(histories))
+
+	var run portfolioRun
+	bars := len(histories[0].prices)
+
+	for bar := portfolioWarmupBars; bar < bars; bar++ {
+		for _, h := range histories {
+			closes[h.ticker.Symbol] = h.prices[bar].Close
+		}
+		value := func() float64 {
+			total := cash
+			for symbol, n := range shares {
+				total += n * closes[symbol]
+			}
+			return total
+		}
+
+		if (bar-portfolioWarmupBars)%req.RebalanceEvery == 0 {
+			equity := value()
+			// Hold back enough cash to pay commissions on a full rotation
+			wanted := targets(bar, equity*(1-2*commission))
+
+			symbols := make([]string, 0, len(shares)+len(wanted))
+			for symbol := range shares {
+				symbols = append(symbols, symbol)
+			}
+			for symbol := range wanted {
+				if _, held := shares[symbol]; !held {
+					symbols = append(symbols, symbol)
+				}
+			}
+			sort.Strings(symbols)
+
+			traded := 0.0
+			for _, symbol := range symbols {
+				diff := wanted[symbol] - shares[symbol]*closes[symbol]
+				if diff == 0 {
+					continue
+				}
+				traded += math.Abs(diff)
+				cash -= diff + math.Abs(diff)*commission
+				shares[symbol] += diff / closes[symbol]
+				if wanted[symbol] == 0 {
+					delete(shares, symbol)
+				}
+			}
+
+			weights := make(map[string]float64, len(wanted))
+			for symbol, target := range wanted {
+				weights[symbol] = target / equity * 100
+			}
+			run.rebalances = append(run.rebalances, models.RebalanceRecord{
+				Time:     histories[0].prices[bar].Time,
+				Holdings: weights,
+				Turnover: traded / equity * 100,
+			})
+			run.holdings += len(wanted)
+		}
+
+		run.equity = append(run.equity, value())
+		run.cash = append(run.cash, cash)
+	}
+
+	return run
+}
+
+// metrics summarizes the portfolio run
+func (r portfolioRun) metrics(initialCapital float64) models.PortfolioMetrics {
+	normalized := backtestRun{equity: make([]float64, len(r.equity))}
+	for i, equity := range r.equity {
+		normalized.equity[i] = equity / initialCapital
+	}
+
+	metrics := models.PortfolioMetrics{
+		TotalReturn: normalized.totalReturn(),
+		MaxDrawdown: maxDrawdown(normalized.equity),
+		SharpeRatio: sharpeRatio(normalized.barReturns()),
+		Rebalances:  len(r.rebalances),
+	}
+
+	if n := len(normalized.equity); n > 0 && normalized.equity[n-1] > 0 {
+		metrics.AnnualizedReturn = (math.Pow(normalized.equity[n-1], barsPerYear/float64(n)) - 1) * 100
+	}
+
+	for _, rebalance := range r.rebalances {
+		metrics.TotalTurnover += rebalance.Turnover
+	}
+	if len(r.rebalances) > 0 {
+		metrics.AverageTurnover = metrics.TotalTurnover / float64(len(r.rebalances))
+		metrics.AverageHoldings = float64(r.holdings) / float64(len(r.rebalances))
+	}
+
+	return metrics
+}
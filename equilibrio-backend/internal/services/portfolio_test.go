@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"equilibrio-backend/internal/models"
+)
+
+// newTestPortfolio returns a backtest service and deterministic histories of
+// phase-shifted waves, so that the symbols rank differently at each bar
+func newTestPortfolio(t *testing.T, symbols, days int) (*BacktestService, []*symbolHistory) {
+	_, md := newTestMarketDataService(t)
+	backtest := NewBacktestService(md, NewIndicatorService())
+
+	histories := make([]*symbolHistory, symbols)
+	for i := range histories {
+		shift := i * 10
+		ticker := models.UniverseSymbol{Symbol: fmt.Sprintf("S%d", i), Name: fmt.Sprintf("Symbol %d", i)}
+		histories[i] = newSymbolHistory(ticker, generateWaveChartData(days + shift)[shift:], 14)
+	}
+	return backtest, histories
+}
+
+// testPortfolioRequest returns a request with defaults applied, and its
+// parsed query and rank keys
+func testPortfolioRequest(t *testing.T, req models.PortfolioRequest) (models.PortfolioRequest, *ScreenerQuery, []models.SortKey) {
+	req, err := withPortfolioDefaults(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	query, err := parseFilterQuery(req.Filter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rankKey, err := newSortKey(req.RankField, req.RankOrder)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return req, query, []models.SortKey{rankKey}
+}
+
+func sumTargets(targets map[string]float64) float64 {
+	total := 0.0
+	for _, value := range targets {
+		total += value
+	}
+	return total
+}
+
+// TestStrategyTargetsSizing tests equal, fixed and volatility sizing
+func TestStrategyTargetsSizing(t *testing.T) {
+	backtest, histories := newTestPortfolio(t, 4, 400)
+	bar := portfolioWarmupBars + 10
+
+	req, query, rankKeys := testPortfolioRequest(t, models.PortfolioRequest{TopN: 2, Sizing: "equal"})
+	targets := backtest.strategyTargets(histories, req, query, rankKeys, bar, 1000)
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %v", targets)
+	}
+	for symbol, value := range targets {
+		if value != 500 {
+			t.Errorf("Expected an equal target of 500 for %s, got %v", symbol, value)
+		}
+	}
+
+	// The lowest RSI names are picked
+	lowest := histories[0]
+	for _, h := range histories {
+		if h.rsi[bar] < lowest.rsi[bar] {
+			lowest = h
+		}
+	}
+	if _, ok := targets[lowest.ticker.Symbol]; !ok {
+		t.Errorf("Expected %s with the lowest RSI to be held, got %v", lowest.ticker.Symbol, targets)
+	}
+
+	req, query, rankKeys = testPortfolioRequest(t, models.PortfolioRequest{TopN: 2, Sizing: "fixed", FixedFraction: 10})
+	targets = backtest.strategyTargets(histories, req, query, rankKeys, bar, 1000)
+	for symbol, value := range targets {
+		if math.Abs(value-100) > 1e-9 {
+			t.Errorf("Expected a fixed target of 100 for %s, got %v", symbol, value)
+		}
+	}
+
+	req, query, rankKeys = testPortfolioRequest(t, models.PortfolioRequest{TopN: 2, Sizing: "volatility", RiskPerPosition: 0.5})
+	targets = backtest.strategyTargets(histories, req, query, rankKeys, bar, 1000)
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %v", targets)
+	}
+	for _, h := range histories {
+		value, ok := targets[h.ticker.Symbol]
+		if !ok {
+			continue
+		}
+		want := 1000 * 0.5 / 100 * h.prices[bar].Close / h.atr[bar]
+		if math.Abs(value-want) > 1e-9 {
+			t.Errorf("Expected a volatility target of %v for %s, got %v", want, h.ticker.Symbol, value)
+		}
+	}
+	if total := sumTargets(targets); total > 1000 {
+		t.Errorf("Expected targets within equity, got %v", total)
+	}
+}
+
+// TestStrategyTargetsNeverLever tests that oversized targets are scaled down
+// to the available equity, keeping their proportions
+func TestStrategyTargetsNeverLever(t *testing.T) {
+	backtest, histories := newTestPortfolio(t, 4, 400)
+	bar := portfolioWarmupBars + 10
+
+	req, query, rankKeys := testPortfolioRequest(t, models.PortfolioRequest{TopN: 3, Sizing: "fixed", FixedFraction: 50})
+	targets := backtest.strategyTargets(histories, req, query, rankKeys, bar, 1000)
+	if total := sumTargets(targets); math.Abs(total-1000) > 1e-9 {
+		t.Errorf("Expected fixed targets scaled to 1000, got %v", total)
+	}
+	for symbol, value := range targets {
+		if math.Abs(value-1000.0/3) > 1e-9 {
+			t.Errorf("Expected %s scaled to a third, got %v", symbol, value)
+		}
+	}
+
+	req, query, rankKeys = testPortfolioRequest(t, models.PortfolioRequest{TopN: 3, Sizing: "volatility", RiskPerPosition: 50})
+	targets = backtest.strategyTargets(histories, req, query, rankKeys, bar, 1000)
+	if total := sumTargets(targets); math.Abs(total-1000) > 1e-9 {
+		t.Errorf("Expected volatility targets scaled to 1000, got %v", total)
+	}
+}
+
+// TestRunPortfolioCashAndTurnover tests the cash, equity and turnover of trades
+func TestRunPortfolioCashAndTurnover(t *testing.T) {
+	backtest, histories := newTestPortfolio(t, 2, 400)
+	req, _, _ := testPortfolioRequest(t, models.PortfolioRequest{RebalanceEvery: 50, CommissionPct: 0.1})
+
+	run := backtest.runPortfolio(histories, req, func(bar int, investable float64) map[string]float64 {
+		return map[string]float64{"S0": investable / 2}
+	})
+
+	if len(run.equity) != 400-portfolioWarmupBars || len(run.cash) != len(run.equity) {
+		t.Fatalf("Expected %d bars, got %d", 400-portfolioWarmupBars, len(run.equity))
+	}
+	if len(run.rebalances) != 3 {
+		t.Fatalf("Expected 3 rebalances, got %d", len(run.rebalances))
+	}
+
+	// Half of the equity, less the commission reserve, is bought on the first bar
+	bought := 100000 * (1 - 2*0.001) / 2
+	if want := 100000 - bought*1.001; math.Abs(run.cash[0]-want) > 1e-6 {
+		t.Errorf("Expected cash of %v, got %v", want, run.cash[0])
+	}
+	if want := 100000 - bought*0.001; math.Abs(run.equity[0]-want) > 1e-6 {
+		t.Errorf("Expected equity of %v, got %v", want, run.equity[0])
+	}
+	first := run.rebalances[0]
+	if math.Abs(first.Turnover-bought/1000) > 1e-9 || math.Abs(first.Holdings["S0"]-bought/1000) > 1e-9 {
+		t.Errorf("Expected turnover and weight of %v, got %+v", bought/1000, first)
+	}
+
+	// Later rebalances only trade the drift back to the target
+	for _, rebalance := range run.rebalances[1:] {
+		if rebalance.Turnover <= 0 || rebalance.Turnover >= first.Turnover {
+			t.Errorf("Expected a small drift turnover, got %v", rebalance.Turnover)
+		}
+	}
+	for i := range run.cash {
+		if run.cash[i] < 0 {
+			t.Fatalf("Cash went negative at bar %d: %v", i, run.cash[i])
+		}
+	}
+}
+
+// TestSimulatePortfolioBenchmark tests that holding the whole universe with
+// equal weights matches the benchmark
+func TestSimulatePortfolioBenchmark(t *testing.T) {
+	backtest, histories := newTestPortfolio(t, 3, 400)
+	req, query, rankKeys := testPortfolioRequest(t, models.PortfolioRequest{TopN: 3, CommissionPct: 0.05})
+
+	// Sums over maps add up in varying order, so compare with a tolerance
+	near := func(a, b float64) bool { return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b)) }
+
+	result := backtest.simulatePortfolio(histories, req, query, rankKeys)
+	m, b := result.Metrics, result.Benchmark
+	if !near(m.TotalReturn, b.TotalReturn) || !near(m.MaxDrawdown, b.MaxDrawdown) || !near(m.SharpeRatio, b.SharpeRatio) ||
+		!near(m.TotalTurnover, b.TotalTurnover) || m.Rebalances != b.Rebalances || m.AverageHoldings != b.AverageHoldings {
+		t.Errorf("Expected the metrics to match the benchmark:\n%+v\n%+v", m, b)
+	}
+	if len(result.EquityCurve) != 400-portfolioWarmupBars {
+		t.Fatalf("Expected %d points, got %d", 400-portfolioWarmupBars, len(result.EquityCurve))
+	}
+	for _, point := range result.EquityCurve {
+		if !near(point.Equity, point.Benchmark) {
+			t.Fatalf("Expected equity to match the benchmark at %s", point.Time)
+		}
+	}
+	if result.EquityCurve[0].Time != histories[0].prices[portfolioWarmupBars].Time {
+		t.Errorf("Expected the curve to start after warm-up, got %s", result.EquityCurve[0].Time)
+	}
+
+	// Holding fewer names diverges from the benchmark
+	req, query, rankKeys = testPortfolioRequest(t, models.PortfolioRequest{TopN: 1, CommissionPct: 0.05})
+	result = backtest.simulatePortfolio(histories, req, query, rankKeys)
+	if result.Metrics.AverageHoldings != 1 || result.Benchmark.AverageHoldings != 3 {
+		t.Errorf("Expected 1 and 3 average holdings, got %v and %v",
+			result.Metrics.AverageHoldings, result.Benchmark.AverageHoldings)
+	}
+}
+
+// TestPortfolioMetrics tests the summary statistics of a run
+func TestPortfolioMetrics(t *testing.T) {
+	run := portfolioRun{
+		equity: []float64{1000, 1100, 990, 1210},
+		rebalances: []models.RebalanceRecord{
+			{Turnover: 100},
+			{Turnover: 20},
+		},
+		holdings: 5,
+	}
+
+	metrics := run.metrics(1000)
+	if math.Abs(metrics.TotalReturn-21) > 1e-9 {
+		t.Errorf("Expected a total return of 21%%, got %v", metrics.TotalReturn)
+	}
+	if math.Abs(metrics.MaxDrawdown-10) > 1e-9 {
+		t.Errorf("Expected a max drawdown of 10%%, got %v", metrics.MaxDrawdown)
+	}
+	if metrics.Rebalances != 2 || metrics.TotalTurnover != 120 || metrics.AverageTurnover != 60 || metrics.AverageHoldings != 2.5 {
+		t.Errorf("Unexpected rebalance metrics: %+v", metrics)
+	}
+	if want := (math.Pow(1.21, barsPerYear/4) - 1) * 100; math.Abs(metrics.AnnualizedReturn-want) > 1e-6 {
+		t.Errorf("Expected an annualized return of %v, got %v", want, metrics.AnnualizedReturn)
+	}
+}
+
+// TestPortfolioRejectsVolumeProfile tests that a volume profile filter is an
+// error rather than being dropped
+func TestPortfolioRejectsVolumeProfile(t *testing.T) {
+	_, md := newTestMarketDataService(t)
+	backtest := NewBacktestService(md, NewIndicatorService())
+
+	_, err := backtest.Portfolio(models.PortfolioRequest{
+		Filter: models.StockFilter{VolumeProfile: []string{"high"}},
+	})
+	if !errors.Is(err, ErrInvalidBacktestConfig) {
+		t.Errorf("Expected ErrInvalidBacktestConfig, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+// ErrPresetNotFound is returned when no preset has the requested ID
+var ErrPresetNotFound = errors.New("preset not found")
+
+type PresetService struct {
+	store             *jsonStore[models.FilterPreset]
+	marketDataService *MarketDataService
+}
+
+func NewPresetService(cfg *config.Config, marketDataService *MarketDataService) (*PresetService, error) {
+	store, err := newJSONStore[models.FilterPreset](cfg.PresetsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load presets: %w", err)
+	}
+
+	return &PresetService{
+		store:             store,
+		marketDataService: marketDataService,
+	}, nil
+}
+
+// ListPresets returns all presets ordered by name
+func (s *PresetService) ListPresets() []models.FilterPreset {
+	presets := s.store.list()
+	sort.Slice(presets, func(i, j int) bool {
+		if presets[i].Name != presets[j].Name {
+			return presets[i].Name < presets[j].Name
+		}
+		return presets[i].ID < presets[j].ID
+	})
+	return presets
+}
+
+// GetPreset returns a preset by ID
+func (s *PresetService) GetPreset(id string) (*models.FilterPreset, error) {
+	preset, ok := s.store.get(id)
+	if !ok {
+		return nil, ErrPresetNotFound
+	}
+	return &preset, nil
+}
+
+// CreatePreset validates and stores a new preset
+func (s *PresetService) CreatePreset(req models.PresetRequest) (*models.FilterPreset, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preset := models.FilterPreset{
+		ID:          newID(),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Filter:      req.Filter,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.store.put(preset.ID, preset); err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// UpdatePreset validates and replaces an existing preset
+func (s *PresetService) UpdatePreset(id string, req models.PresetRequest) (*models.FilterPreset, error) {
+	preset, ok := s.store.get(id)
+	if !ok {
+		return nil, ErrPresetNotFound
+	}
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+
+	preset.Name = strings.TrimSpace(req.Name)
+	preset.Description = req.Description
+	preset.Filter = req.Filter
+	preset.UpdatedAt = time.Now()
+
+	if err := s.store.put(id, preset); err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// DeletePreset removes a preset
+func (s *PresetService) DeletePreset(id string) error {
+	deleted, err := s.store.delete(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPresetNotFound
+	}
+	return nil
+}
+
+// validate checks the preset name and its embedded filter
+func (s *PresetService) validate(req models.PresetRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidFilter)
+	}
+	return s.marketDataService.ValidateFilter(req.Filter)
+}
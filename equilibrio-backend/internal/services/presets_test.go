@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+// newTestMarketDataService returns a market data service whose stores live in a temporary directory
+func newTestMarketDataService(t *testing.T) (*config.Config, *MarketDataService) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		PresetsFile:   filepath.Join(dir, "presets.json"),
+		UniversesFile: filepath.Join(dir, "universes.json"),
+		SnapshotTTL:   time.Minute,
+	}
+	universes, err := NewUniverseService(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cache, err := NewCacheService(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return cfg, NewMarketDataService(cfg, cache, universes)
+}
+
+// TestPresetLifecycle tests that presets survive a reload of the store
+func TestPresetLifecycle(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+
+	presets, err := NewPresetService(cfg, marketData)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	created, err := presets.CreatePreset(models.PresetRequest{
+		Name:   "Oversold tech",
+		Filter: models.StockFilter{Sectors: []string{"Technology"}, RSIMax: 30, Query: "sma50 > sma200"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	updated, err := presets.UpdatePreset(created.ID, models.PresetRequest{
+		Name:   "Oversold tech",
+		Filter: models.StockFilter{Sectors: []string{"Technology"}, RSIMax: 35},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updated.Filter.RSIMax != 35 || updated.CreatedAt != created.CreatedAt {
+		t.Errorf("Expected updated filter with the original creation time, got %+v", updated)
+	}
+
+	reloaded, err := NewPresetService(cfg, marketData)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, err := reloaded.GetPreset(created.ID)
+	if err != nil {
+		t.Fatalf("Preset should persist across reloads: %v", err)
+	}
+	if got.Filter.RSIMax != 35 {
+		t.Errorf("Expected persisted rsiMax 35, got %f", got.Filter.RSIMax)
+	}
+
+	if err := reloaded.DeletePreset(created.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := reloaded.GetPreset(created.ID); !errors.Is(err, ErrPresetNotFound) {
+		t.Errorf("Expected ErrPresetNotFound after delete, got %v", err)
+	}
+}
+
+// TestPresetValidation tests that invalid embedded filters are rejected
+func TestPresetValidation(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	presets, _ := NewPresetService(cfg, marketData)
+
+	invalid := []models.StockFilter{
+		{RSIMin: 70, RSIMax: 30},
+		{PriceMin: -1},
+		{Signals: []string{"strong-buy"}},
+		{Sectors: []string{"Crypto"}},
+		{Query: "rsi <"},
+	}
+
+	for _, filter := range invalid {
+		_, err := presets.CreatePreset(models.PresetRequest{Name: "bad", Filter: filter})
+		if !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for %+v, got %v", filter, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"equilibrio-backend/internal/models"
+)
+
+const (
+	rangeMinPrefix = "min_"
+	rangeMaxPrefix = "max_"
+)
+
+// ParseRangeParams collects min_<field> and max_<field> query parameters
+// into range filters. Fields are validated against the numeric StockData fields.
+func ParseRangeParams(params map[string][]string) ([]models.RangeFilter, error) {
+	byField := make(map[string]*models.RangeFilter)
+
+	for key, values := range params {
+		var prefix string
+		switch {
+		case strings.HasPrefix(key, rangeMinPrefix):
+			prefix = rangeMinPrefix
+		case strings.HasPrefix(key, rangeMaxPrefix):
+			prefix = rangeMaxPrefix
+		default:
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		field, err := numericStockField(strings.TrimPrefix(key, prefix))
+		if err != nil {
+			return nil, err
+		}
+		bound, err := strconv.ParseFloat(values[len(values)-1], 64)
+		if err != nil || math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return nil, fmt.Errorf("%w: %s must be a number, got %q", ErrInvalidFilter, key, values[len(values)-1])
+		}
+
+		r, ok := byField[field.name]
+		if !ok {
+			r = &models.RangeFilter{Field: field.name}
+			byField[field.name] = r
+		}
+		if prefix == rangeMinPrefix {
+			r.Min = &bound
+		} else {
+			r.Max = &bound
+		}
+	}
+
+	ranges := make([]models.RangeFilter, 0, len(byField))
+	for _, r := range byField {
+		ranges = append(ranges, *r)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Field < ranges[j].Field })
+
+	if err := validateRanges(ranges); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// validateRanges checks that every range names a numeric field and that its bounds are ordered
+func validateRanges(ranges []models.RangeFilter) error {
+	for _, r := range ranges {
+		if _, err := numericStockField(r.Field); err != nil {
+			return err
+		}
+		if r.Min == nil && r.Max == nil {
+			return fmt.Errorf("%w: range on %s needs a min or a max", ErrInvalidFilter, r.Field)
+		}
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			return fmt.Errorf("%w: min %s must not exceed max %s", ErrInvalidFilter, r.Field, r.Field)
+		}
+	}
+	return nil
+}
+
+// numericStockField looks up a StockData field and checks that it is numeric
+func numericStockField(name string) (stockField, error) {
+	field, ok := lookupStockField(name)
+	if !ok {
+		return stockField{}, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, name)
+	}
+	if field.kind != fieldNumber {
+		return stockField{}, fmt.Errorf("%w: field %q is not numeric", ErrInvalidFilter, field.name)
+	}
+	return field, nil
+}
+
+// compiledRange is a range filter with its field resolved
+type compiledRange struct {
+	field    stockField
+	min, max float64
+}
+
+// filterRanges returns every numeric range of a filter, including the
+// legacy RSI and price ranges, with fields resolved. Ranges on unknown or
+// non-numeric fields are dropped; callers validate them beforehand.
+func filterRanges(filter models.StockFilter) []compiledRange {
+	rsi, _ := lookupStockField("rsi")
+	price, _ := lookupStockField("price")
+
+	ranges := []compiledRange{
+		{field: rsi, min: filter.RSIMin, max: filter.RSIMax},
+		{field: price, min: filter.PriceMin, max: filter.PriceMax},
+	}
+
+	for _, r := range filter.Ranges {
+		field, err := numericStockField(r.Field)
+		if err != nil {
+			continue
+		}
+		compiled := compiledRange{field: field, min: math.Inf(-1), max: math.Inf(1)}
+		if r.Min != nil {
+			compiled.min = *r.Min
+		}
+		if r.Max != nil {
+			compiled.max = *r.Max
+		}
+		ranges = append(ranges, compiled)
+	}
+
+	return ranges
+}
+
+// contains reports whether the stock's field value lies within the range
+func (r compiledRange) contains(stock *models.StockData) bool {
+	v := r.field.number(stock)
+	return v >= r.min && v <= r.max
+}
+
+// rangesKey renders range filters for use in cache keys
+func rangesKey(ranges []models.RangeFilter) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		bound := func(b *float64) string {
+			if b == nil {
+				return ""
+			}
+			return formatKeyFloat(*b)
+		}
+		parts[i] = fmt.Sprintf("%s:%s:%s", r.Field, bound(r.Min), bound(r.Max))
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatKeyFloat renders a number for a cache key or fingerprint in its
+// shortest exact form, so that distinct values never share a key
+func formatKeyFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
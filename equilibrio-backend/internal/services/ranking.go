@@ -0,0 +1,174 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"equilibrio-backend/internal/models"
+)
+
+// Opportunity score weights. Discount depth and RSI use cross-sectional
+// percentiles so the score means the same in calm and volatile markets.
+const (
+	opportunityDiscountWeight = 0.45
+	opportunityRSIWeight      = 0.35
+	opportunityTrendWeight    = 0.20
+)
+
+// crossSectionalMetric ties a StockData field to the fields holding its
+// percentile rank and sector z-score
+type crossSectionalMetric struct {
+	value      func(*models.StockData) float64
+	percentile func(*models.StockData) *float64
+	sectorZ    func(*models.StockData) *float64
+}
+
+var crossSectionalMetrics = []crossSectionalMetric{
+	{
+		value:      func(s *models.StockData) float64 { return s.RSI },
+		percentile: func(s *models.StockData) *float64 { return &s.RSIPercentile },
+		sectorZ:    func(s *models.StockData) *float64 { return &s.RSISectorZ },
+	},
+	{
+		value:      func(s *models.StockData) float64 { return s.PriceToEquilibrium },
+		percentile: func(s *models.StockData) *float64 { return &s.PriceToEquilibriumPercentile },
+		sectorZ:    func(s *models.StockData) *float64 { return &s.PriceToEquilibriumSectorZ },
+	},
+	{
+		value:      func(s *models.StockData) float64 { return s.ChangePercent },
+		percentile: func(s *models.StockData) *float64 { return &s.ChangePercentPercentile },
+		sectorZ:    func(s *models.StockData) *float64 { return &s.ChangePercentSectorZ },
+	},
+	{
+		value:      func(s *models.StockData) float64 { return float64(s.Volume) },
+		percentile: func(s *models.StockData) *float64 { return &s.VolumePercentile },
+		sectorZ:    func(s *models.StockData) *float64 { return &s.VolumeSectorZ },
+	},
+	{
+		value:      func(s *models.StockData) float64 { return s.MarketCap },
+		percentile: func(s *models.StockData) *float64 { return &s.MarketCapPercentile },
+		sectorZ:    func(s *models.StockData) *float64 { return &s.MarketCapSectorZ },
+	},
+}
+
+// applyCrossSectionalScores fills in the percentile ranks, sector z-scores
+// and opportunity score of every stock relative to the others in the slice
+func applyCrossSectionalScores(stocks []models.StockData) {
+	bySector := make(map[string][]int)
+	for i := range stocks {
+		bySector[stocks[i].Sector] = append(bySector[stocks[i].Sector], i)
+	}
+
+	all := make([]int, len(stocks))
+	for i := range all {
+		all[i] = i
+	}
+
+	for _, metric := range crossSectionalMetrics {
+		values := make([]float64, len(stocks))
+		for i := range stocks {
+			values[i] = metric.value(&stocks[i])
+		}
+
+		for i, pct := range percentileRanks(values) {
+			*metric.percentile(&stocks[i]) = pct
+		}
+		for _, members := range bySector {
+			for i, z := range zScores(values, members) {
+				*metric.sectorZ(&stocks[members[i]]) = z
+			}
+		}
+	}
+
+	for i := range stocks {
+		stocks[i].OpportunityScore = opportunityScore(&stocks[i])
+	}
+}
+
+// percentileRanks returns the mid-rank percentile (0-100) of each value.
+// Ties share a percentile; NaN values get NaN.
+func percentileRanks(values []float64) []float64 {
+	order := make([]int, 0, len(values))
+	ranks := make([]float64, len(values))
+	for i, v := range values {
+		if math.IsNaN(v) {
+			ranks[i] = math.NaN()
+			continue
+		}
+		order = append(order, i)
+	}
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	n := len(order)
+	for start := 0; start < n; {
+		end := start
+		for end < n && values[order[end]] == values[order[start]] {
+			end++
+		}
+		pct := 50.0
+		if n > 1 {
+			pct = (float64(start+end-1) / 2) / float64(n-1) * 100
+		}
+		for _, i := range order[start:end] {
+			ranks[i] = pct
+		}
+		start = end
+	}
+	return ranks
+}
+
+// zScores returns the z-score of each member value relative to the members'
+// mean and population standard deviation. Groups without spread score 0.
+func zScores(values []float64, members []int) []float64 {
+	scores := make([]float64, len(members))
+	sum, count := 0.0, 0
+	for _, i := range members {
+		if !math.IsNaN(values[i]) {
+			sum += values[i]
+			count++
+		}
+	}
+	if count == 0 {
+		return scores
+	}
+	avg := sum / float64(count)
+
+	variance := 0.0
+	for _, i := range members {
+		if !math.IsNaN(values[i]) {
+			variance += (values[i] - avg) * (values[i] - avg)
+		}
+	}
+	stdDev := math.Sqrt(variance / float64(count))
+
+	for j, i := range members {
+		switch {
+		case math.IsNaN(values[i]):
+			scores[j] = math.NaN()
+		case stdDev > 0:
+			scores[j] = (values[i] - avg) / stdDev
+		}
+	}
+	return scores
+}
+
+// opportunityScore combines discount depth, RSI and trend into a 0-100 score.
+// A stock deeper below equilibrium and more oversold than its peers scores
+// higher, and a bullish trend adds to it while a bearish one does not.
+func opportunityScore(stock *models.StockData) float64 {
+	trend := 50.0
+	switch stock.Trend {
+	case "bullish":
+		trend = 100
+	case "bearish":
+		trend = 0
+	}
+
+	discount := 100 - stock.PriceToEquilibriumPercentile
+	oversold := 100 - stock.RSIPercentile
+	if math.IsNaN(discount) || math.IsNaN(oversold) {
+		return math.NaN()
+	}
+
+	return opportunityDiscountWeight*discount + opportunityRSIWeight*oversold + opportunityTrendWeight*trend
+}
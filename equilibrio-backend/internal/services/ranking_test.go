@@ -0,0 +1,49 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"equilibrio-backend/internal/models"
+)
+
+// TestPercentileRanks tests mid-rank percentiles with ties and NaN
+func TestPercentileRanks(t *testing.T) {
+	ranks := percentileRanks([]float64{30, 10, 20, 20, math.NaN()})
+	want := []float64{100, 0, 50, 50}
+	for i, w := range want {
+		if math.Abs(ranks[i]-w) > 1e-9 {
+			t.Errorf("Rank %d: expected %f, got %f", i, w, ranks[i])
+		}
+	}
+	if !math.IsNaN(ranks[4]) {
+		t.Errorf("Expected NaN to rank as NaN, got %f", ranks[4])
+	}
+}
+
+// TestCrossSectionalScores tests sector z-scores and the opportunity score ordering
+func TestCrossSectionalScores(t *testing.T) {
+	stocks := []models.StockData{
+		{Symbol: "DEEP", Sector: "Energy", RSI: 20, PriceToEquilibrium: -20, Trend: "bullish"},
+		{Symbol: "MID", Sector: "Energy", RSI: 50, PriceToEquilibrium: 0, Trend: "neutral"},
+		{Symbol: "RICH", Sector: "Technology", RSI: 80, PriceToEquilibrium: 20, Trend: "bearish"},
+		{Symbol: "SOLO", Sector: "Utilities", RSI: 40, PriceToEquilibrium: -5, Trend: "neutral"},
+	}
+	applyCrossSectionalScores(stocks)
+
+	if stocks[0].RSISectorZ != -1 || stocks[1].RSISectorZ != 1 {
+		t.Errorf("Expected sector z-scores of -1 and 1, got %f and %f", stocks[0].RSISectorZ, stocks[1].RSISectorZ)
+	}
+	if stocks[3].RSISectorZ != 0 {
+		t.Errorf("Expected a single-stock sector to score 0, got %f", stocks[3].RSISectorZ)
+	}
+	if stocks[0].OpportunityScore != 100 || stocks[2].OpportunityScore != 0 {
+		t.Errorf("Expected scores of 100 and 0, got %f and %f", stocks[0].OpportunityScore, stocks[2].OpportunityScore)
+	}
+
+	s := &MarketDataService{}
+	keys, _ := ParseSortKeys("opportunityScore:desc")
+	if sorted := s.applySorting(stocks, keys); sorted[0].Symbol != "DEEP" {
+		t.Errorf("Expected opportunityScore to be sortable, got %s first", sorted[0].Symbol)
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis pub/sub channels replicas exchange updates on
+const (
+	replicationSnapshots = cacheNamespace + ":replication:snapshots"
+	replicationRefreshes = cacheNamespace + ":replication:refreshes"
+)
+
+// replicatedSnapshot is a snapshot as published to the other replicas
+type replicatedSnapshot struct {
+	Origin            string             `json:"origin"`
+	Universe          string             `json:"universe"`
+	Version           int64              `json:"version"`
+	AsOf              time.Time          `json:"asOf"`
+	UniverseUpdatedAt time.Time          `json:"universeUpdatedAt"`
+	Stocks            []models.StockData `json:"stocks"`
+}
+
+// replicatedRefresh is a refresh as published to the other replicas
+type replicatedRefresh struct {
+	Origin  string               `json:"origin"`
+	Refresh models.RefreshResult `json:"refresh"`
+}
+
+// replicationQueue is how many messages may wait to be published before new
+// ones are dropped
+const replicationQueue = 64
+
+// outgoingMessage is a message waiting to be published
+type outgoingMessage struct {
+	channel string
+	payload []byte
+}
+
+// Replicator keeps the replicas of a deployment in step over Redis pub/sub.
+// It publishes every snapshot this replica builds and every refresh it runs;
+// from the other replicas it installs newer snapshots, which reach the local
+// WebSocket and SSE clients like local ones, and applies their evictions.
+// Messages are published in order from a queue so a slow or unreachable
+// Redis never holds up requests.
+type Replicator struct {
+	client     *redis.Client
+	pubsub     *redis.PubSub
+	marketData *MarketDataService
+	origin     string // Identifies this replica's messages
+	done       sync.WaitGroup
+
+	mu       sync.RWMutex // Guards publishing against Close
+	outgoing chan outgoingMessage
+	closed   bool
+}
+
+// NewReplicator subscribes to the replication channels of the Redis server
+// at the configured URL
+func NewReplicator(cfg *config.Config, marketData *MarketDataService) (*Replicator, error) {
+	opt, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+
+	opt.DialTimeout = redisOpTimeout
+	opt.WriteTimeout = redisOpTimeout
+
+	r := &Replicator{
+		client:     redis.NewClient(opt),
+		marketData: marketData,
+		origin:     newID(),
+		outgoing:   make(chan outgoingMessage, replicationQueue),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r.pubsub = r.client.Subscribe(ctx, replicationSnapshots, replicationRefreshes)
+	// Wait for the subscription so nothing published after this returns is missed
+	if _, err := r.pubsub.Receive(ctx); err != nil {
+		r.pubsub.Close()
+		r.client.Close()
+		return nil, fmt.Errorf("failed to subscribe to replication channels: %w", err)
+	}
+
+	marketData.OnSnapshot(r.publishSnapshot)
+	marketData.OnRefresh(r.publishRefresh)
+
+	r.done.Add(2)
+	go r.receive()
+	go r.send()
+	return r, nil
+}
+
+// Close unsubscribes, publishes the queued messages and closes the Redis connection
+func (r *Replicator) Close() error {
+	r.pubsub.Close()
+	r.mu.Lock()
+	r.closed = true
+	close(r.outgoing)
+	r.mu.Unlock()
+	r.done.Wait()
+	return r.client.Close()
+}
+
+func (r *Replicator) receive() {
+	defer r.done.Done()
+	for msg := range r.pubsub.Channel() {
+		var err error
+		switch msg.Channel {
+		case replicationSnapshots:
+			err = r.applySnapshot([]byte(msg.Payload))
+		case replicationRefreshes:
+			err = r.applyRefresh([]byte(msg.Payload))
+		}
+		if err != nil {
+			log.Printf("Replication: failed to apply a message on %s: %v", msg.Channel, err)
+		}
+	}
+}
+
+func (r *Replicator) send() {
+	defer r.done.Done()
+	for msg := range r.outgoing {
+		ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+		if err := r.client.Publish(ctx, msg.channel, msg.payload).Err(); err != nil {
+			log.Printf("Replication: failed to publish on %s: %v", msg.channel, err)
+		}
+		cancel()
+	}
+}
+
+// publish queues a message, dropping it when the queue is full
+func (r *Replicator) publish(channel string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Replication: failed to encode a message for %s: %v", channel, err)
+		return
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.outgoing <- outgoingMessage{channel: channel, payload: data}:
+	default:
+		log.Printf("Replication: queue full, dropped a message for %s", channel)
+	}
+}
+
+// publishSnapshot publishes a snapshot built by this replica
+func (r *Replicator) publishSnapshot(snapshot *Snapshot) {
+	if snapshot.replicated {
+		return
+	}
+	r.publish(replicationSnapshots, replicatedSnapshot{
+		Origin:            r.origin,
+		Universe:          snapshot.Universe,
+		Version:           snapshot.Version,
+		AsOf:              snapshot.AsOf,
+		UniverseUpdatedAt: snapshot.universeUpdatedAt,
+		Stocks:            snapshot.stocks,
+	})
+}
+
+// publishRefresh publishes a refresh run on this replica
+func (r *Replicator) publishRefresh(refresh models.RefreshResult) {
+	r.publish(replicationRefreshes, replicatedRefresh{Origin: r.origin, Refresh: refresh})
+}
+
+// applySnapshot installs another replica's snapshot if it is newer
+func (r *Replicator) applySnapshot(payload []byte) error {
+	var msg replicatedSnapshot
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return err
+	}
+	if msg.Origin == r.origin {
+		return nil
+	}
+
+	snapshot := newSnapshot(msg.Universe, msg.UniverseUpdatedAt, msg.Stocks, msg.AsOf)
+	snapshot.Version = msg.Version
+	snapshot.replicated = true
+	r.marketData.installSnapshot(snapshot)
+	return nil
+}
+
+// applyRefresh evicts what another replica's refresh evicted there
+func (r *Replicator) applyRefresh(payload []byte) error {
+	var msg replicatedRefresh
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return err
+	}
+	if msg.Origin == r.origin {
+		return nil
+	}
+
+	_, err := r.marketData.refresh(models.RefreshRequest{
+		Scope:    msg.Refresh.Scope,
+		Symbols:  msg.Refresh.Symbols,
+		Universe: msg.Refresh.Universe,
+	}, false)
+	return err
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestReplica creates a market data service replicating through a Redis server
+func newTestReplica(t *testing.T, server *miniredis.Miniredis) (*MarketDataService, *Replicator) {
+	t.Helper()
+	cfg, marketData := newTestMarketDataService(t)
+	cfg.RedisURL = "redis://" + server.Addr()
+	replicator, err := NewReplicator(cfg, marketData)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { replicator.Close() })
+	return marketData, replicator
+}
+
+// eventually polls a condition until it holds or a second has passed
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+// TestReplicationSharesSnapshots tests that snapshots and refreshes reach other replicas
+func TestReplicationSharesSnapshots(t *testing.T) {
+	server := miniredis.RunT(t)
+	first, _ := newTestReplica(t, server)
+	second, _ := newTestReplica(t, server)
+
+	cfg, _ := newTestMarketDataService(t)
+	hub := NewStreamHub(cfg, second)
+	defer hub.Close()
+
+	built, err := first.currentSnapshot("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	installed := func() *Snapshot {
+		second.snapshotsMu.Lock()
+		defer second.snapshotsMu.Unlock()
+		return second.snapshots[built.Universe]
+	}
+	eventually(t, "the snapshot to replicate", func() bool {
+		return installed() != nil && installed().Version == built.Version
+	})
+	if !installed().replicated || installed().Len() != built.Len() {
+		t.Errorf("Expected the replicated snapshot to match the original")
+	}
+
+	client := hub.Connect()
+	client.Handle(models.StreamRequest{Action: "subscribe", Symbols: []string{"AAPL"}})
+	receive(t, client)
+
+	if _, err := first.Refresh(models.RefreshRequest{Scope: "symbols", Symbols: []string{"AAPL"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg := receive(t, client); msg.Type != "tick" || msg.Symbol != "AAPL" {
+		t.Errorf("Expected a tick on the other replica's stream, got %+v", msg)
+	}
+
+	if _, err := first.Refresh(models.RefreshRequest{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	eventually(t, "the refresh to replicate", func() bool { return installed() == nil })
+}
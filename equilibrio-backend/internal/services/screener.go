@@ -0,0 +1,787 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"equilibrio-backend/internal/models"
+)
+
+// QueryError reports a problem with a screener expression and where it occurred
+type QueryError struct {
+	Pos     int // 1-based character position in the expression
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Message)
+}
+
+// Limits of screener expressions, which come from untrusted requests and are
+// parsed recursively
+const (
+	maxQueryLength = 4096 // Characters
+	maxQueryDepth  = 64   // Nested parentheses, "not"s and "-"s
+)
+
+// ScreenerQuery is a parsed and type-checked screener expression such as
+//
+//	rsi < 35 and priceToEquilibrium < -10 and sector in ("Technology", "Energy")
+//
+// Identifiers name StockData fields by their JSON name, case-insensitively.
+type ScreenerQuery struct {
+	source string
+	root   exprNode
+	fields []stockField // Fields the expression reads, in order of appearance
+}
+
+// ParseScreenerQuery parses a screener expression into a typed AST
+func ParseScreenerQuery(source string) (*ScreenerQuery, error) {
+	if utf8.RuneCountInString(source) > maxQueryLength {
+		return nil, &QueryError{Pos: maxQueryLength + 1, Message: fmt.Sprintf("expression is longer than %d characters", maxQueryLength)}
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &QueryError{Pos: tok.pos, Message: fmt.Sprintf("unexpected %s", tok)}
+	}
+	if root.valueType() != typeBool {
+		return nil, &QueryError{Pos: root.position(), Message: "expression must be a condition, got a " + root.valueType().String()}
+	}
+
+	return &ScreenerQuery{source: source, root: root, fields: p.fields}, nil
+}
+
+// String returns the expression the query was parsed from
+func (q *ScreenerQuery) String() string {
+	return q.source
+}
+
+// Match reports whether a stock satisfies the query
+func (q *ScreenerQuery) Match(stock *models.StockData) bool {
+	return q.root.eval(stock).boolean
+}
+
+// Values returns the values of the fields the query reads, by field name.
+// Numbers that are not finite are nil.
+func (q *ScreenerQuery) Values(stock *models.StockData) map[string]interface{} {
+	values := make(map[string]interface{}, len(q.fields))
+	for _, field := range q.fields {
+		v := field.value(stock)
+		if n, ok := v.(float64); ok && (math.IsNaN(n) || math.IsInf(n, 0)) {
+			v = nil
+		}
+		values[field.name] = v
+	}
+	return values
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokKeyword
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+var keywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "contains": true,
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			text := string(runes[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, &QueryError{Pos: pos, Message: fmt.Sprintf("invalid number %q", text)}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: text, num: num, pos: pos})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			kind := tokIdent
+			if keywords[strings.ToLower(text)] {
+				kind = tokKeyword
+				text = strings.ToLower(text)
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: pos})
+
+		case r == '"' || r == '\'':
+			quote := r
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, &QueryError{Pos: pos, Message: "unterminated string"}
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					sb.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == quote {
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: pos})
+
+		default:
+			text := string(r)
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				if pair == "<=" || pair == ">=" || pair == "!=" || pair == "==" {
+					text = pair
+				}
+			}
+			if !strings.Contains("<>=!+-*/(),", text[:1]) || text == "!" {
+				return nil, &QueryError{Pos: pos, Message: fmt.Sprintf("unexpected character %q", r)}
+			}
+			if text == "==" {
+				tokens = append(tokens, token{kind: tokSymbol, text: "=", pos: pos})
+			} else {
+				tokens = append(tokens, token{kind: tokSymbol, text: text, pos: pos})
+			}
+			i += len([]rune(text))
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int // Nesting of the rule being parsed
+	fields []stockField
+}
+
+// enter descends into a nested rule at tok, failing past the maximum depth
+// so that a deeply nested query cannot overflow the stack. Each successful
+// enter is paired with a leave.
+func (p *parser) enter(tok token) error {
+	if p.depth >= maxQueryDepth {
+		return &QueryError{Pos: tok.pos, Message: fmt.Sprintf("expression is nested more than %d levels deep", maxQueryDepth)}
+	}
+	p.depth++
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(kind tokenKind, text string) bool {
+	tok := p.peek()
+	if tok.kind == kind && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if !p.accept(kind, text) {
+		tok := p.peek()
+		return &QueryError{Pos: tok.pos, Message: fmt.Sprintf("expected %q, got %s", text, tok)}
+	}
+	return nil
+}
+
+// parseOr parses: and ("or" and)*
+func (p *parser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept(tokKeyword, "or") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogical(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseAnd parses: not ("and" not)*
+func (p *parser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept(tokKeyword, "and") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newLogical(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseNot parses: "not" not | comparison
+func (p *parser) parseNot() (exprNode, error) {
+	tok := p.peek()
+	if p.accept(tokKeyword, "not") {
+		if err := p.enter(tok); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseNot()
+		p.leave()
+		if err != nil {
+			return nil, err
+		}
+		if operand.valueType() != typeBool {
+			return nil, &QueryError{Pos: operand.position(), Message: "\"not\" needs a condition, got a " + operand.valueType().String()}
+		}
+		return &notExpr{pos: tok.pos, operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses: sum [op sum | ["not"] "in" "(" list ")" | ["not"] "contains" sum]
+func (p *parser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok.kind == tokSymbol {
+		switch tok.text {
+		case "<", "<=", ">", ">=", "=", "!=":
+			p.next()
+			right, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return newComparison(tok, left, right)
+		}
+	}
+
+	negate := false
+	if tok.kind == tokKeyword && tok.text == "not" {
+		following := p.tokens[p.pos+1]
+		if following.kind == tokKeyword && (following.text == "in" || following.text == "contains") {
+			p.next()
+			negate = true
+			tok = p.peek()
+		}
+	}
+
+	if p.accept(tokKeyword, "in") {
+		return p.parseInList(tok, left, negate)
+	}
+	if p.accept(tokKeyword, "contains") {
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if left.valueType() != typeString || right.valueType() != typeString {
+			return nil, &QueryError{Pos: tok.pos, Message: "\"contains\" needs strings on both sides"}
+		}
+		var node exprNode = &containsExpr{pos: tok.pos, left: left, right: right}
+		if negate {
+			node = &notExpr{pos: tok.pos, operand: node}
+		}
+		return node, nil
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseInList(tok token, left exprNode, negate bool) (exprNode, error) {
+	if err := p.expect(tokSymbol, "("); err != nil {
+		return nil, err
+	}
+
+	node := &inExpr{pos: tok.pos, operand: left, negate: negate}
+	for {
+		item, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if item.valueType() != left.valueType() {
+			return nil, &QueryError{Pos: item.position(), Message: fmt.Sprintf(
+				"list item is a %s but %s is a %s", item.valueType(), describe(left), left.valueType())}
+		}
+		node.items = append(node.items, item)
+		if !p.accept(tokSymbol, ",") {
+			break
+		}
+	}
+
+	if err := p.expect(tokSymbol, ")"); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parseSum parses: term (("+" | "-") term)*
+func (p *parser) parseSum() (exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept(tokSymbol, "+") && !p.accept(tokSymbol, "-") {
+			return left, nil
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newArithmetic(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseTerm parses: unary (("*" | "/") unary)*
+func (p *parser) parseTerm() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept(tokSymbol, "*") && !p.accept(tokSymbol, "/") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = newArithmetic(tok, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseUnary parses: "-" unary | primary
+func (p *parser) parseUnary() (exprNode, error) {
+	tok := p.peek()
+	if p.accept(tokSymbol, "-") {
+		if err := p.enter(tok); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		p.leave()
+		if err != nil {
+			return nil, err
+		}
+		if operand.valueType() != typeNumber {
+			return nil, &QueryError{Pos: tok.pos, Message: "\"-\" needs a number, got a " + operand.valueType().String()}
+		}
+		return &negateExpr{pos: tok.pos, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses: number | string | field | "(" or ")"
+func (p *parser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokNumber:
+		return &numberLit{pos: tok.pos, value: tok.num}, nil
+	case tokString:
+		return &stringLit{pos: tok.pos, value: tok.text}, nil
+	case tokIdent:
+		field, ok := lookupStockField(tok.text)
+		if !ok {
+			return nil, &QueryError{Pos: tok.pos, Message: fmt.Sprintf("unknown field %q", tok.text)}
+		}
+		if field.kind == fieldTime {
+			return nil, &QueryError{Pos: tok.pos, Message: fmt.Sprintf("field %q cannot be used in queries", field.name)}
+		}
+		p.addField(field)
+		return &fieldRef{pos: tok.pos, field: field}, nil
+	case tokSymbol:
+		if tok.text == "(" {
+			if err := p.enter(tok); err != nil {
+				return nil, err
+			}
+			inner, err := p.parseOr()
+			p.leave()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokSymbol, ")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		}
+	}
+
+	return nil, &QueryError{Pos: tok.pos, Message: fmt.Sprintf("unexpected %s", tok)}
+}
+
+// addField records a field the expression reads
+func (p *parser) addField(field stockField) {
+	for _, seen := range p.fields {
+		if seen.name == field.name {
+			return
+		}
+	}
+	p.fields = append(p.fields, field)
+}
+
+func newLogical(tok token, left, right exprNode) (exprNode, error) {
+	for _, operand := range []exprNode{left, right} {
+		if operand.valueType() != typeBool {
+			return nil, &QueryError{Pos: operand.position(), Message: fmt.Sprintf(
+				"%q needs conditions on both sides, got a %s", tok.text, operand.valueType())}
+		}
+	}
+	return &logicalExpr{pos: tok.pos, and: tok.text == "and", left: left, right: right}, nil
+}
+
+func newComparison(tok token, left, right exprNode) (exprNode, error) {
+	if left.valueType() != right.valueType() {
+		return nil, &QueryError{Pos: tok.pos, Message: fmt.Sprintf(
+			"cannot compare %s (%s) with %s (%s)", describe(left), left.valueType(), describe(right), right.valueType())}
+	}
+	if left.valueType() == typeBool {
+		return nil, &QueryError{Pos: tok.pos, Message: fmt.Sprintf("%q cannot compare conditions", tok.text)}
+	}
+	if left.valueType() == typeString && tok.text != "=" && tok.text != "!=" {
+		return nil, &QueryError{Pos: tok.pos, Message: fmt.Sprintf("%q needs numbers; strings support =, !=, in and contains", tok.text)}
+	}
+	return &compareExpr{pos: tok.pos, op: tok.text, left: left, right: right}, nil
+}
+
+func newArithmetic(tok token, left, right exprNode) (exprNode, error) {
+	for _, operand := range []exprNode{left, right} {
+		if operand.valueType() != typeNumber {
+			return nil, &QueryError{Pos: operand.position(), Message: fmt.Sprintf(
+				"%q needs numbers, got %s (%s)", tok.text, describe(operand), operand.valueType())}
+		}
+	}
+	return &arithmeticExpr{pos: tok.pos, op: tok.text, left: left, right: right}, nil
+}
+
+// describe names a node for error messages
+func describe(node exprNode) string {
+	switch n := node.(type) {
+	case *fieldRef:
+		return n.field.name
+	case *numberLit:
+		return strconv.FormatFloat(n.value, 'g', -1, 64)
+	case *stringLit:
+		return strconv.Quote(n.value)
+	default:
+		return "expression"
+	}
+}
+
+// AST
+
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeString
+	typeBool
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeNumber:
+		return "number"
+	case typeString:
+		return "string"
+	default:
+		return "condition"
+	}
+}
+
+// value is the result of evaluating a node; only the field matching the
+// node's static type is meaningful
+type value struct {
+	number  float64
+	text    string
+	boolean bool
+}
+
+type exprNode interface {
+	position() int
+	valueType() valueType
+	eval(stock *models.StockData) value
+}
+
+type numberLit struct {
+	pos   int
+	value float64
+}
+
+func (n *numberLit) position() int                { return n.pos }
+func (n *numberLit) valueType() valueType         { return typeNumber }
+func (n *numberLit) eval(*models.StockData) value { return value{number: n.value} }
+
+type stringLit struct {
+	pos   int
+	value string
+}
+
+func (n *stringLit) position() int                { return n.pos }
+func (n *stringLit) valueType() valueType         { return typeString }
+func (n *stringLit) eval(*models.StockData) value { return value{text: n.value} }
+
+type fieldRef struct {
+	pos   int
+	field stockField
+}
+
+func (n *fieldRef) position() int { return n.pos }
+
+func (n *fieldRef) valueType() valueType {
+	if n.field.kind == fieldString {
+		return typeString
+	}
+	return typeNumber
+}
+
+func (n *fieldRef) eval(stock *models.StockData) value {
+	if n.field.kind == fieldString {
+		return value{text: n.field.text(stock)}
+	}
+	return value{number: n.field.number(stock)}
+}
+
+type negateExpr struct {
+	pos     int
+	operand exprNode
+}
+
+func (n *negateExpr) position() int        { return n.pos }
+func (n *negateExpr) valueType() valueType { return typeNumber }
+
+func (n *negateExpr) eval(stock *models.StockData) value {
+	return value{number: -n.operand.eval(stock).number}
+}
+
+type arithmeticExpr struct {
+	pos         int
+	op          string
+	left, right exprNode
+}
+
+func (n *arithmeticExpr) position() int        { return n.pos }
+func (n *arithmeticExpr) valueType() valueType { return typeNumber }
+
+func (n *arithmeticExpr) eval(stock *models.StockData) value {
+	a, b := n.left.eval(stock).number, n.right.eval(stock).number
+	switch n.op {
+	case "+":
+		return value{number: a + b}
+	case "-":
+		return value{number: a - b}
+	case "*":
+		return value{number: a * b}
+	default:
+		if b == 0 {
+			return value{number: math.NaN()} // Every comparison with NaN is false
+		}
+		return value{number: a / b}
+	}
+}
+
+type compareExpr struct {
+	pos         int
+	op          string
+	left, right exprNode
+}
+
+func (n *compareExpr) position() int        { return n.pos }
+func (n *compareExpr) valueType() valueType { return typeBool }
+
+func (n *compareExpr) eval(stock *models.StockData) value {
+	a, b := n.left.eval(stock), n.right.eval(stock)
+
+	if n.left.valueType() == typeString {
+		equal := strings.EqualFold(a.text, b.text)
+		return value{boolean: equal == (n.op == "=")}
+	}
+
+	var result bool
+	switch n.op {
+	case "<":
+		result = a.number < b.number
+	case "<=":
+		result = a.number <= b.number
+	case ">":
+		result = a.number > b.number
+	case ">=":
+		result = a.number >= b.number
+	case "=":
+		result = a.number == b.number
+	case "!=":
+		result = a.number != b.number
+	}
+	return value{boolean: result}
+}
+
+type inExpr struct {
+	pos     int
+	operand exprNode
+	items   []exprNode
+	negate  bool
+}
+
+func (n *inExpr) position() int        { return n.pos }
+func (n *inExpr) valueType() valueType { return typeBool }
+
+func (n *inExpr) eval(stock *models.StockData) value {
+	v := n.operand.eval(stock)
+	found := false
+	for _, item := range n.items {
+		iv := item.eval(stock)
+		if n.operand.valueType() == typeString {
+			found = strings.EqualFold(v.text, iv.text)
+		} else {
+			found = v.number == iv.number
+		}
+		if found {
+			break
+		}
+	}
+	return value{boolean: found != n.negate}
+}
+
+type containsExpr struct {
+	pos         int
+	left, right exprNode
+}
+
+func (n *containsExpr) position() int        { return n.pos }
+func (n *containsExpr) valueType() valueType { return typeBool }
+
+func (n *containsExpr) eval(stock *models.StockData) value {
+	haystack := strings.ToLower(n.left.eval(stock).text)
+	needle := strings.ToLower(n.right.eval(stock).text)
+	return value{boolean: strings.Contains(haystack, needle)}
+}
+
+type notExpr struct {
+	pos     int
+	operand exprNode
+}
+
+func (n *notExpr) position() int        { return n.pos }
+func (n *notExpr) valueType() valueType { return typeBool }
+
+func (n *notExpr) eval(stock *models.StockData) value {
+	return value{boolean: !n.operand.eval(stock).boolean}
+}
+
+type logicalExpr struct {
+	pos         int
+	and         bool
+	left, right exprNode
+}
+
+func (n *logicalExpr) position() int        { return n.pos }
+func (n *logicalExpr) valueType() valueType { return typeBool }
+
+func (n *logicalExpr) eval(stock *models.StockData) value {
+	left := n.left.eval(stock).boolean
+	if n.and && !left {
+		return value{boolean: false}
+	}
+	if !n.and && left {
+		return value{boolean: true}
+	}
+	return value{boolean: n.right.eval(stock).boolean}
+}
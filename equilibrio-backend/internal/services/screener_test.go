@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"equilibrio-backend/internal/models"
+)
+
+// TestScreenerQueryMatch tests evaluating expressions against a stock
+func TestScreenerQueryMatch(t *testing.T) {
+	stock := models.StockData{
+		Symbol:             "XOM",
+		Name:               "Exxon Mobil",
+		Sector:             "Energy",
+		Price:              100,
+		RSI:                32,
+		SMA50:              105,
+		SMA200:             98,
+		PriceToEquilibrium: -12,
+		Volume:             20000000,
+		Trend:              "neutral",
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{`rsi < 35 and priceToEquilibrium < -10 and sma50 > sma200 and sector in ("Technology","Energy")`, true},
+		{`rsi < 30`, false},
+		{`RSI <= 32`, true},
+		{`price > sma50 * 0.9 and price < sma50`, true},
+		{`volume >= 2e7`, true},
+		{`sector = "energy"`, true},
+		{`sector != 'Energy' or trend = "neutral"`, true},
+		{`sector not in ("Energy")`, false},
+		{`name contains "mobil"`, true},
+		{`not (rsi < 35)`, false},
+		{`-priceToEquilibrium > 10`, true},
+		{`price / (rsi - 32) > 0`, false},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseScreenerQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseScreenerQuery(%q) returned error: %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(&stock); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestScreenerQueryErrors tests that invalid expressions report their position
+func TestScreenerQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{`rsi < 35 and foo > 1`, 14},
+		{`rsi < "high"`, 5},
+		{`sector > "Energy"`, 8},
+		{`rsi < 35 and`, 13},
+		{`rsi`, 1},
+		{`rsi < 35 # 1`, 10},
+		{`sector in ("Energy", 5)`, 22},
+		{`name = "unterminated`, 8},
+		{`(rsi < 35`, 10},
+		{strings.Repeat("(", 65) + "rsi < 35" + strings.Repeat(")", 65), 65},
+		{strings.Repeat("not ", 65) + "rsi < 35", 257},
+		{"rsi < " + strings.Repeat("-", 65) + "35", 71},
+		{strings.Repeat("(", 2000000) + "rsi < 35", maxQueryLength + 1},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseScreenerQuery(tt.query)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("ParseScreenerQuery(%q) expected a QueryError, got %v", tt.query, err)
+			continue
+		}
+		if queryErr.Pos != tt.pos {
+			t.Errorf("ParseScreenerQuery(%q) error at position %d, want %d (%s)", tt.query, queryErr.Pos, tt.pos, queryErr.Message)
+		}
+	}
+
+	nested := strings.Repeat("(", maxQueryDepth) + "rsi < 35" + strings.Repeat(")", maxQueryDepth)
+	if _, err := ParseScreenerQuery(nested); err != nil {
+		t.Errorf("Expected %d levels of parentheses to parse, got %v", maxQueryDepth, err)
+	}
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"equilibrio-backend/internal/models"
+)
+
+// ErrInvalidSearch is returned when a search query or limit is unusable
+var ErrInvalidSearch = errors.New("invalid search")
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	maxSearchQueryLen  = 100
+)
+
+// searchField identifies which part of a symbol's metadata a term came from
+type searchField int
+
+const (
+	searchSymbol searchField = iota
+	searchName
+	searchIndustry
+	searchSector
+)
+
+func (f searchField) String() string {
+	switch f {
+	case searchSymbol:
+		return "symbol"
+	case searchName:
+		return "name"
+	case searchIndustry:
+		return "industry"
+	default:
+		return "sector"
+	}
+}
+
+// weight ranks matches on the symbol above the name, and both above the
+// broader sector and industry classifications
+func (f searchField) weight() float64 {
+	switch f {
+	case searchSymbol:
+		return 4
+	case searchName:
+		return 3
+	case searchIndustry:
+		return 1.5
+	default:
+		return 1
+	}
+}
+
+// posting records that a term occurs in a field of a document
+type posting struct {
+	doc   int
+	field searchField
+}
+
+// searchIndex is an in-memory term index over the universe's symbol metadata.
+// Terms are kept sorted so prefix lookups are a binary search; fuzzy lookups
+// scan the terms whose length is within the edit distance.
+type searchIndex struct {
+	version  int64
+	docs     []models.SearchResult
+	terms    []string
+	postings map[string][]posting
+}
+
+// newSearchIndex indexes the symbol, name, sector and industry of each stock
+func newSearchIndex(stocks []models.StockData, version int64) *searchIndex {
+	idx := &searchIndex{
+		version:  version,
+		docs:     make([]models.SearchResult, len(stocks)),
+		postings: make(map[string][]posting),
+	}
+
+	for i, stock := range stocks {
+		idx.docs[i] = models.SearchResult{
+			Symbol:   stock.Symbol,
+			Name:     stock.Name,
+			Sector:   stock.Sector,
+			Industry: stock.Industry,
+		}
+		idx.add(i, searchSymbol, strings.ToLower(stock.Symbol))
+		for field, text := range map[searchField]string{
+			searchName:     stock.Name,
+			searchIndustry: stock.Industry,
+			searchSector:   stock.Sector,
+		} {
+			for _, term := range searchTerms(text) {
+				idx.add(i, field, term)
+			}
+		}
+	}
+
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+
+	return idx
+}
+
+func (idx *searchIndex) add(doc int, field searchField, term string) {
+	for _, p := range idx.postings[term] {
+		if p.doc == doc && p.field == field {
+			return
+		}
+	}
+	idx.postings[term] = append(idx.postings[term], posting{doc: doc, field: field})
+}
+
+// searchTerms lower-cases text and splits it into alphanumeric words
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+}
+
+// termMatch is how well a query token matched a document field
+type termMatch struct {
+	score float64
+	field searchField
+}
+
+// search returns documents matching every query token, best first. Each token
+// matches a term exactly, as a prefix, or within a small edit distance.
+func (idx *searchIndex) search(query string, limit int) []models.SearchResult {
+	tokens := searchTerms(query)
+	if len(tokens) == 0 {
+		return []models.SearchResult{}
+	}
+
+	var total map[int]float64
+	best := make(map[int]termMatch)
+	for _, token := range tokens {
+		matches := idx.matchToken(token)
+		next := make(map[int]float64, len(matches))
+		for doc, m := range matches {
+			if total != nil {
+				if _, ok := total[doc]; !ok {
+					continue
+				}
+			}
+			next[doc] = total[doc] + m.score
+			if m.score > best[doc].score {
+				best[doc] = m
+			}
+		}
+		total = next
+	}
+
+	results := make([]models.SearchResult, 0, len(total))
+	for doc, score := range total {
+		result := idx.docs[doc]
+		result.Score = score
+		result.MatchedField = best[doc].field.String()
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Symbol < results[j].Symbol
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchToken returns the best match per document for a single query token
+func (idx *searchIndex) matchToken(token string) map[int]termMatch {
+	matches := make(map[int]termMatch)
+	record := func(term string, quality float64) {
+		for _, p := range idx.postings[term] {
+			score := quality * p.field.weight()
+			if score > matches[p.doc].score {
+				matches[p.doc] = termMatch{score: score, field: p.field}
+			}
+		}
+	}
+
+	// Exact and prefix matches; shorter completions rank higher
+	start := sort.SearchStrings(idx.terms, token)
+	for i := start; i < len(idx.terms) && strings.HasPrefix(idx.terms[i], token); i++ {
+		term := idx.terms[i]
+		if term == token {
+			record(term, 1)
+		} else {
+			record(term, 0.5+0.3*float64(len(token))/float64(len(term)))
+		}
+	}
+
+	// Typo-tolerant matches against whole terms and term prefixes
+	maxEdits := allowedEdits(token)
+	if maxEdits == 0 {
+		return matches
+	}
+	tokenRunes := []rune(token)
+	for _, term := range idx.terms {
+		if strings.HasPrefix(term, token) {
+			continue
+		}
+		termRunes := []rune(term)
+		dist := maxEdits + 1
+		if abs(len(termRunes)-len(tokenRunes)) <= maxEdits {
+			dist = editDistance(tokenRunes, termRunes, maxEdits)
+		}
+		if len(termRunes) > len(tokenRunes) {
+			if d := editDistance(tokenRunes, termRunes[:len(tokenRunes)], maxEdits); d < dist {
+				dist = d
+			}
+		}
+		if dist <= maxEdits {
+			record(term, 0.4-0.1*float64(dist))
+		}
+	}
+
+	return matches
+}
+
+// allowedEdits scales typo tolerance with token length, in characters, so
+// short tokens such as ticker symbols only match exactly or by prefix
+func allowedEdits(token string) int {
+	switch n := utf8.RuneCountInString(token); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// counted in characters, or limit+1 once it is known to exceed limit
+func editDistance(a, b []rune, limit int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	if prev[len(b)] > limit {
+		return limit + 1
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Search returns ranked symbol suggestions for a free-text query over a
+// universe. The index is rebuilt whenever the universe changes.
+func (s *MarketDataService) Search(universeName, query string, limit int) ([]models.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if len(query) > maxSearchQueryLen {
+		return nil, fmt.Errorf("%w: q must be at most %d characters", ErrInvalidSearch, maxSearchQueryLen)
+	}
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 0 || limit > maxSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSearch, maxSearchLimit)
+	}
+
+	idx, err := s.currentSearchIndex(universeName)
+	if err != nil {
+		return nil, err
+	}
+	return idx.search(query, limit), nil
+}
+
+// currentSearchIndex returns the index for a universe, rebuilding it when the
+// universe's snapshot has been replaced
+func (s *MarketDataService) currentSearchIndex(universeName string) (*searchIndex, error) {
+	snapshot, err := s.currentSnapshot(universeName)
+	if err != nil {
+		return nil, err
+	}
+
+	s.searchMu.Lock()
+	defer s.searchMu.Unlock()
+	idx := s.search[snapshot.Universe]
+	if idx == nil || idx.version != snapshot.Version {
+		idx = newSearchIndex(snapshot.stocks, snapshot.Version)
+		s.search[snapshot.Universe] = idx
+	}
+	return idx, nil
+}
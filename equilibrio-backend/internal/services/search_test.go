@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"equilibrio-backend/internal/models"
+)
+
+func newTestSearchIndex() *searchIndex {
+	return newSearchIndex([]models.StockData{
+		{Symbol: "AAPL", Name: "Apple Inc.", Sector: "Technology", Industry: "Consumer Electronics"},
+		{Symbol: "AMZN", Name: "Amazon.com Inc.", Sector: "Consumer Cyclical", Industry: "Internet Retail"},
+		{Symbol: "MSFT", Name: "Microsoft Corp.", Sector: "Technology", Industry: "Software"},
+		{Symbol: "XOM", Name: "Exxon Mobil", Sector: "Energy", Industry: "Oil & Gas"},
+	}, 1)
+}
+
+// TestSearchRanking tests exact, prefix and typo-tolerant matching
+func TestSearchRanking(t *testing.T) {
+	idx := newTestSearchIndex()
+
+	tests := []struct {
+		query string
+		first string
+	}{
+		{"aapl", "AAPL"},
+		{"am", "AMZN"},
+		{"micro", "MSFT"},
+		{"mircosoft", "MSFT"},
+		{"exon", "XOM"},
+		{"oil", "XOM"},
+		{"apple electronics", "AAPL"},
+	}
+
+	for _, tt := range tests {
+		results := idx.search(tt.query, 10)
+		if len(results) == 0 || results[0].Symbol != tt.first {
+			t.Errorf("search(%q) expected %s first, got %+v", tt.query, tt.first, results)
+		}
+	}
+
+	if results := idx.search("technology", 10); len(results) != 2 {
+		t.Errorf("Expected both technology stocks, got %+v", results)
+	}
+	if results := idx.search("apple energy", 10); len(results) != 0 {
+		t.Errorf("Expected every token to be required, got %+v", results)
+	}
+	if results := idx.search("xyz", 10); len(results) != 0 {
+		t.Errorf("Expected no typo matches for short tokens, got %+v", results)
+	}
+}
+
+// TestEditDistance tests the bounded edit distance
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"micro", "micro", 0},
+		{"mircosoft", "microsoft", 1},
+		{"exon", "exxon", 1},
+		{"tesla", "apple", 3},
+		{"nestle", "nestlé", 1},
+		{"müller", "mulelr", 2},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b), 2); got != min(tt.want, 3) {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// Typo tolerance follows characters, not bytes
+	if got := allowedEdits("société"); got != 1 {
+		t.Errorf("allowedEdits(société) = %d, want 1", got)
+	}
+}
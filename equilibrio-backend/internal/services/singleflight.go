@@ -0,0 +1,78 @@
+package services
+
+import "sync"
+
+// flightCall is an in-flight or completed call of a flightGroup
+type flightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+	dups int // Callers waiting on another caller's call
+}
+
+// flightGroup coalesces concurrent calls with the same key so the work runs
+// once and every caller receives its result
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// Do runs fn once for all concurrent callers with the same key. shared reports
+// whether the result came from another caller's call.
+func (g *flightGroup) Do(key string, fn func() (interface{}, error)) (val interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		call.dups++
+		g.mu.Unlock()
+		<-call.done
+		return call.val, call.err, true
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	g.run(key, call, fn)
+	return call.val, call.err, false
+}
+
+// Go starts fn in the background unless a call with the same key is already
+// in flight, reporting whether it started one
+func (g *flightGroup) Go(key string, fn func() (interface{}, error)) bool {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if _, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return false
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	go g.run(key, call, fn)
+	return true
+}
+
+// waiting returns how many callers are waiting on the in-flight call of a key
+func (g *flightGroup) waiting(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if call, ok := g.calls[key]; ok {
+		return call.dups
+	}
+	return 0
+}
+
+func (g *flightGroup) run(key string, call *flightCall, fn func() (interface{}, error)) {
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.val, call.err = fn()
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+// Snapshot is an immutable, versioned view of the stock data of a whole
+// universe. Every refresh cycle builds a new snapshot and swaps it in, so a
+// reader holding a snapshot sees consistent data for as long as it keeps it.
+// Its stocks are sorted by symbol and must never be modified.
+type Snapshot struct {
+	Universe string
+	Version  int64
+	AsOf     time.Time
+
+	stocks            []models.StockData
+	bySymbol          map[string]int
+	universeUpdatedAt time.Time // Rebuild when the universe definition changes
+	replicated        bool      // Built by another instance
+}
+
+// newSnapshot takes ownership of a universe's stocks, sorting them by symbol
+func newSnapshot(universe string, universeUpdatedAt time.Time, stocks []models.StockData, asOf time.Time) *Snapshot {
+	sort.Slice(stocks, func(i, j int) bool { return stocks[i].Symbol < stocks[j].Symbol })
+
+	snapshot := &Snapshot{
+		Universe:          universe,
+		Version:           time.Now().UnixNano(),
+		AsOf:              asOf,
+		stocks:            stocks,
+		bySymbol:          make(map[string]int, len(stocks)),
+		universeUpdatedAt: universeUpdatedAt,
+	}
+	for i, stock := range stocks {
+		snapshot.bySymbol[stock.Symbol] = i
+	}
+	return snapshot
+}
+
+// Len returns the number of stocks in the snapshot
+func (s *Snapshot) Len() int {
+	return len(s.stocks)
+}
+
+// Stocks returns a copy of the snapshot's stocks
+func (s *Snapshot) Stocks() []models.StockData {
+	stocks := make([]models.StockData, len(s.stocks))
+	copy(stocks, s.stocks)
+	return stocks
+}
+
+// Stock returns the data of one symbol
+func (s *Snapshot) Stock(symbol string) (models.StockData, bool) {
+	i, ok := s.bySymbol[strings.ToUpper(symbol)]
+	if !ok {
+		return models.StockData{}, false
+	}
+	return s.stocks[i], true
+}
+
+// Has reports whether the snapshot holds a symbol
+func (s *Snapshot) Has(symbol string) bool {
+	_, ok := s.bySymbol[strings.ToUpper(symbol)]
+	return ok
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+// fieldKind is the value type of a StockData field as seen by queries
+type fieldKind int
+
+const (
+	fieldNumber fieldKind = iota
+	fieldString
+	fieldTime
+)
+
+func (k fieldKind) String() string {
+	switch k {
+	case fieldNumber:
+		return "number"
+	case fieldString:
+		return "string"
+	default:
+		return "time"
+	}
+}
+
+// stockField describes a StockData field addressable by its JSON name
+type stockField struct {
+	name  string
+	kind  fieldKind
+	index []int
+}
+
+// number returns the field value of a numeric field as float64
+func (f stockField) number(stock *models.StockData) float64 {
+	v := reflect.ValueOf(stock).Elem().FieldByIndex(f.index)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	default:
+		return v.Float()
+	}
+}
+
+// text returns the field value of a string field
+func (f stockField) text(stock *models.StockData) string {
+	return reflect.ValueOf(stock).Elem().FieldByIndex(f.index).String()
+}
+
+// time returns the field value of a time field
+func (f stockField) time(stock *models.StockData) time.Time {
+	return reflect.ValueOf(stock).Elem().FieldByIndex(f.index).Interface().(time.Time)
+}
+
+// value returns the field value as stored in the struct
+func (f stockField) value(stock *models.StockData) interface{} {
+	return reflect.ValueOf(stock).Elem().FieldByIndex(f.index).Interface()
+}
+
+// format renders the field value as a string that parse reads back exactly
+func (f stockField) format(stock *models.StockData) string {
+	v := reflect.ValueOf(stock).Elem().FieldByIndex(f.index)
+	switch f.kind {
+	case fieldString:
+		return v.String()
+	case fieldTime:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	}
+	if v.CanInt() {
+		return strconv.FormatInt(v.Int(), 10)
+	}
+	return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+}
+
+// parse sets the field from a string produced by format
+func (f stockField) parse(stock *models.StockData, raw string) error {
+	v := reflect.ValueOf(stock).Elem().FieldByIndex(f.index)
+	switch {
+	case f.kind == fieldString:
+		v.SetString(raw)
+	case f.kind == fieldTime:
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+	case v.CanInt():
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	default:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
+
+// stockFields indexes StockData fields by lower-cased JSON name
+var stockFields = buildStockFields()
+
+func buildStockFields() map[string]stockField {
+	fields := make(map[string]stockField)
+	t := reflect.TypeOf(models.StockData{})
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		var kind fieldKind
+		switch {
+		case sf.Type == reflect.TypeOf(time.Time{}):
+			kind = fieldTime
+		case sf.Type.Kind() == reflect.String:
+			kind = fieldString
+		case sf.Type.Kind() == reflect.Float64, sf.Type.Kind() == reflect.Int64, sf.Type.Kind() == reflect.Int:
+			kind = fieldNumber
+		default:
+			continue
+		}
+
+		fields[strings.ToLower(name)] = stockField{name: name, kind: kind, index: sf.Index}
+	}
+
+	return fields
+}
+
+// lookupStockField finds a StockData field by JSON name, ignoring case
+func lookupStockField(name string) (stockField, bool) {
+	field, ok := stockFields[strings.ToLower(name)]
+	return field, ok
+}
+
+// ErrInvalidFields is returned when a field projection names an unknown field
+var ErrInvalidFields = errors.New("invalid fields")
+
+// ParseFieldList parses a comma-separated field projection such as
+// "symbol,price,rsi" into canonical JSON names. The symbol is always
+// included so rows stay identifiable.
+func ParseFieldList(spec string) ([]string, error) {
+	names := []string{"symbol"}
+	seen := map[string]bool{"symbol": true}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, ok := lookupStockField(part)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFields, part)
+		}
+		if !seen[field.name] {
+			seen[field.name] = true
+			names = append(names, field.name)
+		}
+	}
+	return names, nil
+}
+
+// ProjectStocks returns each stock as a map holding only the named fields
+func ProjectStocks(stocks []models.StockData, names []string) []map[string]interface{} {
+	fields := make([]stockField, 0, len(names))
+	for _, name := range names {
+		if field, ok := lookupStockField(name); ok {
+			fields = append(fields, field)
+		}
+	}
+
+	rows := make([]map[string]interface{}, len(stocks))
+	for i := range stocks {
+		row := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			row[field.name] = field.value(&stocks[i])
+		}
+		rows[i] = row
+	}
+	return rows
+}
@@ -0,0 +1,492 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+// ErrSlowConsumer is the reason a client is disconnected when it falls a full
+// buffer behind
+var ErrSlowConsumer = errors.New("slow consumer")
+
+const (
+	maxStreamSymbols = 500
+	maxStreamScreens = 20
+)
+
+// Fields whose changes are pushed as ticks and as indicator updates
+var (
+	tickFields      = []string{"price", "change", "changePercent", "volume"}
+	indicatorFields = []string{
+		"rsi", "stochRsi", "sma50", "sma200", "ema20", "macd", "macdSignal", "macdHistogram",
+		"equilibriumLevel", "priceToEquilibrium", "trend", "signal", "volumeProfile",
+	}
+)
+
+// StreamHub fans snapshot changes out to streaming clients. Each client has a
+// bounded buffer; a client that lets it fill up is disconnected rather than
+// slowing down the hub or the other clients.
+type StreamHub struct {
+	marketData *MarketDataService
+	interval   time.Duration
+	buffer     int
+
+	mu      sync.Mutex
+	clients map[*StreamClient]struct{}
+	last    map[string]*Snapshot // Last snapshot published per universe
+
+	slowConsumers atomic.Uint64
+	stop          chan struct{}
+}
+
+// NewStreamHub creates a hub publishing every snapshot the market data
+// service swaps in, and polls the universes clients watch so their snapshots
+// keep being rebuilt while nothing else reads them
+func NewStreamHub(cfg *config.Config, marketData *MarketDataService) *StreamHub {
+	h := &StreamHub{
+		marketData: marketData,
+		interval:   cfg.StreamInterval,
+		buffer:     cfg.StreamBuffer,
+		clients:    make(map[*StreamClient]struct{}),
+		last:       make(map[string]*Snapshot),
+		stop:       make(chan struct{}),
+	}
+	if h.buffer <= 0 {
+		h.buffer = 256
+	}
+	marketData.OnSnapshot(h.publish)
+	if h.interval > 0 {
+		go h.poll()
+	}
+	return h
+}
+
+// Connect registers a new client
+func (h *StreamHub) Connect() *StreamClient {
+	client := &StreamClient{
+		hub:     h,
+		send:    make(chan models.StreamMessage, h.buffer),
+		done:    make(chan struct{}),
+		symbols: make(map[string]string),
+		screens: make(map[string]*streamScreen),
+	}
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+	return client
+}
+
+// ClientCount returns the number of connected clients
+func (h *StreamHub) ClientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// SlowConsumers returns how many clients were disconnected for falling behind
+func (h *StreamHub) SlowConsumers() uint64 {
+	return h.slowConsumers.Load()
+}
+
+// Close stops polling and disconnects every client
+func (h *StreamHub) Close() {
+	close(h.stop)
+
+	h.mu.Lock()
+	clients := make([]*StreamClient, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+func (h *StreamHub) poll() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			for _, universe := range h.watchedUniverses() {
+				if _, err := h.marketData.currentSnapshot(universe); err != nil {
+					log.Printf("Stream: failed to read universe %s: %v", universe, err)
+				}
+			}
+		}
+	}
+}
+
+// watchedUniverses returns the universes any client subscribes to
+func (h *StreamHub) watchedUniverses() []string {
+	h.mu.Lock()
+	clients := make([]*StreamClient, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	universes := make(map[string]bool)
+	for _, client := range clients {
+		client.mu.Lock()
+		for _, universe := range client.symbols {
+			universes[universe] = true
+		}
+		for _, screen := range client.screens {
+			universes[screen.universe] = true
+		}
+		client.mu.Unlock()
+	}
+	return sortedKeys(universes)
+}
+
+// track records a snapshot as the baseline of later updates without publishing it
+func (h *StreamHub) track(snapshot *Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if last := h.last[snapshot.Universe]; last == nil || last.Version < snapshot.Version {
+		h.last[snapshot.Universe] = snapshot
+	}
+}
+
+// publish sends every client the changes between the last published snapshot
+// of a universe and a new one. Snapshots older than the last are ignored.
+func (h *StreamHub) publish(snapshot *Snapshot) {
+	h.mu.Lock()
+	prev := h.last[snapshot.Universe]
+	if prev != nil && prev.Version >= snapshot.Version {
+		h.mu.Unlock()
+		return
+	}
+	h.last[snapshot.Universe] = snapshot
+	clients := make([]*StreamClient, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.update(prev, snapshot)
+	}
+}
+
+// remove unregisters a client
+func (h *StreamHub) remove(client *StreamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, client)
+}
+
+// streamScreen is a client's screener subscription and its current rows
+type streamScreen struct {
+	universe string
+	query    *ScreenerQuery
+	members  map[string]bool
+}
+
+// StreamClient is one streaming connection's subscriptions and outgoing
+// buffer. The transport reads Messages until Done is closed.
+type StreamClient struct {
+	hub  *StreamHub
+	send chan models.StreamMessage
+	done chan struct{}
+
+	closeOnce sync.Once
+	err       error
+
+	mu      sync.Mutex
+	symbols map[string]string // Symbol to the universe it is read from
+	screens map[string]*streamScreen
+}
+
+// Messages returns the client's outgoing messages
+func (c *StreamClient) Messages() <-chan models.StreamMessage {
+	return c.send
+}
+
+// Done is closed when the client is disconnected
+func (c *StreamClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the hub disconnected the client, or nil
+func (c *StreamClient) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close disconnects the client
+func (c *StreamClient) Close() {
+	c.disconnect(nil)
+}
+
+func (c *StreamClient) disconnect(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		c.hub.remove(c)
+		close(c.done)
+	})
+}
+
+// enqueue buffers a message, disconnecting the client when the buffer is full
+func (c *StreamClient) enqueue(msg models.StreamMessage) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+	default:
+		c.hub.slowConsumers.Add(1)
+		c.disconnect(ErrSlowConsumer)
+	}
+}
+
+// Handle applies a subscription request, answering with the subscribed
+// symbols or screener rows, or with an error message
+func (c *StreamClient) Handle(req models.StreamRequest) {
+	var err error
+	switch req.Action {
+	case "subscribe":
+		if req.ID != "" {
+			err = c.subscribeScreen(req)
+		} else {
+			err = c.subscribeSymbols(req.Symbols)
+		}
+	case "unsubscribe":
+		c.unsubscribe(req)
+	default:
+		err = fmt.Errorf("action must be subscribe or unsubscribe, got %q", req.Action)
+	}
+
+	if err != nil {
+		c.Reject(req.ID, err)
+	}
+}
+
+// Reject answers a request the client could not have applied with an error message
+func (c *StreamClient) Reject(subscription string, err error) {
+	c.enqueue(models.StreamMessage{Type: "error", Subscription: subscription, Error: err.Error()})
+}
+
+func (c *StreamClient) subscribeSymbols(symbols []string) error {
+	if len(symbols) == 0 {
+		return errors.New("symbols or an id with a query is required")
+	}
+
+	resolved := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		universe, _, ok := c.hub.marketData.universes.findSymbol(symbol)
+		if !ok {
+			return fmt.Errorf("unknown symbol %q", symbol)
+		}
+		resolved[symbol] = universe
+	}
+
+	c.mu.Lock()
+	for symbol, universe := range resolved {
+		c.symbols[symbol] = universe
+	}
+	if len(c.symbols) > maxStreamSymbols {
+		for symbol := range resolved {
+			delete(c.symbols, symbol)
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("at most %d symbols can be subscribed", maxStreamSymbols)
+	}
+	c.mu.Unlock()
+
+	for _, universe := range resolved {
+		snapshot, err := c.hub.marketData.currentSnapshot(universe)
+		if err != nil {
+			return err
+		}
+		c.hub.track(snapshot)
+	}
+
+	c.enqueue(models.StreamMessage{Type: "subscribed", Symbols: sortedKeys(boolSet(resolved))})
+	return nil
+}
+
+func (c *StreamClient) subscribeScreen(req models.StreamRequest) error {
+	if strings.TrimSpace(req.Query) == "" {
+		return errors.New("q is required for a screener subscription")
+	}
+	query, err := ParseScreenerQuery(req.Query)
+	if err != nil {
+		return err
+	}
+	snapshot, err := c.hub.marketData.currentSnapshot(req.Universe)
+	if err != nil {
+		return err
+	}
+	c.hub.track(snapshot)
+
+	screen := &streamScreen{universe: snapshot.Universe, query: query, members: make(map[string]bool)}
+	for i := range snapshot.stocks {
+		if query.Match(&snapshot.stocks[i]) {
+			screen.members[snapshot.stocks[i].Symbol] = true
+		}
+	}
+
+	c.mu.Lock()
+	if _, exists := c.screens[req.ID]; !exists && len(c.screens) >= maxStreamScreens {
+		c.mu.Unlock()
+		return fmt.Errorf("at most %d screener subscriptions are allowed", maxStreamScreens)
+	}
+	c.screens[req.ID] = screen
+	c.mu.Unlock()
+
+	c.enqueue(models.StreamMessage{
+		Type:         "subscribed",
+		Subscription: req.ID,
+		Symbols:      sortedKeys(screen.members),
+		Version:      snapshot.Version,
+		AsOf:         &snapshot.AsOf,
+	})
+	return nil
+}
+
+func (c *StreamClient) unsubscribe(req models.StreamRequest) {
+	c.mu.Lock()
+	if req.ID != "" {
+		delete(c.screens, req.ID)
+	}
+	var removed []string
+	for _, symbol := range req.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if _, ok := c.symbols[symbol]; ok {
+			delete(c.symbols, symbol)
+			removed = append(removed, symbol)
+		}
+	}
+	c.mu.Unlock()
+
+	c.enqueue(models.StreamMessage{Type: "unsubscribed", Subscription: req.ID, Symbols: removed})
+}
+
+// update sends the client what changed between two snapshots of a universe:
+// rows entering and leaving its screens, then ticks and indicator changes for
+// its symbols and the rows of its screens
+func (c *StreamClient) update(prev, next *Snapshot) {
+	c.mu.Lock()
+	var messages []models.StreamMessage
+	watched := make(map[string]bool)
+	for symbol, universe := range c.symbols {
+		if universe == next.Universe {
+			watched[symbol] = true
+		}
+	}
+
+	for _, id := range sortedKeys(screenIDs(c.screens)) {
+		screen := c.screens[id]
+		if screen.universe != next.Universe {
+			continue
+		}
+		members := make(map[string]bool, len(screen.members))
+		for i := range next.stocks {
+			stock := &next.stocks[i]
+			if !screen.query.Match(stock) {
+				continue
+			}
+			members[stock.Symbol] = true
+			if screen.members[stock.Symbol] {
+				watched[stock.Symbol] = true
+			} else {
+				row := *stock
+				messages = append(messages, streamMessage("enter", id, next, stock.Symbol, &row, nil))
+			}
+		}
+		for _, symbol := range sortedKeys(screen.members) {
+			if !members[symbol] {
+				messages = append(messages, streamMessage("leave", id, next, symbol, nil, nil))
+			}
+		}
+		screen.members = members
+	}
+	c.mu.Unlock()
+
+	if prev != nil {
+		for _, symbol := range sortedKeys(watched) {
+			before, ok := prev.Stock(symbol)
+			after, found := next.Stock(symbol)
+			if !ok || !found {
+				continue
+			}
+			if changes := changedFields(&before, &after, tickFields); changes != nil {
+				messages = append(messages, streamMessage("tick", "", next, symbol, nil, changes))
+			}
+			if changes := changedFields(&before, &after, indicatorFields); changes != nil {
+				messages = append(messages, streamMessage("indicators", "", next, symbol, nil, changes))
+			}
+		}
+	}
+
+	for _, msg := range messages {
+		c.enqueue(msg)
+	}
+}
+
+func streamMessage(kind, subscription string, snapshot *Snapshot, symbol string, stock *models.StockData, changes map[string]interface{}) models.StreamMessage {
+	return models.StreamMessage{
+		Type:         kind,
+		Subscription: subscription,
+		Symbol:       symbol,
+		Stock:        stock,
+		Changes:      changes,
+		Version:      snapshot.Version,
+		AsOf:         &snapshot.AsOf,
+	}
+}
+
+// changedFields returns the new values of the fields that differ, or nil
+func changedFields(before, after *models.StockData, names []string) map[string]interface{} {
+	var changes map[string]interface{}
+	for _, name := range names {
+		field, _ := lookupStockField(name)
+		if value := field.value(after); value != field.value(before) {
+			if changes == nil {
+				changes = make(map[string]interface{})
+			}
+			changes[name] = value
+		}
+	}
+	return changes
+}
+
+func boolSet(m map[string]string) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for key := range m {
+		set[key] = true
+	}
+	return set
+}
+
+func screenIDs(screens map[string]*streamScreen) map[string]bool {
+	ids := make(map[string]bool, len(screens))
+	for id := range screens {
+		ids[id] = true
+	}
+	return ids
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+// receive returns the next buffered message of a client
+func receive(t *testing.T, client *StreamClient) models.StreamMessage {
+	t.Helper()
+	select {
+	case msg := <-client.Messages():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a stream message")
+		return models.StreamMessage{}
+	}
+}
+
+// TestStreamSymbolUpdates tests that a symbol subscriber receives ticks after a refresh
+func TestStreamSymbolUpdates(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	hub := NewStreamHub(cfg, marketData)
+	defer hub.Close()
+
+	client := hub.Connect()
+	client.Handle(models.StreamRequest{Action: "subscribe", Symbols: []string{"aapl"}})
+	if msg := receive(t, client); msg.Type != "subscribed" || len(msg.Symbols) != 1 || msg.Symbols[0] != "AAPL" {
+		t.Fatalf("Expected a subscription to AAPL, got %+v", msg)
+	}
+
+	if _, err := marketData.Refresh(models.RefreshRequest{Scope: "symbols", Symbols: []string{"AAPL", "MSFT"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	msg := receive(t, client)
+	if msg.Type != "tick" || msg.Symbol != "AAPL" || msg.Changes["price"] == nil {
+		t.Errorf("Expected an AAPL price tick, got %+v", msg)
+	}
+	for len(client.Messages()) > 0 {
+		if msg := <-client.Messages(); msg.Symbol != "AAPL" {
+			t.Errorf("Expected only updates of the subscribed symbol, got %+v", msg)
+		}
+	}
+
+	client.Handle(models.StreamRequest{Action: "subscribe", Symbols: []string{"NOPE"}})
+	if msg := receive(t, client); msg.Type != "error" {
+		t.Errorf("Expected an error for an unknown symbol, got %+v", msg)
+	}
+}
+
+// TestStreamScreenMembership tests that rows entering and leaving a screen are pushed
+func TestStreamScreenMembership(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	hub := NewStreamHub(cfg, marketData)
+	defer hub.Close()
+
+	universe := &models.Universe{Name: "test"}
+	snapshot := func(prices map[string]float64) *Snapshot {
+		var stocks []models.StockData
+		for symbol, price := range prices {
+			stocks = append(stocks, models.StockData{Symbol: symbol, Price: price})
+		}
+		return newSnapshot(universe.Name, universe.UpdatedAt, stocks, time.Now())
+	}
+
+	client := hub.Connect()
+	query, _ := ParseScreenerQuery("price > 100")
+	client.screens["cheap"] = &streamScreen{universe: "test", query: query, members: map[string]bool{"AAA": true}}
+	hub.track(snapshot(map[string]float64{"AAA": 150, "BBB": 50}))
+
+	hub.publish(snapshot(map[string]float64{"AAA": 90, "BBB": 120}))
+	enter, leave := receive(t, client), receive(t, client)
+	if enter.Type != "enter" || enter.Symbol != "BBB" || enter.Stock == nil || enter.Subscription != "cheap" {
+		t.Errorf("Expected BBB to enter, got %+v", enter)
+	}
+	if leave.Type != "leave" || leave.Symbol != "AAA" {
+		t.Errorf("Expected AAA to leave, got %+v", leave)
+	}
+
+	hub.publish(snapshot(map[string]float64{"AAA": 80, "BBB": 130}))
+	if tick := receive(t, client); tick.Type != "tick" || tick.Symbol != "BBB" || tick.Changes["price"] != 130.0 {
+		t.Errorf("Expected a tick for the remaining row, got %+v", tick)
+	}
+}
+
+// TestStreamSlowConsumer tests that a client with a full buffer is disconnected
+func TestStreamSlowConsumer(t *testing.T) {
+	cfg, marketData := newTestMarketDataService(t)
+	cfg.StreamBuffer = 1
+	hub := NewStreamHub(cfg, marketData)
+	defer hub.Close()
+
+	slow := hub.Connect()
+	slow.Handle(models.StreamRequest{Action: "subscribe", Symbols: []string{"AAPL"}})
+	slow.Handle(models.StreamRequest{Action: "subscribe", Symbols: []string{"MSFT"}})
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatalf("Expected the slow client to be disconnected")
+	}
+	if !errors.Is(slow.Err(), ErrSlowConsumer) || hub.SlowConsumers() != 1 || hub.ClientCount() != 0 {
+		t.Errorf("Expected a slow consumer disconnect, got %v", slow.Err())
+	}
+}
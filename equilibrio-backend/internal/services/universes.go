@@ -0,0 +1,351 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+var (
+	// ErrUniverseNotFound is returned when no universe has the requested name
+	ErrUniverseNotFound = errors.New("universe not found")
+	// ErrUniverseConflict is returned when creating a universe whose name is
+	// taken, or changing one that is loaded from a file or built in
+	ErrUniverseConflict = errors.New("universe conflict")
+	// ErrInvalidUniverse is returned when a universe definition is unusable
+	ErrInvalidUniverse = errors.New("invalid universe")
+)
+
+const (
+	builtinUniverseName = "default"
+	maxUniverseSymbols  = 5000
+)
+
+var universeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// defaultTickers is the built-in universe scanned when no other is configured
+var defaultTickers = []models.UniverseSymbol{
+	{Symbol: "AAPL", Name: "Apple Inc.", Sector: "Technology", Industry: "Hardware", Exchange: "NASDAQ"},
+	{Symbol: "MSFT", Name: "Microsoft Corp.", Sector: "Technology", Industry: "Software", Exchange: "NASDAQ"},
+	{Symbol: "GOOGL", Name: "Alphabet Inc.", Sector: "Communication Services", Industry: "Media", Exchange: "NASDAQ"},
+	{Symbol: "AMZN", Name: "Amazon.com Inc.", Sector: "Consumer Cyclical", Industry: "Retail", Exchange: "NASDAQ"},
+	{Symbol: "NVDA", Name: "NVIDIA Corp.", Sector: "Technology", Industry: "Semiconductors", Exchange: "NASDAQ"},
+	{Symbol: "TSLA", Name: "Tesla Inc.", Sector: "Consumer Cyclical", Industry: "Automotive", Exchange: "NASDAQ"},
+	{Symbol: "META", Name: "Meta Platforms", Sector: "Communication Services", Industry: "Media", Exchange: "NASDAQ"},
+	{Symbol: "BRK.B", Name: "Berkshire Hathaway", Sector: "Financial", Industry: "Insurance", Exchange: "NYSE"},
+	{Symbol: "JNJ", Name: "Johnson & Johnson", Sector: "Healthcare", Industry: "Pharmaceuticals", Exchange: "NYSE"},
+	{Symbol: "JPM", Name: "JPMorgan Chase", Sector: "Financial", Industry: "Banks", Exchange: "NYSE"},
+	{Symbol: "V", Name: "Visa Inc.", Sector: "Financial", Industry: "Capital Markets", Exchange: "NYSE"},
+	{Symbol: "PG", Name: "Procter & Gamble", Sector: "Consumer Defensive", Industry: "Household Products", Exchange: "NYSE"},
+	{Symbol: "MA", Name: "Mastercard Inc.", Sector: "Financial", Industry: "Capital Markets", Exchange: "NYSE"},
+	{Symbol: "HD", Name: "Home Depot", Sector: "Consumer Cyclical", Industry: "Retail", Exchange: "NYSE"},
+	{Symbol: "BAC", Name: "Bank of America", Sector: "Financial", Industry: "Banks", Exchange: "NYSE"},
+	{Symbol: "XOM", Name: "Exxon Mobil", Sector: "Energy", Industry: "Oil & Gas", Exchange: "NYSE"},
+	{Symbol: "CVX", Name: "Chevron Corp.", Sector: "Energy", Industry: "Oil & Gas", Exchange: "NYSE"},
+	{Symbol: "ABBV", Name: "AbbVie Inc.", Sector: "Healthcare", Industry: "Biotechnology", Exchange: "NYSE"},
+	{Symbol: "KO", Name: "Coca-Cola Co.", Sector: "Consumer Defensive", Industry: "Beverages", Exchange: "NYSE"},
+	{Symbol: "PFE", Name: "Pfizer Inc.", Sector: "Healthcare", Industry: "Pharmaceuticals", Exchange: "NYSE"},
+}
+
+// UniverseService manages the named symbol lists that can be scanned. The
+// built-in universe and those loaded from UniversesDir are read-only;
+// universes created through the API are persisted to UniversesFile.
+type UniverseService struct {
+	store       *jsonStore[models.Universe]
+	fixed       map[string]models.Universe
+	defaultName string
+}
+
+func NewUniverseService(cfg *config.Config) (*UniverseService, error) {
+	store, err := newJSONStore[models.Universe](cfg.UniversesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load universes: %w", err)
+	}
+
+	fixed, err := loadUniverseFiles(cfg.UniversesDir)
+	if err != nil {
+		return nil, err
+	}
+	fixed[builtinUniverseName] = newUniverse(builtinUniverseName, "Built-in large-cap universe", "builtin", defaultTickers, time.Time{})
+
+	s := &UniverseService{
+		store:       store,
+		fixed:       fixed,
+		defaultName: cfg.DefaultUniverse,
+	}
+	if s.defaultName == "" {
+		s.defaultName = builtinUniverseName
+	}
+	if _, err := s.GetUniverse(s.defaultName); err != nil {
+		return nil, fmt.Errorf("default universe %q: %w", s.defaultName, err)
+	}
+
+	return s, nil
+}
+
+// loadUniverseFiles reads every *.json file in dir as a universe. The file
+// name is used when the file does not name the universe. A missing directory
+// loads nothing.
+func loadUniverseFiles(dir string) (map[string]models.Universe, error) {
+	universes := make(map[string]models.Universe)
+	if dir == "" {
+		return universes, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list universes in %s: %w", dir, err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var req models.UniverseRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if req.Name == "" {
+			req.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+
+		symbols, err := validateUniverse(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, exists := universes[req.Name]; exists || req.Name == builtinUniverseName {
+			return nil, fmt.Errorf("%s: %w: duplicate universe name %q", path, ErrUniverseConflict, req.Name)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		universes[req.Name] = newUniverse(req.Name, req.Description, "file", symbols, info.ModTime())
+	}
+
+	return universes, nil
+}
+
+func newUniverse(name, description, source string, symbols []models.UniverseSymbol, at time.Time) models.Universe {
+	return models.Universe{
+		Name:        name,
+		Description: description,
+		Source:      source,
+		SymbolCount: len(symbols),
+		Symbols:     symbols,
+		CreatedAt:   at,
+		UpdatedAt:   at,
+	}
+}
+
+// DefaultUniverse returns the name of the universe scanned when a request names none
+func (s *UniverseService) DefaultUniverse() string {
+	return s.defaultName
+}
+
+// ListUniverses returns every universe ordered by name, without their symbols
+func (s *UniverseService) ListUniverses() []models.Universe {
+	universes := s.store.list()
+	for _, universe := range s.fixed {
+		universes = append(universes, universe)
+	}
+	for i := range universes {
+		universes[i].Symbols = nil
+	}
+	sort.Slice(universes, func(i, j int) bool {
+		return universes[i].Name < universes[j].Name
+	})
+	return universes
+}
+
+// GetUniverse returns a universe by name; an empty name returns the default universe
+func (s *UniverseService) GetUniverse(name string) (*models.Universe, error) {
+	if name == "" {
+		name = s.defaultName
+	}
+	name = strings.ToLower(name)
+	if universe, ok := s.fixed[name]; ok {
+		return &universe, nil
+	}
+	if universe, ok := s.store.get(name); ok {
+		return &universe, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUniverseNotFound, name)
+}
+
+// CreateUniverse validates and stores a new universe
+func (s *UniverseService) CreateUniverse(req models.UniverseRequest) (*models.Universe, error) {
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	symbols, err := validateUniverse(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetUniverse(req.Name); err == nil {
+		return nil, fmt.Errorf("%w: universe %q already exists", ErrUniverseConflict, req.Name)
+	}
+
+	universe := newUniverse(req.Name, req.Description, "api", symbols, time.Now())
+	if err := s.store.put(universe.Name, universe); err != nil {
+		return nil, err
+	}
+	return &universe, nil
+}
+
+// UpdateUniverse replaces the description and symbols of an API-created universe
+func (s *UniverseService) UpdateUniverse(name string, req models.UniverseRequest) (*models.Universe, error) {
+	universe, err := s.editableUniverse(name)
+	if err != nil {
+		return nil, err
+	}
+	req.Name = universe.Name
+	symbols, err := validateUniverse(req)
+	if err != nil {
+		return nil, err
+	}
+
+	universe.Description = req.Description
+	universe.Symbols = symbols
+	universe.SymbolCount = len(symbols)
+	universe.UpdatedAt = time.Now()
+
+	if err := s.store.put(universe.Name, universe); err != nil {
+		return nil, err
+	}
+	return &universe, nil
+}
+
+// DeleteUniverse removes an API-created universe
+func (s *UniverseService) DeleteUniverse(name string) error {
+	universe, err := s.editableUniverse(name)
+	if err != nil {
+		return err
+	}
+	if universe.Name == s.defaultName {
+		return fmt.Errorf("%w: the default universe cannot be deleted", ErrUniverseConflict)
+	}
+	if _, err := s.store.delete(universe.Name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// editableUniverse returns a universe that may be changed through the API
+func (s *UniverseService) editableUniverse(name string) (models.Universe, error) {
+	name = strings.ToLower(name)
+	if _, ok := s.fixed[name]; ok {
+		return models.Universe{}, fmt.Errorf("%w: universe %q is read-only", ErrUniverseConflict, name)
+	}
+	universe, ok := s.store.get(name)
+	if !ok {
+		return models.Universe{}, fmt.Errorf("%w: %s", ErrUniverseNotFound, name)
+	}
+	return universe, nil
+}
+
+// findSymbol returns the metadata of a symbol, preferring the default universe
+func (s *UniverseService) findSymbol(symbol string) (string, models.UniverseSymbol, bool) {
+	symbol = strings.ToUpper(symbol)
+	if universe, err := s.GetUniverse(""); err == nil {
+		for _, sym := range universe.Symbols {
+			if sym.Symbol == symbol {
+				return universe.Name, sym, true
+			}
+		}
+	}
+
+	universes := s.store.list()
+	for _, universe := range s.fixed {
+		universes = append(universes, universe)
+	}
+	sort.Slice(universes, func(i, j int) bool {
+		return universes[i].Name < universes[j].Name
+	})
+	for _, universe := range universes {
+		for _, sym := range universe.Symbols {
+			if sym.Symbol == symbol {
+				return universe.Name, sym, true
+			}
+		}
+	}
+	return "", models.UniverseSymbol{}, false
+}
+
+// allSectors returns the sectors used by any universe
+func (s *UniverseService) allSectors() []string {
+	seen := make(map[string]bool)
+	collect := func(universe models.Universe) {
+		for _, sym := range universe.Symbols {
+			seen[sym.Sector] = true
+		}
+	}
+	for _, universe := range s.fixed {
+		collect(universe)
+	}
+	for _, universe := range s.store.list() {
+		collect(universe)
+	}
+	return sortedKeys(seen)
+}
+
+// universeSectors returns the distinct sectors of a universe in name order
+func universeSectors(universe *models.Universe) []string {
+	seen := make(map[string]bool)
+	for _, sym := range universe.Symbols {
+		seen[sym.Sector] = true
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateUniverse checks the universe name and returns its symbols
+// normalized to upper case with duplicates rejected
+func validateUniverse(req models.UniverseRequest) ([]models.UniverseSymbol, error) {
+	if !universeNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must be lower-case letters, digits, '.', '_' or '-'", ErrInvalidUniverse)
+	}
+	if len(req.Symbols) == 0 {
+		return nil, fmt.Errorf("%w: at least one symbol is required", ErrInvalidUniverse)
+	}
+	if len(req.Symbols) > maxUniverseSymbols {
+		return nil, fmt.Errorf("%w: at most %d symbols are allowed", ErrInvalidUniverse, maxUniverseSymbols)
+	}
+
+	symbols := make([]models.UniverseSymbol, len(req.Symbols))
+	seen := make(map[string]bool, len(req.Symbols))
+	for i, sym := range req.Symbols {
+		sym.Symbol = strings.ToUpper(strings.TrimSpace(sym.Symbol))
+		if sym.Symbol == "" {
+			return nil, fmt.Errorf("%w: symbol %d is empty", ErrInvalidUniverse, i+1)
+		}
+		if seen[sym.Symbol] {
+			return nil, fmt.Errorf("%w: duplicate symbol %s", ErrInvalidUniverse, sym.Symbol)
+		}
+		seen[sym.Symbol] = true
+		if sym.Name == "" {
+			sym.Name = sym.Symbol
+		}
+		symbols[i] = sym
+	}
+	return symbols, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+// TestUniverseLifecycle tests file-loaded and API-created universes
+func TestUniverseLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	universesDir := filepath.Join(dir, "universes")
+	if err := os.MkdirAll(universesDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := `{"description": "Energy names", "symbols": [
+		{"symbol": "xom", "name": "Exxon Mobil", "sector": "Energy", "industry": "Oil & Gas", "exchange": "NYSE"},
+		{"symbol": "CVX", "name": "Chevron Corp.", "sector": "Energy", "industry": "Oil & Gas", "exchange": "NYSE"}
+	]}`
+	if err := os.WriteFile(filepath.Join(universesDir, "energy.json"), []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		UniversesFile: filepath.Join(dir, "universes.json"),
+		UniversesDir:  universesDir,
+	}
+	universes, err := NewUniverseService(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	energy, err := universes.GetUniverse("energy")
+	if err != nil {
+		t.Fatalf("Expected the file universe to load: %v", err)
+	}
+	if energy.Symbols[0].Symbol != "XOM" || energy.Source != "file" {
+		t.Errorf("Expected normalized symbols from a file universe, got %+v", energy)
+	}
+	if sectors := universeSectors(energy); len(sectors) != 1 || sectors[0] != "Energy" {
+		t.Errorf("Expected sectors derived from the universe, got %v", sectors)
+	}
+
+	if _, err := universes.UpdateUniverse("energy", models.UniverseRequest{}); !errors.Is(err, ErrUniverseConflict) {
+		t.Errorf("Expected file universes to be read-only, got %v", err)
+	}
+
+	req := models.UniverseRequest{
+		Name:    "My-Watch",
+		Symbols: []models.UniverseSymbol{{Symbol: "aapl", Sector: "Technology"}},
+	}
+	if _, err := universes.CreateUniverse(req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := universes.CreateUniverse(req); !errors.Is(err, ErrUniverseConflict) {
+		t.Errorf("Expected a conflict for a duplicate name, got %v", err)
+	}
+
+	reloaded, err := NewUniverseService(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	watch, err := reloaded.GetUniverse("my-watch")
+	if err != nil {
+		t.Fatalf("API universes should persist across reloads: %v", err)
+	}
+	if watch.Symbols[0].Name != "AAPL" {
+		t.Errorf("Expected the symbol to default the name, got %+v", watch.Symbols[0])
+	}
+
+	invalid := []models.UniverseRequest{
+		{Name: "bad name", Symbols: req.Symbols},
+		{Name: "empty"},
+		{Name: "dupes", Symbols: []models.UniverseSymbol{{Symbol: "A"}, {Symbol: "a"}}},
+	}
+	for _, r := range invalid {
+		if _, err := reloaded.CreateUniverse(r); !errors.Is(err, ErrInvalidUniverse) {
+			t.Errorf("Expected ErrInvalidUniverse for %+v, got %v", r, err)
+		}
+	}
+
+	if err := reloaded.DeleteUniverse("my-watch"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := reloaded.GetUniverse("my-watch"); !errors.Is(err, ErrUniverseNotFound) {
+		t.Errorf("Expected ErrUniverseNotFound after delete, got %v", err)
+	}
+}
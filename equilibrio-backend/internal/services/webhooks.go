@@ -0,0 +1,692 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"equilibrio-backend/internal/config"
+	"equilibrio-backend/internal/models"
+)
+
+var (
+	// ErrWebhookNotFound is returned when no webhook endpoint has the requested ID
+	ErrWebhookNotFound = errors.New("webhook endpoint not found")
+	// ErrDeliveryNotFound is returned when no delivery or dead letter has the requested ID
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrInvalidWebhook is returned when a webhook endpoint definition or query is unusable
+	ErrInvalidWebhook = errors.New("invalid webhook endpoint")
+)
+
+// Webhook payload types besides the signal event types
+const (
+	WebhookAlert = "alert"
+	WebhookPing  = "ping"
+)
+
+// Delivery statuses. A failed delivery is also in the dead letters until it
+// is retried or deleted.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Headers of webhook requests. The signature is "sha256=" followed by the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed by the endpoint's secret.
+const (
+	WebhookEventHeader     = "X-Equilibrio-Event"
+	WebhookDeliveryHeader  = "X-Equilibrio-Delivery"
+	WebhookTimestampHeader = "X-Equilibrio-Timestamp"
+	WebhookSignatureHeader = "X-Equilibrio-Signature"
+)
+
+// Webhook limits and defaults
+const (
+	webhookAlertChannel    = "webhook"
+	webhookQueueSize       = 10000
+	maxWebhookBackoff      = time.Hour
+	minWebhookSecret       = 16
+	maxWebhookResponse     = 64 << 10 // Response bytes read so the connection can be reused
+	webhookFlushInterval   = time.Second
+	defaultDeliveriesLimit = 100
+	maxDeliveriesLimit     = 1000
+)
+
+// SignWebhook returns the signature header value of a webhook body sent at
+// timestamp (Unix seconds). Receivers recompute it to check that a request
+// comes from this server and reject old timestamps to stop replays.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookService posts alerts and signal events to webhook endpoints. Every
+// payload becomes a delivery per matching endpoint that is attempted by a pool
+// of workers and retried with exponential backoff on network errors, 408, 429
+// and 5xx responses. A delivery that runs out of attempts, or is rejected with
+// another status, fails and moves to the dead letters, where it can be retried.
+// Deliveries and their attempts are kept in a bounded log that is changed in
+// memory and saved in batches, off the path of the snapshot listeners; pending
+// deliveries resume after a restart.
+type WebhookService struct {
+	endpoints    *jsonStore[models.WebhookEndpoint]
+	deliveries   *jsonStore[models.WebhookDelivery]
+	deadLetters  *jsonStore[models.WebhookDelivery]
+	logLimit     int
+	maxAttempts  int
+	backoff      time.Duration
+	client       *http.Client
+	allowPrivate bool
+	universes    *UniverseService
+
+	mu     sync.Mutex // Serializes pruning with moves between the log and the dead letters
+	timers map[string]*time.Timer
+
+	queue   chan string // IDs of deliveries due
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// NewWebhookService loads the stored endpoints and deliveries, registers the
+// "webhook" alert channel, subscribes to signal events and starts delivering
+func NewWebhookService(cfg *config.Config, universes *UniverseService, alerts *AlertService, events *EventService) (*WebhookService, error) {
+	endpoints, err := newJSONStore[models.WebhookEndpoint](cfg.WebhooksFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook endpoints: %w", err)
+	}
+	endpoints.mode = 0o600 // Holds the signing secrets
+	deliveries, err := newJSONStore[models.WebhookDelivery](cfg.WebhookDeliveriesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook deliveries: %w", err)
+	}
+	deadLetters, err := newJSONStore[models.WebhookDelivery](cfg.WebhookDeadLettersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook dead letters: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &WebhookService{
+		endpoints:    endpoints,
+		deliveries:   deliveries,
+		deadLetters:  deadLetters,
+		logLimit:     cfg.WebhookLogLimit,
+		maxAttempts:  cfg.WebhookMaxAttempts,
+		backoff:      cfg.WebhookBackoff,
+		client:       newWebhookClient(cfg.WebhookTimeout, cfg.WebhookAllowPrivate),
+		allowPrivate: cfg.WebhookAllowPrivate,
+		universes:    universes,
+		timers:       make(map[string]*time.Timer),
+		queue:        make(chan string, webhookQueueSize),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	if w.logLimit <= 0 {
+		w.logLimit = 1000
+	}
+	if w.maxAttempts <= 0 {
+		w.maxAttempts = 6
+	}
+	if w.backoff <= 0 {
+		w.backoff = time.Second
+	}
+	workers := cfg.WebhookWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	for i := 0; i < workers; i++ {
+		w.workers.Add(1)
+		go w.work()
+	}
+	w.workers.Add(1)
+	go w.flushLoop()
+	for _, delivery := range deliveries.list() {
+		if delivery.Status == DeliveryPending {
+			w.schedule(delivery)
+		}
+	}
+
+	alerts.RegisterChannel(webhookAlertChannel, w.sendAlert)
+	events.OnEvent(w.sendEvent)
+	return w, nil
+}
+
+// Close stops delivering and saves the delivery log. Requests in flight are
+// abandoned and their deliveries stay pending until the next start.
+func (w *WebhookService) Close() {
+	w.cancel()
+	w.mu.Lock()
+	for id, timer := range w.timers {
+		timer.Stop()
+		delete(w.timers, id)
+	}
+	w.mu.Unlock()
+	w.workers.Wait()
+	w.persist()
+}
+
+// flushLoop saves the delivery log and dead letters in batches
+func (w *WebhookService) flushLoop() {
+	defer w.workers.Done()
+	ticker := time.NewTicker(webhookFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.persist()
+		}
+	}
+}
+
+// persist drops the oldest settled deliveries beyond the log limit and saves
+// the staged changes
+func (w *WebhookService) persist() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, store := range []*jsonStore[models.WebhookDelivery]{w.deliveries, w.deadLetters} {
+		existing := store.list()
+		if excess := len(existing) - w.logLimit; excess > 0 {
+			sortDeliveries(existing)
+			var remove []string
+			for i := len(existing) - 1; i >= 0 && len(remove) < excess; i-- {
+				if existing[i].Status != DeliveryPending {
+					remove = append(remove, existing[i].ID)
+				}
+			}
+			store.stage(nil, remove)
+		}
+		if err := store.flush(); err != nil {
+			log.Printf("Webhooks: failed to save deliveries: %v", err)
+		}
+	}
+}
+
+// ListEndpoints returns all endpoints ordered by name, without their secrets
+func (w *WebhookService) ListEndpoints() []models.WebhookEndpoint {
+	endpoints := w.endpoints.list()
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Name != endpoints[j].Name {
+			return endpoints[i].Name < endpoints[j].Name
+		}
+		return endpoints[i].ID < endpoints[j].ID
+	})
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+	return endpoints
+}
+
+// GetEndpoint returns an endpoint by ID, without its secret
+func (w *WebhookService) GetEndpoint(id string) (*models.WebhookEndpoint, error) {
+	endpoint, ok := w.endpoints.get(id)
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	endpoint.Secret = ""
+	return &endpoint, nil
+}
+
+// CreateEndpoint validates and stores a new endpoint. The endpoint is
+// returned with its secret, generated when the request has none, which is
+// not shown again.
+func (w *WebhookService) CreateEndpoint(req models.WebhookEndpointRequest) (*models.WebhookEndpoint, error) {
+	now := time.Now()
+	endpoint := models.WebhookEndpoint{ID: newID(), CreatedAt: now}
+	if err := w.apply(&endpoint, req); err != nil {
+		return nil, err
+	}
+	if endpoint.Secret == "" {
+		endpoint.Secret = newWebhookSecret()
+	}
+	endpoint.UpdatedAt = now
+
+	if err := w.endpoints.put(endpoint.ID, endpoint); err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// UpdateEndpoint validates and replaces an existing endpoint, keeping its
+// secret unless the request sets a new one. Pending deliveries go to the new
+// URL with the new secret.
+func (w *WebhookService) UpdateEndpoint(id string, req models.WebhookEndpointRequest) (*models.WebhookEndpoint, error) {
+	endpoint, ok := w.endpoints.get(id)
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	if err := w.apply(&endpoint, req); err != nil {
+		return nil, err
+	}
+	endpoint.UpdatedAt = time.Now()
+
+	if err := w.endpoints.put(id, endpoint); err != nil {
+		return nil, err
+	}
+	endpoint.Secret = ""
+	return &endpoint, nil
+}
+
+// DeleteEndpoint removes an endpoint; its pending deliveries fail
+func (w *WebhookService) DeleteEndpoint(id string) error {
+	deleted, err := w.endpoints.delete(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// apply validates a request and copies it onto an endpoint
+func (w *WebhookService) apply(endpoint *models.WebhookEndpoint, req models.WebhookEndpointRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidWebhook)
+	}
+	target, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	// Names are checked when they are resolved, on every connection
+	host := target.Hostname()
+	if !w.allowPrivate {
+		if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+			return fmt.Errorf("%w: url must not point to a loopback, private or link-local address", ErrInvalidWebhook)
+		}
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecret {
+		return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhook, minWebhookSecret)
+	}
+
+	events := req.Events
+	if len(events) == 0 {
+		events = []string{WebhookAlert, EventSignal}
+	}
+	for _, event := range events {
+		switch event {
+		case WebhookAlert, EventSignal, EventTrend, EventEquilibriumZone:
+		default:
+			return fmt.Errorf("%w: unknown event %q (use %s, %s, %s or %s)",
+				ErrInvalidWebhook, event, WebhookAlert, EventSignal, EventTrend, EventEquilibriumZone)
+		}
+	}
+
+	universe := ""
+	if req.Universe != "" {
+		u, err := w.universes.GetUniverse(req.Universe)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+		}
+		universe = u.Name
+	}
+	var symbols []string
+	for _, symbol := range req.Symbols {
+		if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	endpoint.Name = name
+	endpoint.URL = target.String()
+	if req.Secret != "" {
+		endpoint.Secret = req.Secret
+	}
+	endpoint.Events = events
+	endpoint.Universe = universe
+	endpoint.Symbols = symbols
+	endpoint.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+// newWebhookSecret returns a random signing secret
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// subscribed reports whether an endpoint takes an event of a stock
+func (w *WebhookService) subscribed(endpoint *models.WebhookEndpoint, event, universe, symbol string) bool {
+	if !endpoint.Enabled || (endpoint.Universe != "" && endpoint.Universe != universe) {
+		return false
+	}
+	if len(endpoint.Symbols) > 0 && !containsString(endpoint.Symbols, symbol) {
+		return false
+	}
+	return containsString(endpoint.Events, event)
+}
+
+// sendAlert is the "webhook" alert channel
+func (w *WebhookService) sendAlert(alert models.Alert) {
+	w.send(WebhookAlert, alert.Universe, alert.Symbol, alert)
+}
+
+// sendEvent delivers a signal, trend or equilibrium zone change
+func (w *WebhookService) sendEvent(event models.SignalEvent) {
+	w.send(event.Type, event.Universe, event.Symbol, event)
+}
+
+// send creates a delivery of a payload for every endpoint subscribed to it
+func (w *WebhookService) send(kind, universe, symbol string, data interface{}) {
+	endpoints := w.endpoints.list()
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].ID < endpoints[j].ID })
+	for i := range endpoints {
+		if !w.subscribed(&endpoints[i], kind, universe, symbol) {
+			continue
+		}
+		if _, err := w.dispatch(&endpoints[i], kind, data); err != nil {
+			log.Printf("Webhooks: failed to queue %s for endpoint %s: %v", kind, endpoints[i].ID, err)
+		}
+	}
+}
+
+// TestEndpoint sends a ping to an endpoint, enabled or not, and returns its
+// pending delivery
+func (w *WebhookService) TestEndpoint(id string) (*models.WebhookDelivery, error) {
+	endpoint, ok := w.endpoints.get(id)
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return w.dispatch(&endpoint, WebhookPing, map[string]string{"endpointId": endpoint.ID, "name": endpoint.Name})
+}
+
+// dispatch logs a new delivery to an endpoint and queues its first attempt.
+// It only touches memory, as it runs within snapshot listeners.
+func (w *WebhookService) dispatch(endpoint *models.WebhookEndpoint, kind string, data interface{}) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", kind, err)
+	}
+	now := time.Now().UTC()
+	delivery := models.WebhookDelivery{
+		ID:           newID(),
+		EndpointID:   endpoint.ID,
+		URL:          endpoint.URL,
+		Type:         kind,
+		Status:       DeliveryPending,
+		Attempts:     []models.WebhookAttempt{},
+		AttemptsLeft: w.maxAttempts,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	delivery.Payload = models.WebhookPayload{ID: delivery.ID, Type: kind, Time: now, Data: body}
+
+	stageDelivery(w.deliveries, delivery)
+	w.enqueue(delivery)
+	return &delivery, nil
+}
+
+// enqueue queues a delivery that is due, failing it when the queue is full
+// rather than blocking the evaluation of alerts and events
+func (w *WebhookService) enqueue(delivery models.WebhookDelivery) {
+	select {
+	case w.queue <- delivery.ID:
+	default:
+		delivery.Attempts = append(delivery.Attempts, models.WebhookAttempt{Time: time.Now().UTC(), Error: "delivery queue full"})
+		w.finish(delivery, DeliveryFailed)
+	}
+}
+
+// schedule queues a pending delivery when its next attempt is due
+func (w *WebhookService) schedule(delivery models.WebhookDelivery) {
+	var delay time.Duration
+	if delivery.NextAttemptAt != nil {
+		delay = time.Until(*delivery.NextAttemptAt)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx.Err() != nil {
+		return
+	}
+	w.timers[delivery.ID] = time.AfterFunc(delay, func() {
+		w.mu.Lock()
+		delete(w.timers, delivery.ID)
+		w.mu.Unlock()
+
+		select {
+		case w.queue <- delivery.ID:
+		case <-w.ctx.Done():
+		}
+	})
+}
+
+func (w *WebhookService) work() {
+	defer w.workers.Done()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case id := <-w.queue:
+			w.attempt(id)
+		}
+	}
+}
+
+// attempt posts a pending delivery to its endpoint's current URL and decides
+// whether it is delivered, retried later or failed
+func (w *WebhookService) attempt(id string) {
+	delivery, ok := w.deliveries.get(id)
+	if !ok || delivery.Status != DeliveryPending {
+		return
+	}
+	endpoint, ok := w.endpoints.get(delivery.EndpointID)
+	if !ok {
+		delivery.Attempts = append(delivery.Attempts, models.WebhookAttempt{Time: time.Now().UTC(), Error: "endpoint was deleted"})
+		w.finish(delivery, DeliveryFailed)
+		return
+	}
+
+	delivery.URL = endpoint.URL
+	attempt, retryable := w.post(&endpoint, delivery.Payload)
+	if w.ctx.Err() != nil {
+		return // Closing: the delivery stays pending
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.AttemptsLeft--
+
+	switch {
+	case attempt.Error == "" && attempt.StatusCode >= 200 && attempt.StatusCode < 300:
+		w.finish(delivery, DeliveryDelivered)
+	case retryable && delivery.AttemptsLeft > 0:
+		next := time.Now().UTC().Add(w.retryDelay(w.maxAttempts - delivery.AttemptsLeft))
+		delivery.NextAttemptAt = &next
+		delivery.UpdatedAt = time.Now().UTC()
+		stageDelivery(w.deliveries, delivery)
+		w.schedule(delivery)
+	default:
+		w.finish(delivery, DeliveryFailed)
+	}
+}
+
+// retryDelay returns the wait after a delivery's nth attempt of a round,
+// doubling from the base backoff up to an hour
+func (w *WebhookService) retryDelay(n int) time.Duration {
+	delay := w.backoff
+	for i := 1; i < n && delay < maxWebhookBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxWebhookBackoff {
+		delay = maxWebhookBackoff
+	}
+	return delay
+}
+
+// post sends a signed payload once and reports whether a failure is worth
+// retrying
+func (w *WebhookService) post(endpoint *models.WebhookEndpoint, payload models.WebhookPayload) (models.WebhookAttempt, bool) {
+	start := time.Now()
+	attempt := models.WebhookAttempt{Time: start.UTC()}
+	fail := func(err error, retryable bool) (models.WebhookAttempt, bool) {
+		attempt.Error = err.Error()
+		attempt.DurationMs = time.Since(start).Milliseconds()
+		return attempt, retryable
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fail(err, false)
+	}
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(err, false)
+	}
+	timestamp := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Equilibrio-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, payload.Type)
+	req.Header.Set(WebhookDeliveryHeader, payload.ID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(endpoint.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fail(err, !errors.Is(err, errNonPublicAddress))
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponse))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	retryable := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return attempt, retryable
+}
+
+// finish logs a delivery as delivered or failed; failed deliveries also go
+// to the dead letters
+func (w *WebhookService) finish(delivery models.WebhookDelivery, status string) {
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	delivery.UpdatedAt = time.Now().UTC()
+
+	stageDelivery(w.deliveries, delivery)
+	if status != DeliveryFailed {
+		return
+	}
+	log.Printf("Webhooks: %s delivery %s to endpoint %s failed after %d attempts", delivery.Type, delivery.ID, delivery.EndpointID, len(delivery.Attempts))
+	stageDelivery(w.deadLetters, delivery)
+}
+
+// stageDelivery changes a delivery in memory; persist saves it
+func stageDelivery(store *jsonStore[models.WebhookDelivery], delivery models.WebhookDelivery) {
+	store.stage(map[string]models.WebhookDelivery{delivery.ID: delivery}, nil)
+}
+
+// sortDeliveries orders deliveries newest first
+func sortDeliveries(deliveries []models.WebhookDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+}
+
+// ListDeliveries returns the delivery log, newest first, optionally of one
+// endpoint or status, up to limit (100 when zero)
+func (w *WebhookService) ListDeliveries(endpointID, status string, limit int) ([]models.WebhookDelivery, error) {
+	switch status {
+	case "", DeliveryPending, DeliveryDelivered, DeliveryFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q (use %s, %s or %s)", ErrInvalidWebhook, status, DeliveryPending, DeliveryDelivered, DeliveryFailed)
+	}
+	return filterDeliveries(w.deliveries.list(), endpointID, status, limit)
+}
+
+// GetDelivery returns a logged delivery by ID
+func (w *WebhookService) GetDelivery(id string) (*models.WebhookDelivery, error) {
+	delivery, ok := w.deliveries.get(id)
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+// ListDeadLetters returns the failed deliveries, newest first, optionally of
+// one endpoint, up to limit (100 when zero)
+func (w *WebhookService) ListDeadLetters(endpointID string, limit int) ([]models.WebhookDelivery, error) {
+	return filterDeliveries(w.deadLetters.list(), endpointID, "", limit)
+}
+
+func filterDeliveries(all []models.WebhookDelivery, endpointID, status string, limit int) ([]models.WebhookDelivery, error) {
+	if limit == 0 {
+		limit = defaultDeliveriesLimit
+	}
+	if limit < 0 || limit > maxDeliveriesLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidWebhook, maxDeliveriesLimit)
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range all {
+		if (endpointID == "" || delivery.EndpointID == endpointID) && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sortDeliveries(deliveries)
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// RetryDeadLetter takes a failed delivery out of the dead letters and starts
+// a new round of attempts with the same payload
+func (w *WebhookService) RetryDeadLetter(id string) (*models.WebhookDelivery, error) {
+	w.mu.Lock()
+	delivery, ok := w.deadLetters.get(id)
+	if !ok {
+		w.mu.Unlock()
+		return nil, ErrDeliveryNotFound
+	}
+	delivery.Status = DeliveryPending
+	delivery.AttemptsLeft = w.maxAttempts
+	delivery.UpdatedAt = time.Now().UTC()
+	stageDelivery(w.deliveries, delivery)
+	w.deadLetters.stage(nil, []string{id})
+	w.mu.Unlock()
+
+	w.enqueue(delivery)
+	return &delivery, nil
+}
+
+// DeleteDeadLetter discards a failed delivery; it stays in the delivery log
+func (w *WebhookService) DeleteDeadLetter(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	deleted, err := w.deadLetters.delete(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"equilibrio-backend/internal/models"
+)
+
+const testWebhookSecret = "0123456789abcdef0123"
+
+// newTestWebhookService creates a webhook service that retries three times,
+// fast, and the alert and event services that feed it
+func newTestWebhookService(t *testing.T) (*WebhookService, *AlertService, *EventService) {
+	alerts := newTestAlertService(t)
+	cfg, marketData := newTestMarketDataService(t)
+	events := NewEventService(cfg, marketData)
+	t.Cleanup(events.Close)
+
+	dir := t.TempDir()
+	cfg.WebhooksFile = filepath.Join(dir, "webhooks.json")
+	cfg.WebhookDeliveriesFile = filepath.Join(dir, "webhook_deliveries.json")
+	cfg.WebhookDeadLettersFile = filepath.Join(dir, "webhook_dead_letters.json")
+	cfg.WebhookMaxAttempts = 3
+	cfg.WebhookBackoff = 10 * time.Millisecond
+	cfg.WebhookTimeout = time.Second
+	cfg.WebhookAllowPrivate = true // Test receivers listen on loopback
+	webhooks, err := NewWebhookService(cfg, marketData.universes, alerts, events)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(webhooks.Close)
+	return webhooks, alerts, events
+}
+
+// webhookRequest is what a test receiver got
+type webhookRequest struct {
+	header  http.Header
+	payload models.WebhookPayload
+	valid   bool // Whether the signature matched the test secret
+}
+
+// TestWebhookSignedAlertDelivery tests that alerts are posted signed to subscribed endpoints
+func TestWebhookSignedAlertDelivery(t *testing.T) {
+	received := make(chan webhookRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		req := webhookRequest{
+			header: r.Header,
+			valid:  r.Header.Get(WebhookSignatureHeader) == SignWebhook(testWebhookSecret, timestamp, body),
+		}
+		json.Unmarshal(body, &req.payload)
+		received <- req
+	}))
+	t.Cleanup(server.Close)
+	webhooks, alerts, _ := newTestWebhookService(t)
+
+	endpoint, err := webhooks.CreateEndpoint(models.WebhookEndpointRequest{
+		Name:   "Chat",
+		URL:    server.URL,
+		Secret: testWebhookSecret,
+		Events: []string{WebhookAlert},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if endpoint.Secret != testWebhookSecret {
+		t.Errorf("Expected the secret returned on creation, got %q", endpoint.Secret)
+	}
+	if listed := webhooks.ListEndpoints(); len(listed) != 1 || listed[0].Secret != "" {
+		t.Errorf("Expected the endpoint listed without its secret, got %+v", listed)
+	}
+
+	noCooldown := 0
+	rule, err := alerts.CreateRule(models.AlertRuleRequest{
+		Name:            "AAPL oversold",
+		Symbol:          "AAPL",
+		Condition:       "rsi < 30",
+		CooldownSeconds: &noCooldown,
+		Channels:        []string{"webhook"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAPL": 40}))
+	alerts.check(rsiSnapshot(rule.Universe, map[string]float64{"AAPL": 20}))
+
+	var req webhookRequest
+	select {
+	case req = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the webhook")
+	}
+	if !req.valid {
+		t.Errorf("Expected a valid signature, got %q", req.header.Get(WebhookSignatureHeader))
+	}
+	if req.header.Get(WebhookEventHeader) != WebhookAlert || req.payload.Type != WebhookAlert {
+		t.Errorf("Expected an alert payload, got %q %+v", req.header.Get(WebhookEventHeader), req.payload)
+	}
+	var alert models.Alert
+	if err := json.Unmarshal(req.payload.Data, &alert); err != nil || alert.RuleID != rule.ID || alert.Symbol != "AAPL" {
+		t.Errorf("Expected AAPL's alert as data, got %s (%v)", req.payload.Data, err)
+	}
+
+	eventually(t, "the delivery to be logged", func() bool {
+		delivery, err := webhooks.GetDelivery(req.payload.ID)
+		return err == nil && delivery.Status == DeliveryDelivered
+	})
+	delivery, _ := webhooks.GetDelivery(req.payload.ID)
+	if len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != http.StatusOK || delivery.EndpointID != endpoint.ID {
+		t.Errorf("Expected one successful attempt logged, got %+v", delivery)
+	}
+
+	// The log is saved in batches, and at the latest when closing
+	webhooks.Close()
+	saved, err := newJSONStore[models.WebhookDelivery](webhooks.deliveries.path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stored, ok := saved.get(delivery.ID); !ok || stored.Status != DeliveryDelivered {
+		t.Errorf("Expected the delivery saved, got %+v", stored)
+	}
+	info, err := os.Stat(webhooks.endpoints.path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the endpoints and their secrets readable by the owner only, got %v", info.Mode())
+	}
+}
+
+// TestWebhookRetriesAndDeadLetters tests backoff, failed deliveries and their retry
+func TestWebhookRetriesAndDeadLetters(t *testing.T) {
+	var flaky, down int32
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.URL.Path]++
+		n := attempts[r.URL.Path]
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/flaky":
+			if n <= int(atomic.LoadInt32(&flaky)) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/down":
+			if atomic.LoadInt32(&down) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "/reject":
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	webhooks, _, events := newTestWebhookService(t)
+	atomic.StoreInt32(&flaky, 2)
+	atomic.StoreInt32(&down, 1)
+
+	create := func(path string, events ...string) *models.WebhookEndpoint {
+		endpoint, err := webhooks.CreateEndpoint(models.WebhookEndpointRequest{Name: path, URL: server.URL + path, Events: events})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return endpoint
+	}
+	flakyEndpoint := create("/flaky")
+	downEndpoint := create("/down", EventTrend)
+	rejectEndpoint := create("/reject", EventTrend)
+
+	// A signal change reaches the flaky endpoint on its third attempt
+	events.observe(testSnapshot(map[string]string{"AAA": "hold"}))
+	events.observe(testSnapshot(map[string]string{"AAA": "buy"}))
+	eventually(t, "the signal delivery", func() bool {
+		deliveries, _ := webhooks.ListDeliveries(flakyEndpoint.ID, DeliveryDelivered, 0)
+		return len(deliveries) == 1
+	})
+	deliveries, _ := webhooks.ListDeliveries(flakyEndpoint.ID, "", 0)
+	if len(deliveries) != 1 || deliveries[0].Type != EventSignal || len(deliveries[0].Attempts) != 3 {
+		t.Fatalf("Expected one signal delivery in three attempts, got %+v", deliveries)
+	}
+	tries := deliveries[0].Attempts
+	if tries[0].StatusCode != http.StatusServiceUnavailable || tries[2].StatusCode != http.StatusOK {
+		t.Errorf("Expected two 503s then a 200, got %+v", tries)
+	}
+	if gap := tries[2].Time.Sub(tries[1].Time); gap < 20*time.Millisecond {
+		t.Errorf("Expected the backoff doubled before the third attempt, got %v", gap)
+	}
+
+	// The failing endpoints end up in the dead letters: one after all its
+	// attempts, the other at once as 400 is not retried
+	webhooks.TestEndpoint(downEndpoint.ID)
+	webhooks.TestEndpoint(rejectEndpoint.ID)
+	eventually(t, "the failed deliveries", func() bool {
+		deadLetters, _ := webhooks.ListDeadLetters("", 0)
+		return len(deadLetters) == 2
+	})
+	deadLetters, _ := webhooks.ListDeadLetters(downEndpoint.ID, 0)
+	if len(deadLetters) != 1 || deadLetters[0].Status != DeliveryFailed || len(deadLetters[0].Attempts) != 3 || deadLetters[0].Type != WebhookPing {
+		t.Errorf("Expected a ping failed after three attempts, got %+v", deadLetters)
+	}
+	if rejected, _ := webhooks.ListDeadLetters(rejectEndpoint.ID, 0); len(rejected) != 1 || len(rejected[0].Attempts) != 1 {
+		t.Errorf("Expected a rejected delivery failed after one attempt, got %+v", rejected)
+	}
+
+	// Once the endpoint recovers, retrying the dead letter delivers it
+	atomic.StoreInt32(&down, 0)
+	retried, err := webhooks.RetryDeadLetter(deadLetters[0].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	eventually(t, "the retried delivery", func() bool {
+		delivery, err := webhooks.GetDelivery(retried.ID)
+		return err == nil && delivery.Status == DeliveryDelivered
+	})
+	if remaining, _ := webhooks.ListDeadLetters(downEndpoint.ID, 0); len(remaining) != 0 {
+		t.Errorf("Expected the dead letter removed, got %+v", remaining)
+	}
+	if _, err := webhooks.RetryDeadLetter(retried.ID); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("Expected ErrDeliveryNotFound retrying a delivered delivery, got %v", err)
+	}
+}
+
+// TestWebhookEndpointValidation tests rejected endpoint definitions and secret handling
+func TestWebhookEndpointValidation(t *testing.T) {
+	webhooks, _, _ := newTestWebhookService(t)
+
+	for _, req := range []models.WebhookEndpointRequest{
+		{Name: " ", URL: "https://example.com/hook"},
+		{Name: "Relative URL", URL: "/hook"},
+		{Name: "Other scheme", URL: "ftp://example.com/hook"},
+		{Name: "Short secret", URL: "https://example.com/hook", Secret: "short"},
+		{Name: "Unknown event", URL: "https://example.com/hook", Events: []string{"price"}},
+		{Name: "Unknown universe", URL: "https://example.com/hook", Universe: "nope"},
+	} {
+		if _, err := webhooks.CreateEndpoint(req); !errors.Is(err, ErrInvalidWebhook) {
+			t.Errorf("Expected ErrInvalidWebhook for %q, got %v", req.Name, err)
+		}
+	}
+
+	endpoint, err := webhooks.CreateEndpoint(models.WebhookEndpointRequest{Name: "Tools", URL: "https://example.com/hook", Symbols: []string{" aapl "}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(endpoint.Secret) != 64 || len(endpoint.Events) != 2 || !endpoint.Enabled {
+		t.Errorf("Expected a generated secret and alert and signal events, got %+v", endpoint)
+	}
+	if !webhooks.subscribed(endpoint, EventSignal, "default", "AAPL") || webhooks.subscribed(endpoint, EventSignal, "default", "MSFT") {
+		t.Errorf("Expected only AAPL's events to match, got %+v", endpoint.Symbols)
+	}
+
+	disabled := false
+	if _, err := webhooks.UpdateEndpoint(endpoint.ID, models.WebhookEndpointRequest{Name: "Tools", URL: "https://example.com/v2", Enabled: &disabled}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stored, _ := webhooks.endpoints.get(endpoint.ID)
+	if stored.Secret != endpoint.Secret || stored.URL != "https://example.com/v2" || stored.Enabled {
+		t.Errorf("Expected the secret kept through an update, got %+v", stored)
+	}
+	if webhooks.subscribed(&stored, EventSignal, "default", "AAPL") {
+		t.Error("Expected a disabled endpoint to match no events")
+	}
+	if err := webhooks.DeleteEndpoint("missing"); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound, got %v", err)
+	}
+}
+
+// TestWebhookRefusesPrivateAddresses tests that webhooks only reach public addresses by default
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	webhooks, _, _ := newTestWebhookService(t)
+	webhooks.allowPrivate = false
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.1.2.3/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+	} {
+		if _, err := webhooks.CreateEndpoint(models.WebhookEndpointRequest{Name: "Internal", URL: url}); !errors.Is(err, ErrInvalidWebhook) {
+			t.Errorf("Expected ErrInvalidWebhook for %s, got %v", url, err)
+		}
+	}
+
+	// Names resolving to internal addresses are refused when connecting
+	_, err := newWebhookClient(time.Second, false).Get(server.URL)
+	if !errors.Is(err, errNonPublicAddress) {
+		t.Errorf("Expected errNonPublicAddress connecting to %s, got %v", server.URL, err)
+	}
+	for ip, public := range map[string]bool{"8.8.8.8": true, "2606:4700::1111": true, "100.64.0.1": false, "::ffff:127.0.0.1": false, "0.0.0.0": false} {
+		if got := isPublicIP(net.ParseIP(ip)); got != public {
+			t.Errorf("isPublicIP(%s) = %v, want %v", ip, got, public)
+		}
+	}
+}